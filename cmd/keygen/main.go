@@ -0,0 +1,52 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+)
+
+// Generates a PEM private key for the "jwt_keys" section of configs/appsettings.json
+func main() {
+	keyType := flag.String("type", "ed25519", "Key type: ed25519 or rsa")
+	out := flag.String("out", "", "Path of the PEM file to create, e.g. configs/keys/2020-08.pem")
+	flag.Parse()
+
+	if *out == "" {
+		log.Fatal("-out is required!")
+	}
+
+	var privateKey crypto.PrivateKey
+	var err error
+
+	if *keyType == "ed25519" {
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	} else if *keyType == "rsa" {
+		privateKey, err = rsa.GenerateKey(rand.Reader, 3072)
+	} else {
+		log.Fatal("Bad key type!")
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := ioutil.WriteFile(*out, data, 0600); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Private key is written to", *out)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+	"github.com/shuryak/shuryak-backend/internal/search"
+	"github.com/shuryak/shuryak-backend/internal/utils"
+	"log"
+)
+
+// Rebuilds the local search index from the published articles in MongoDB. The index directory
+// can't be shared, so webapi must be stopped while the command runs.
+func main() {
+	profile := flag.String("profile", "debug", "Configuration profile selection")
+	flag.Parse()
+
+	configuration, err := utils.LoadConfiguration(utils.ConfigurationPath)
+	if err != nil {
+		log.Fatal("Bad config!\n\t>>> ", err)
+	}
+
+	var config *utils.ProfileType
+
+	if *profile == "debug" {
+		config = configuration.Debug
+	} else if *profile == "release" {
+		config = configuration.Release
+	} else {
+		log.Fatal("Bad profile!")
+	}
+
+	if config == nil {
+		log.Fatal("Profile ", *profile, " is missing in the config!")
+	}
+
+	if err := config.ApplyEnvironment(); err != nil {
+		log.Fatal("Bad config!\n\t>>> ", err)
+	}
+
+	utils.OpenMongo(config)
+	defer utils.CloseMongo()
+
+	articleRepository := repositories.NewMongoArticleRepository(utils.Mongo.Database(*config.MongoDatabase))
+	documents, err := repositories.PublishedDocuments(context.Background(), articleRepository)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	index, err := search.OpenLocalIndex(*config.ArticlesSearchIndexPath)
+	if err != nil {
+		log.Fatal("Bad search index!\n\t>>> ", err)
+	}
+	defer index.Close()
+
+	if err := index.Reset(documents); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(len(documents), "articles are indexed in", *config.ArticlesSearchIndexPath)
+}
@@ -1,60 +1,331 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/shuryak/shuryak-backend/internal/handlers/articles"
+	"github.com/shuryak/shuryak-backend/internal/handlers/bookmarks"
+	"github.com/shuryak/shuryak-backend/internal/handlers/categories"
+	"github.com/shuryak/shuryak-backend/internal/handlers/comments"
+	"github.com/shuryak/shuryak-backend/internal/handlers/keys"
 	"github.com/shuryak/shuryak-backend/internal/handlers/users"
+	"github.com/shuryak/shuryak-backend/internal/jobs"
 	"github.com/shuryak/shuryak-backend/internal/middleware"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/renderer"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+	"github.com/shuryak/shuryak-backend/internal/search"
 	"github.com/shuryak/shuryak-backend/internal/utils"
+	"github.com/shuryak/shuryak-backend/internal/views"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 )
 
-func handleRequests() http.Handler {
+func handleRequests(auth *middleware.Auth, articlesApi *articles.Api, categoriesApi *categories.Api, commentsApi *comments.Api, bookmarksApi *bookmarks.Api, usersApi *users.Api) http.Handler {
 	router := mux.NewRouter()
 
 	router.Use(middleware.HeadersMiddleware)
-	router.HandleFunc("/api/articles.create", middleware.IsAuthMiddleware(articles.CreateHandler))
-	router.HandleFunc("/api/articles.update", middleware.IsAuthMiddleware(articles.UpdateHandler))
-	router.HandleFunc("/api/articles.findOne", articles.FindOneHandler)
-	router.HandleFunc("/api/articles.findMany", articles.FindManyHandler)
-	router.HandleFunc("/api/articles.getById", articles.GetByCustomIdHandler)
-	router.HandleFunc("/api/articles.getList", articles.GetListHandler)
-	router.HandleFunc("/api/articles.getDraftsList", middleware.IsAuthMiddleware(articles.GetDraftsListHandler))
-	router.HandleFunc("/api/users.register", users.CreateHandler)
-	router.HandleFunc("/api/users.login", users.LoginHandler)
-	router.HandleFunc("/api/users.getUserInfo", middleware.IsAuthMiddleware(users.GetUserInfoHandler))
-	router.HandleFunc("/api/users.refreshTokenPair", users.RefreshTokenPairHandler)
+	router.HandleFunc("/.well-known/jwks.json", keys.JwksHandler).Methods(http.MethodGet)
+	router.HandleFunc("/articles/{id}.html", articlesApi.PageHandler).Methods(http.MethodGet)
+	router.HandleFunc("/api/articles.create", auth.RequireRole(models.RoleAuthor, articlesApi.CreateHandler))
+	router.HandleFunc("/api/articles.update", auth.RequireRole(models.RoleAuthor, articlesApi.UpdateHandler))
+	router.HandleFunc("/api/articles.importMarkdown", auth.RequireRole(models.RoleAuthor, articlesApi.ImportMarkdownHandler))
+	router.HandleFunc("/api/articles.findOne", articlesApi.FindOneHandler)
+	router.HandleFunc("/api/articles.findMany", articlesApi.FindManyHandler)
+	router.HandleFunc("/api/articles.search", articlesApi.SearchHandler)
+	router.HandleFunc("/api/articles.getById", articlesApi.GetByCustomIdHandler)
+	router.HandleFunc("/api/articles.getList", articlesApi.GetListHandler)
+	router.HandleFunc("/api/articles.getDraftsList", auth.RequireRole(models.RoleAuthor, articlesApi.GetDraftsListHandler))
+	router.HandleFunc("/api/articles.delete", auth.RequireRole(models.RoleAuthor, articlesApi.DeleteHandler))
+	router.HandleFunc("/api/articles.getTrash", auth.RequireRole(models.RoleAuthor, articlesApi.GetTrashHandler))
+	router.HandleFunc("/api/articles.restore", auth.RequireRole(models.RoleAuthor, articlesApi.RestoreHandler))
+	router.HandleFunc("/api/articles.getRevisions", auth.RequireRole(models.RoleAuthor, articlesApi.GetRevisionsHandler))
+	router.HandleFunc("/api/articles.getRevision", auth.RequireRole(models.RoleAuthor, articlesApi.GetRevisionHandler))
+	router.HandleFunc("/api/articles.diffRevisions", auth.RequireRole(models.RoleAuthor, articlesApi.DiffRevisionsHandler))
+	router.HandleFunc("/api/articles.rollback", auth.RequireRole(models.RoleAuthor, articlesApi.RollbackHandler))
+	router.HandleFunc("/api/articles.submitForReview", auth.RequireRole(models.RoleAuthor, articlesApi.SubmitForReviewHandler))
+	router.HandleFunc("/api/articles.approve", auth.RequireRole(models.RoleEditor, articlesApi.ApproveHandler))
+	router.HandleFunc("/api/articles.requestChanges", auth.RequireRole(models.RoleEditor, articlesApi.RequestChangesHandler))
+	router.HandleFunc("/api/articles.publish", auth.RequireRole(models.RoleAuthor, articlesApi.PublishHandler))
+	router.HandleFunc("/api/articles.archive", auth.RequireRole(models.RoleAuthor, articlesApi.ArchiveHandler))
+	router.HandleFunc("/api/articles.getReview", auth.RequireRole(models.RoleAuthor, articlesApi.GetReviewHandler))
+	router.HandleFunc("/api/articles.getPopular", articlesApi.GetPopularHandler)
+	router.HandleFunc("/api/articles.react", auth.IsAuthMiddleware(articlesApi.ReactHandler))
+	router.HandleFunc("/api/articles.unreact", auth.IsAuthMiddleware(articlesApi.UnreactHandler))
+	router.HandleFunc("/api/articles.getByTag", articlesApi.GetByTagHandler)
+	router.HandleFunc("/api/tags.getPopular", articlesApi.GetPopularTagsHandler)
+	router.HandleFunc("/api/categories.getTree", categoriesApi.GetTreeHandler)
+	router.HandleFunc("/api/categories.create", auth.RequireRole(models.RoleEditor, categoriesApi.CreateHandler))
+	router.HandleFunc("/api/categories.update", auth.RequireRole(models.RoleEditor, categoriesApi.UpdateHandler))
+	router.HandleFunc("/api/categories.delete", auth.RequireRole(models.RoleEditor, categoriesApi.DeleteHandler))
+	router.HandleFunc("/api/comments.create", auth.IsAuthMiddleware(commentsApi.CreateHandler))
+	router.HandleFunc("/api/comments.edit", auth.IsAuthMiddleware(commentsApi.EditHandler))
+	router.HandleFunc("/api/comments.delete", auth.IsAuthMiddleware(commentsApi.DeleteHandler))
+	router.HandleFunc("/api/comments.getThread", commentsApi.GetThreadHandler)
+	router.HandleFunc("/api/comments.hide", auth.IsAuthMiddleware(commentsApi.HideHandler))
+	router.HandleFunc("/api/comments.pin", auth.IsAuthMiddleware(commentsApi.PinHandler))
+	router.HandleFunc("/api/comments.lockThread", auth.IsAuthMiddleware(commentsApi.LockThreadHandler))
+	router.HandleFunc("/api/bookmarks.add", auth.IsAuthMiddleware(bookmarksApi.AddHandler))
+	router.HandleFunc("/api/bookmarks.remove", auth.IsAuthMiddleware(bookmarksApi.RemoveHandler))
+	router.HandleFunc("/api/bookmarks.getList", auth.IsAuthMiddleware(bookmarksApi.GetListHandler))
+	router.HandleFunc("/api/bookmarks.reorder", auth.IsAuthMiddleware(bookmarksApi.ReorderHandler))
+	router.HandleFunc("/api/readingLists.create", auth.IsAuthMiddleware(bookmarksApi.CreateListHandler))
+	router.HandleFunc("/api/readingLists.rename", auth.IsAuthMiddleware(bookmarksApi.RenameListHandler))
+	router.HandleFunc("/api/readingLists.delete", auth.IsAuthMiddleware(bookmarksApi.DeleteListHandler))
+	router.HandleFunc("/api/readingLists.getAll", auth.IsAuthMiddleware(bookmarksApi.GetListsHandler))
+	router.HandleFunc("/api/users.register", usersApi.CreateHandler)
+	router.HandleFunc("/api/users.login", usersApi.LoginHandler)
+	router.HandleFunc("/api/users.getUserInfo", auth.IsAuthMiddleware(usersApi.GetUserInfoHandler))
+	router.HandleFunc("/api/users.refreshTokenPair", usersApi.RefreshTokenPairHandler)
+	router.HandleFunc("/api/users.setRole", auth.RequireRole(models.RoleAdmin, usersApi.SetRoleHandler))
+	router.HandleFunc("/api/users.getReactedArticles", auth.IsAuthMiddleware(usersApi.GetReactedArticlesHandler))
+	router.HandleFunc("/api/users.getSessions", auth.IsAuthMiddleware(usersApi.GetSessionsHandler))
+	router.HandleFunc("/api/users.revokeSession", auth.IsAuthMiddleware(usersApi.RevokeSessionHandler))
+	router.HandleFunc("/api/users.logout", auth.IsAuthMiddleware(usersApi.LogoutHandler))
+	router.HandleFunc("/api/users.logoutAll", auth.IsAuthMiddleware(usersApi.LogoutAllHandler))
 
 	http.Handle("/", router)
 
-	return cors.AllowAll().Handler(router)
+	// Same as cors.AllowAll, but lets browsers read the ETag for If-Match
+	return cors.New(cors.Options{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{
+			http.MethodHead,
+			http.MethodGet,
+			http.MethodPost,
+			http.MethodPut,
+			http.MethodPatch,
+			http.MethodDelete,
+		},
+		AllowedHeaders:   []string{"*"},
+		ExposedHeaders:   []string{"ETag"},
+		AllowCredentials: false,
+	}).Handler(router)
 }
 
+// shutdownTimeout bounds the wait for the requests in flight and the final flush of the views
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	profile := flag.String("profile", "debug", "Configuration profile selection")
+	storage := flag.String("storage", "mongo", "Storage selection: mongo or memory")
 	flag.Parse()
 
+	configuration, err := utils.LoadConfiguration(utils.ConfigurationPath)
+	if err != nil {
+		log.Fatal("Bad config!\n\t>>> ", err)
+	}
+
 	var config *utils.ProfileType
 
 	if *profile == "debug" {
-		config = utils.Configuration.Debug
+		config = configuration.Debug
 	} else if *profile == "release" {
-		config = utils.Configuration.Release
+		config = configuration.Release
 	} else {
 		log.Fatal("Bad profile!")
 	}
 
-	utils.OpenMongo("mongodb://localhost:27017")
-	defer utils.CloseMongo()
+	if config == nil {
+		log.Fatal("Profile ", *profile, " is missing in the config!")
+	}
+
+	if err := config.ApplyEnvironment(); err != nil {
+		log.Fatal("Bad config!\n\t>>> ", err)
+	}
+
+	if *profile == "release" {
+		if err := config.CheckSecrets(); err != nil {
+			log.Fatal("Refusing to start!\n\t>>> ", err)
+		}
+	}
+
+	if err := utils.ConfigureJwt(config); err != nil {
+		log.Fatal("Bad JWT keys!\n\t>>> ", err)
+	}
+	utils.ConfigureCursors(config)
+	renderer.ConfigureEmbeds(config.ArticlesEmbedHosts)
+
+	var userRepository repositories.UserRepository
+	var sessionRepository repositories.SessionRepository
+	var revokedTokenRepository repositories.RevokedTokenRepository
+	var articleRepository repositories.ArticleRepository
+	var revisionRepository repositories.RevisionRepository
+	var leaseRepository repositories.LeaseRepository
+	var categoryRepository repositories.CategoryRepository
+	var viewRepository repositories.ViewRepository
+	var reactionRepository repositories.ReactionRepository
+	var commentRepository repositories.CommentRepository
+	var bookmarkRepository repositories.BookmarkRepository
+	var readingListRepository repositories.ReadingListRepository
+
+	if *storage == "mongo" {
+		utils.OpenMongo(config)
+		defer utils.CloseMongo()
+
+		db := utils.Mongo.Database(*config.MongoDatabase)
+		mongoUserRepository := repositories.NewMongoUserRepository(db)
+		if err := mongoUserRepository.EnsureIndexes(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+		userRepository = mongoUserRepository
+		mongoSessionRepository := repositories.NewMongoSessionRepository(db)
+		if err := mongoSessionRepository.EnsureIndexes(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+		sessionRepository = mongoSessionRepository
+		mongoRevokedTokenRepository := repositories.NewMongoRevokedTokenRepository(db)
+		if err := mongoRevokedTokenRepository.EnsureIndexes(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+		revokedTokenRepository = mongoRevokedTokenRepository
+		mongoArticleRepository := repositories.NewMongoArticleRepository(db)
+		if err := mongoArticleRepository.EnsureIndexes(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+		articleRepository = mongoArticleRepository
+		mongoRevisionRepository := repositories.NewMongoRevisionRepository(db)
+		if err := mongoRevisionRepository.EnsureIndexes(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+		revisionRepository = mongoRevisionRepository
+		leaseRepository = repositories.NewMongoLeaseRepository(db)
+		categoryRepository = repositories.NewMongoCategoryRepository(db)
+		mongoViewRepository := repositories.NewMongoViewRepository(db)
+		if err := mongoViewRepository.EnsureIndexes(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+		viewRepository = mongoViewRepository
+		mongoReactionRepository := repositories.NewMongoReactionRepository(db)
+		if err := mongoReactionRepository.EnsureIndexes(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+		reactionRepository = mongoReactionRepository
+		mongoCommentRepository := repositories.NewMongoCommentRepository(db)
+		if err := mongoCommentRepository.EnsureIndexes(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+		commentRepository = mongoCommentRepository
+		mongoBookmarkRepository := repositories.NewMongoBookmarkRepository(db)
+		if err := mongoBookmarkRepository.EnsureIndexes(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+		bookmarkRepository = mongoBookmarkRepository
+		mongoReadingListRepository := repositories.NewMongoReadingListRepository(db)
+		if err := mongoReadingListRepository.EnsureIndexes(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+		readingListRepository = mongoReadingListRepository
+	} else if *storage == "memory" {
+		userRepository = repositories.NewMemoryUserRepository()
+		sessionRepository = repositories.NewMemorySessionRepository()
+		revokedTokenRepository = repositories.NewMemoryRevokedTokenRepository()
+		articleRepository = repositories.NewMemoryArticleRepository()
+		revisionRepository = repositories.NewMemoryRevisionRepository()
+		leaseRepository = repositories.NewMemoryLeaseRepository()
+		categoryRepository = repositories.NewMemoryCategoryRepository()
+		viewRepository = repositories.NewMemoryViewRepository()
+		reactionRepository = repositories.NewMemoryReactionRepository()
+		commentRepository = repositories.NewMemoryCommentRepository()
+		bookmarkRepository = repositories.NewMemoryBookmarkRepository()
+		readingListRepository = repositories.NewMemoryReadingListRepository()
+		fmt.Println("Using in-memory storage, data will be lost on exit!")
+	} else {
+		log.Fatal("Bad storage!")
+	}
+
+	articleRepository = repositories.NewBookmarkedArticleRepository(articleRepository, bookmarkRepository)
+
+	var searchIndex search.SearchIndex
+
+	if *config.ArticlesSearchBackend == "local" {
+		localIndex, err := search.OpenLocalIndex(*config.ArticlesSearchIndexPath)
+		if err != nil {
+			log.Fatal("Bad search index!\n\t>>> ", err)
+		}
+		defer localIndex.Close()
+
+		// A stale index of the persistent storage is rebuilt with cmd/searchindex, the in-memory one starts empty
+		if localIndex.IsEmpty() || *storage == "memory" {
+			documents, err := repositories.PublishedDocuments(context.Background(), articleRepository)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err := localIndex.Reset(documents); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		articleRepository = repositories.NewIndexedArticleRepository(articleRepository, localIndex)
+		searchIndex = localIndex
+	} else {
+		searchIndex = repositories.NewRepositorySearchIndex(articleRepository)
+	}
+
+	revocationCacheTtl := time.Duration(*config.JwtRevocationCacheSeconds) * time.Second
+	revokedTokenRepository = repositories.NewCachedRevokedTokenRepository(revokedTokenRepository, revocationCacheTtl)
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	trashRetention := time.Duration(*config.ArticlesTrashRetentionDays) * 24 * time.Hour
+	go jobs.Every(jobsCtx, time.Hour, "trash purge", jobs.PurgeTrash(articleRepository, revisionRepository, reactionRepository, commentRepository, trashRetention))
+	go jobs.Every(jobsCtx, time.Hour, "reaction recount", jobs.RecountReactions(articleRepository, reactionRepository))
+
+	publishInterval := time.Duration(*config.ArticlesPublishIntervalSeconds) * time.Second
+	go jobs.Every(jobsCtx, publishInterval, "scheduled publishing", jobs.PublishScheduled(articleRepository, leaseRepository, publishInterval))
+
+	viewWindow := time.Duration(*config.ArticlesViewWindowMinutes) * time.Minute
+	viewCounter := views.NewCounter(articleRepository, viewRepository, viewWindow)
+	viewFlushInterval := time.Duration(*config.ArticlesViewFlushSeconds) * time.Second
+	viewFlushStopped := make(chan struct{})
+	go func() {
+		jobs.Every(jobsCtx, viewFlushInterval, "view flush", viewCounter.Flush)
+		close(viewFlushStopped)
+	}()
+
+	auth := middleware.NewAuth(revokedTokenRepository)
+	articlesApi := articles.NewApi(articleRepository, revisionRepository, userRepository, categoryRepository, searchIndex, viewRepository, viewCounter, reactionRepository, models.Workflow{AuthorsCanPublish: *config.ArticlesAuthorsCanPublish})
+	categoriesApi := categories.NewApi(categoryRepository, articleRepository)
+	commentsApi := comments.NewApi(commentRepository, articleRepository)
+	bookmarksApi := bookmarks.NewApi(bookmarkRepository, readingListRepository, articleRepository)
+	usersApi := users.NewApi(userRepository, sessionRepository, revokedTokenRepository, articleRepository, reactionRepository)
+
+	server := &http.Server{
+		Addr:    ":" + *config.ServerPort,
+		Handler: handleRequests(auth, articlesApi, categoriesApi, commentsApi, bookmarksApi, usersApi),
+	}
 
 	fmt.Println("Server is running on", *config.ServerPort, "port!")
-	err := http.ListenAndServe(":"+*config.ServerPort, handleRequests())
-	if err != nil {
-		log.Fatal("Internal error!")
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Internal error!")
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println("Shutdown failed:", err)
+	}
+
+	// The views counted since the last flush would be lost, the flush job is let finish first
+	stopJobs()
+	<-viewFlushStopped
+	if err := viewCounter.Flush(shutdownCtx); err != nil {
+		log.Println("Final view flush failed:", err)
 	}
 }
@@ -0,0 +1,50 @@
+package blocks
+
+import "encoding/json"
+
+// Document is a typed view of article_data, see the block types in schema.go
+type Document struct {
+	Blocks []Block `json:"blocks"`
+}
+
+type Block struct {
+	Type     string     `json:"type"`
+	Text     string     `json:"text,omitempty"`
+	Level    int        `json:"level,omitempty"`
+	Url      string     `json:"url,omitempty"`
+	Alt      string     `json:"alt,omitempty"`
+	Caption  string     `json:"caption,omitempty"`
+	Code     string     `json:"code,omitempty"`
+	Language string     `json:"language,omitempty"`
+	Style    string     `json:"style,omitempty"`
+	Items    []ListItem `json:"items,omitempty"`
+}
+
+type ListItem struct {
+	Text  string     `json:"text"`
+	Items []ListItem `json:"items,omitempty"`
+}
+
+// Parse converts stored article_data into a Document, it doesn't validate it
+func Parse(data map[string]interface{}) (Document, error) {
+	var document Document
+
+	// article_data comes from JSON or BSON, going through JSON handles both
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return document, err
+	}
+
+	err = json.Unmarshal(encoded, &document)
+	return document, err
+}
+
+// ToData converts the document into the article_data form the API stores
+func (document Document) ToData() map[string]interface{} {
+	var data map[string]interface{}
+
+	encoded, _ := json.Marshal(document)
+	json.Unmarshal(encoded, &data)
+
+	return data
+}
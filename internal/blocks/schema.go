@@ -0,0 +1,272 @@
+package blocks
+
+import (
+	"encoding/json"
+	"fmt"
+	v "github.com/asaskevich/govalidator"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Block types of article_data.blocks, text and captions may contain the inline tags
+// allowed by the renderer package (b, strong, i, em, u, s, code, a and br)
+const (
+	TypeParagraph = "paragraph" // {text}
+	TypeHeading   = "heading"   // {level 1-6, text}
+	TypeImage     = "image"     // {url, alt?, caption?}
+	TypeCode      = "code"      // {code, language?}
+	TypeQuote     = "quote"     // {text, caption?}
+	TypeList      = "list"      // {style "ordered" or "unordered", items: [{text, items?}]}
+	TypeEmbed     = "embed"     // {url, caption?}, only https
+)
+
+// Fields allowed in every block type besides "type"
+var blockFields = map[string][]string{
+	TypeParagraph: {"text"},
+	TypeHeading:   {"level", "text"},
+	TypeImage:     {"url", "alt", "caption"},
+	TypeCode:      {"code", "language"},
+	TypeQuote:     {"text", "caption"},
+	TypeList:      {"style", "items"},
+	TypeEmbed:     {"url", "caption"},
+}
+
+var languagePattern = regexp.MustCompile(`^[a-z0-9+#._-]{1,32}$`)
+
+func IsLanguage(language string) bool {
+	return languagePattern.MatchString(language)
+}
+
+// A long list of errors doesn't help anyone, the rest is reported after the first ones are fixed
+const maxErrors = 20
+
+type validator struct {
+	errors []models.FieldError
+}
+
+// Validate checks article_data against the block schema and the size limits,
+// the errors have paths like "article_data.blocks[3].text"
+func Validate(data map[string]interface{}) []models.FieldError {
+	const root = "article_data"
+	val := &validator{}
+
+	if data == nil {
+		val.fail(root, "is required")
+		return val.errors
+	}
+
+	if encoded, err := json.Marshal(data); err != nil || len(encoded) > int(models.ArticleDataMaxSize) {
+		val.fail(root, fmt.Sprint("size > ", models.ArticleDataMaxSize, " bytes"))
+		return val.errors
+	}
+
+	val.onlyFields(root, reflect.ValueOf(data), "blocks")
+
+	blocks, ok := asSlice(data["blocks"])
+	if !ok {
+		val.fail(root+".blocks", "must be an array")
+		return val.errors
+	}
+
+	if blocks.Len() > int(models.ArticleDataMaxBlocks) {
+		val.fail(root+".blocks", fmt.Sprint("length > ", models.ArticleDataMaxBlocks))
+		return val.errors
+	}
+
+	for i := 0; i < blocks.Len() && len(val.errors) < maxErrors; i++ {
+		val.block(fmt.Sprintf("%s.blocks[%d]", root, i), blocks.Index(i).Interface())
+	}
+
+	return val.errors
+}
+
+func (val *validator) fail(path string, message string) {
+	if len(val.errors) < maxErrors {
+		val.errors = append(val.errors, models.FieldError{Path: path, Message: message})
+	}
+}
+
+func (val *validator) block(path string, value interface{}) {
+	block, ok := asMap(value)
+	if !ok {
+		val.fail(path, "must be an object")
+		return
+	}
+
+	blockType, ok := field(block, "type").(string)
+	if !ok {
+		val.fail(path+".type", "must be a string")
+		return
+	}
+
+	fields, ok := blockFields[blockType]
+	if !ok {
+		val.fail(path+".type", fmt.Sprint("unknown block type ", blockType))
+		return
+	}
+	val.onlyFields(path, block, append(fields, "type")...)
+
+	switch blockType {
+	case TypeParagraph:
+		val.text(path+".text", field(block, "text"), true)
+	case TypeHeading:
+		level, ok := asInt(field(block, "level"))
+		if !ok || level < 1 || level > 6 {
+			val.fail(path+".level", "must be an integer from 1 to 6")
+		}
+		val.text(path+".text", field(block, "text"), true)
+	case TypeImage:
+		val.url(path+".url", field(block, "url"), false)
+		val.text(path+".alt", field(block, "alt"), false)
+		val.text(path+".caption", field(block, "caption"), false)
+	case TypeCode:
+		val.text(path+".code", field(block, "code"), true)
+		if language := field(block, "language"); language != nil {
+			if s, ok := language.(string); !ok || !IsLanguage(s) {
+				val.fail(path+".language", "must be a lowercase language name")
+			}
+		}
+	case TypeQuote:
+		val.text(path+".text", field(block, "text"), true)
+		val.text(path+".caption", field(block, "caption"), false)
+	case TypeList:
+		if style, _ := field(block, "style").(string); style != "ordered" && style != "unordered" {
+			val.fail(path+".style", "must be ordered or unordered")
+		}
+		val.listItems(path+".items", field(block, "items"), 1)
+	case TypeEmbed:
+		val.url(path+".url", field(block, "url"), true)
+		val.text(path+".caption", field(block, "caption"), false)
+	}
+}
+
+func (val *validator) listItems(path string, value interface{}, depth int) {
+	if depth > int(models.ArticleListMaxDepth) {
+		val.fail(path, fmt.Sprint("lists are nested deeper than ", models.ArticleListMaxDepth))
+		return
+	}
+
+	items, ok := asSlice(value)
+	if !ok || items.Len() == 0 {
+		val.fail(path, "must be a non-empty array")
+		return
+	}
+
+	if items.Len() > int(models.ArticleDataMaxBlocks) {
+		val.fail(path, fmt.Sprint("length > ", models.ArticleDataMaxBlocks))
+		return
+	}
+
+	for i := 0; i < items.Len() && len(val.errors) < maxErrors; i++ {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+
+		item, ok := asMap(items.Index(i).Interface())
+		if !ok {
+			val.fail(itemPath, "must be an object")
+			continue
+		}
+		val.onlyFields(itemPath, item, "text", "items")
+
+		val.text(itemPath+".text", field(item, "text"), true)
+		if nested := field(item, "items"); nested != nil {
+			val.listItems(itemPath+".items", nested, depth+1)
+		}
+	}
+}
+
+func (val *validator) text(path string, value interface{}, required bool) {
+	if value == nil {
+		if required {
+			val.fail(path, "is required")
+		}
+		return
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		val.fail(path, "must be a string")
+		return
+	}
+
+	if required && s == "" {
+		val.fail(path, "must not be empty")
+		return
+	}
+
+	if len(s) > int(models.ArticleBlockTextMaxLimit) {
+		val.fail(path, fmt.Sprint("length > ", models.ArticleBlockTextMaxLimit))
+	}
+}
+
+func (val *validator) url(path string, value interface{}, httpsOnly bool) {
+	s, ok := value.(string)
+	if !ok || !v.IsURL(s) {
+		val.fail(path, "must be a URL")
+		return
+	}
+
+	if httpsOnly && !strings.HasPrefix(s, "https://") {
+		val.fail(path, "must be an https URL")
+	}
+}
+
+func (val *validator) onlyFields(path string, object reflect.Value, allowed ...string) {
+	var unknown []string
+
+	for _, key := range object.MapKeys() {
+		known := false
+		for _, name := range allowed {
+			if key.String() == name {
+				known = true
+				break
+			}
+		}
+
+		if !known {
+			unknown = append(unknown, key.String())
+		}
+	}
+
+	// Map order is random, the response shouldn't be
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		val.fail(path+"."+key, "unknown field")
+	}
+}
+
+// The values come from JSON or from BSON, so maps, slices and numbers are checked by their kind
+func asMap(value interface{}) (reflect.Value, bool) {
+	object := reflect.ValueOf(value)
+	return object, object.Kind() == reflect.Map && object.Type().Key().Kind() == reflect.String
+}
+
+func asSlice(value interface{}) (reflect.Value, bool) {
+	list := reflect.ValueOf(value)
+	return list, list.Kind() == reflect.Slice
+}
+
+func field(object reflect.Value, name string) interface{} {
+	value := object.MapIndex(reflect.ValueOf(name).Convert(object.Type().Key()))
+	if !value.IsValid() || (value.Kind() == reflect.Interface && value.IsNil()) {
+		return nil
+	}
+
+	return value.Interface()
+}
+
+func asInt(value interface{}) (int, bool) {
+	number := reflect.ValueOf(value)
+
+	switch number.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(number.Int()), true
+	case reflect.Float32, reflect.Float64:
+		f := number.Float()
+		return int(f), f == float64(int(f))
+	}
+
+	return 0, false
+}
@@ -0,0 +1,61 @@
+package blocks
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantPath string // Of the first error, empty when the data is valid
+	}{
+		{"empty document", `{"blocks": []}`, ""},
+		{"all the blocks", `{"blocks": [
+			{"type": "paragraph", "text": "a"},
+			{"type": "heading", "level": 2, "text": "a"},
+			{"type": "image", "url": "https://a.io/i.png", "alt": "a", "caption": "a"},
+			{"type": "code", "language": "go", "code": "a"},
+			{"type": "quote", "text": "a", "caption": "a"},
+			{"type": "list", "style": "unordered", "items": [{"text": "a", "items": [{"text": "b"}]}]},
+			{"type": "embed", "url": "https://a.io/v"}
+		]}`, ""},
+		{"missing", `null`, "article_data"},
+		{"unknown field", `{"blocks": [], "version": 1}`, "article_data.version"},
+		{"blocks aren't an array", `{"blocks": {}}`, "article_data.blocks"},
+		{"unknown block type", `{"blocks": [{"type": "table"}]}`, "article_data.blocks[0].type"},
+		{"unknown block field", `{"blocks": [{"type": "paragraph", "text": "a", "html": "a"}]}`, "article_data.blocks[0].html"},
+		{"empty text", `{"blocks": [{"type": "paragraph", "text": ""}]}`, "article_data.blocks[0].text"},
+		{"heading level", `{"blocks": [{"type": "heading", "level": 7, "text": "a"}]}`, "article_data.blocks[0].level"},
+		{"empty code", `{"blocks": [{"type": "code", "code": ""}]}`, "article_data.blocks[0].code"},
+		{"bad language", `{"blocks": [{"type": "code", "code": "a", "language": "Go Lang"}]}`, "article_data.blocks[0].language"},
+		{"list style", `{"blocks": [{"type": "list", "style": "dotted", "items": [{"text": "a"}]}]}`, "article_data.blocks[0].style"},
+		{"empty list", `{"blocks": [{"type": "list", "style": "ordered", "items": []}]}`, "article_data.blocks[0].items"},
+		{"empty list item", `{"blocks": [{"type": "list", "style": "ordered", "items": [{"text": ""}]}]}`, "article_data.blocks[0].items[0].text"},
+		{"deep list", `{"blocks": [{"type": "list", "style": "ordered", "items": [{"text": "1", "items": [{"text": "2", "items": [{"text": "3", "items": [{"text": "4", "items": [{"text": "5"}]}]}]}]}]}]}`,
+			"article_data.blocks[0].items[0].items[0].items[0].items[0].items"},
+		{"missing embed url", `{"blocks": [{"type": "embed"}]}`, "article_data.blocks[0].url"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var data map[string]interface{}
+			if err := json.Unmarshal([]byte(test.data), &data); err != nil {
+				t.Fatal(err)
+			}
+
+			errors := Validate(data)
+
+			if test.wantPath == "" {
+				if len(errors) != 0 {
+					t.Errorf("got errors %+v, want none", errors)
+				}
+				return
+			}
+			if len(errors) == 0 || errors[0].Path != test.wantPath {
+				t.Errorf("got errors %+v, want one at %s", errors, test.wantPath)
+			}
+		})
+	}
+}
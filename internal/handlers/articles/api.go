@@ -1,21 +1,42 @@
 package articles
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	v "github.com/asaskevich/govalidator"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/shuryak/shuryak-backend/internal/blocks"
 	"github.com/shuryak/shuryak-backend/internal/models"
-	"github.com/shuryak/shuryak-backend/internal/utils"
+	"github.com/shuryak/shuryak-backend/internal/renderer"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+	"github.com/shuryak/shuryak-backend/internal/search"
 	"github.com/shuryak/shuryak-backend/internal/utils/http-result"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/shuryak/shuryak-backend/internal/views"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"net/http"
+	"time"
 )
 
-func CreateHandler(w http.ResponseWriter, r *http.Request) {
+// In runes, about two lines of text
+const snippetLength = 160
+
+type Api struct {
+	articles   repositories.ArticleRepository
+	revisions  repositories.RevisionRepository
+	users      repositories.UserRepository
+	categories repositories.CategoryRepository
+	index      search.SearchIndex
+	views      repositories.ViewRepository
+	counter    *views.Counter
+	reactions  repositories.ReactionRepository
+	workflow   models.Workflow
+}
+
+func NewApi(articles repositories.ArticleRepository, revisions repositories.RevisionRepository, users repositories.UserRepository, categories repositories.CategoryRepository, index search.SearchIndex, views repositories.ViewRepository, counter *views.Counter, reactions repositories.ReactionRepository, workflow models.Workflow) *Api {
+	return &Api{articles: articles, revisions: revisions, users: users, categories: categories, index: index, views: views, counter: counter, reactions: reactions, workflow: workflow}
+}
+
+func (api *Api) CreateHandler(w http.ResponseWriter, r *http.Request) {
 	var dto models.ArticleDTO
 
 	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
@@ -23,64 +44,90 @@ func CreateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	article, ok := api.create(w, r, dto)
+	if !ok {
+		return
+	}
+
+	json.NewEncoder(w).Encode(article.ToMeta())
+}
+
+// create validates and stores a new article with its first revision, it writes the error itself
+func (api *Api) create(w http.ResponseWriter, r *http.Request, dto models.ArticleDTO) (*models.Article, bool) {
 	// region Validation
 	if len(dto.CustomId) < int(models.ArticleIdMinLimit) || len(dto.CustomId) > int(models.ArticleIdMaxLimit) {
 		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("id length < ", models.ArticleIdMinLimit, " or > ", models.ArticleIdMaxLimit))
-		return
+		return nil, false
 	}
 
 	if len(dto.Name) < int(models.ArticleNameMinLimit) || len(dto.Name) > int(models.ArticleNameMaxLimit) {
 		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("name length < ", models.ArticleNameMinLimit, " or > ", models.ArticleNameMaxLimit))
-		return
+		return nil, false
 	}
 
 	if !v.IsURL(dto.Thumbnail) {
 		http_result.WriteError(&w, models.BadRequest, "invalid thumbnail")
-		return
+		return nil, false
+	}
+
+	if fieldErrors := blocks.Validate(dto.ArticleData); len(fieldErrors) != 0 {
+		http_result.WriteFieldErrors(&w, models.InvalidDocument, "invalid article_data", fieldErrors)
+		return nil, false
 	}
-	// endregion Validation
 
-	collection := utils.Mongo.Database("shuryakDb").Collection("articles")
+	if !api.checkTaxonomy(w, r, &dto) {
+		return nil, false
+	}
+	// endregion Validation
 
 	// Checking for the existence of an article with this name
-	var dbArticle models.User
-	findFilter := bson.D{{"custom_id", dto.CustomId}}
-	if err := collection.FindOne(context.TODO(), findFilter).Decode(&dbArticle); err == nil {
+	if _, err := api.articles.FindByCustomId(r.Context(), dto.CustomId); err == nil {
 		http_result.WriteError(&w, models.NotUniqueData, "article with this id already exists")
-		return
+		return nil, false
 	}
-	findFilter = bson.D{{"name", dto.Name}}
-	if err := collection.FindOne(context.TODO(), findFilter).Decode(&dbArticle); err == nil {
+	if _, err := api.articles.FindByName(r.Context(), dto.Name); err == nil {
 		http_result.WriteError(&w, models.NotUniqueData, "article with this name already exists")
-		return
+		return nil, false
 	}
 
-	_, err := collection.InsertOne(context.TODO(), models.Article{
+	article := models.Article{
 		Id:          primitive.NewObjectID(),
 		CustomId:    dto.CustomId,
 		Name:        dto.Name,
 		Author:      r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string),
-		IsDraft:     dto.IsDraft,
 		Thumbnail:   dto.Thumbnail,
+		Tags:        dto.Tags,
+		Category:    dto.Category,
 		ArticleData: dto.ArticleData,
-	})
-	if err != nil {
+		PublishAt:   dto.PublishAt,
+		Version:     1,
+	}
+
+	state := requestedState(models.StateDraft, dto.IsDraft, article.IsScheduled(time.Now()))
+	if !api.checkTransition(w, r, models.StateDraft, state) {
+		return nil, false
+	}
+	article.SetState(state)
+
+	if err := api.articles.Create(r.Context(), &article); err != nil {
+		if err == repositories.ErrDuplicate {
+			http_result.WriteError(&w, models.NotUniqueData, "article with this id already exists")
+			return nil, false
+		}
 		http_result.WriteError(&w, models.InternalError, "internal error")
-		return
+		return nil, false
 	}
 
-	result := models.MetaArticle{
-		Id:        dto.CustomId,
-		Name:      dto.Name,
-		Author:    r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string),
-		IsDraft:   dto.IsDraft,
-		Thumbnail: dto.Thumbnail,
+	revision := models.NewRevision(article, article.Author)
+	if err := api.revisions.Add(r.Context(), &revision); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return nil, false
 	}
 
-	json.NewEncoder(w).Encode(result)
+	return &article, true
 }
 
-func UpdateHandler(w http.ResponseWriter, r *http.Request) {
+func (api *Api) UpdateHandler(w http.ResponseWriter, r *http.Request) {
 	var dto models.ArticleDTO
 
 	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
@@ -103,51 +150,89 @@ func UpdateHandler(w http.ResponseWriter, r *http.Request) {
 		http_result.WriteError(&w, models.BadRequest, "invalid thumbnail")
 		return
 	}
-	// endregion Validation
 
-	collection := utils.Mongo.Database("shuryakDb").Collection("articles")
+	if fieldErrors := blocks.Validate(dto.ArticleData); len(fieldErrors) != 0 {
+		http_result.WriteFieldErrors(&w, models.InvalidDocument, "invalid article_data", fieldErrors)
+		return
+	}
+
+	if !api.checkTaxonomy(w, r, &dto) {
+		return
+	}
 
-	findFilter := bson.D{{"custom_id", dto.CustomId}}
+	// If-Match takes precedence over the version in the body
+	expectedVersion, ok, err := parseIfMatch(r)
+	if err != nil {
+		http_result.WriteError(&w, models.BadRequest, err.Error())
+		return
+	}
+	if !ok {
+		expectedVersion = dto.Version
+	}
+	// endregion Validation
 
-	var dbArticle models.Article
-	if err := collection.FindOne(context.TODO(), findFilter).Decode(&dbArticle); err != nil {
+	dbArticle, err := api.articles.FindByCustomId(r.Context(), dto.CustomId)
+	if err != nil {
 		http_result.WriteError(&w, models.BadRequest, "article with this id doesn't exist")
 		return
 	}
 
-	if dbArticle.Author != r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string) {
-		http_result.WriteError(&w, models.BadRequest, "you're not the author of this article")
+	if !canEdit(r, dbArticle) {
+		http_result.WriteError(&w, models.Forbidden, "you're not the author of this article")
 		return
 	}
 
-	articleUpdated := models.Article{
-		Id:          dbArticle.Id,
-		CustomId:    dbArticle.CustomId,
-		Author:      dbArticle.Author,
-		Name:        dto.Name,
-		IsDraft:     dto.IsDraft,
-		Thumbnail:   dto.Thumbnail,
-		ArticleData: dto.ArticleData,
+	// Only an article saved before versioning can be updated without knowing its version
+	if expectedVersion == 0 && dbArticle.Version != 0 {
+		http_result.WriteError(&w, models.BadRequest, "version or If-Match is required")
+		return
+	}
+
+	// The workflow fields aren't editable here, so they're kept from the stored article
+	articleUpdated := *dbArticle
+	articleUpdated.Name = dto.Name
+	articleUpdated.Thumbnail = dto.Thumbnail
+	articleUpdated.Tags = dto.Tags
+	articleUpdated.Category = dto.Category
+	articleUpdated.ArticleData = dto.ArticleData
+	articleUpdated.PublishAt = dto.PublishAt
+	articleUpdated.Version = expectedVersion
+
+	state := requestedState(dbArticle.EffectiveState(), dto.IsDraft, articleUpdated.IsScheduled(time.Now()))
+	// An approval only covers the content the reviewer has seen
+	role := models.RoleFromClaims(r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims))
+	if dbArticle.EffectiveState() == models.StateApproved && !api.workflow.CanApprove(role) && contentChanged(dbArticle, &articleUpdated) {
+		state = models.StateInReview
+	}
+	if !api.checkTransition(w, r, dbArticle.EffectiveState(), state) {
+		return
 	}
+	articleUpdated.SetState(state)
 
-	update := bson.D{{"$set", articleUpdated}}
+	if !api.addInitialRevision(w, r, dbArticle) {
+		return
+	}
 
-	if _, err := collection.UpdateOne(context.TODO(), findFilter, update); err != nil {
+	if err := api.articles.Update(r.Context(), &articleUpdated); err != nil {
+		if err == repositories.ErrConflict {
+			http_result.WriteError(&w, models.VersionConflict, "article was changed by someone else, reload it and try again")
+			return
+		}
 		http_result.WriteError(&w, models.InternalError, "internal error")
 		return
 	}
 
-	json.NewEncoder(w).Encode(models.ArticleDTO{
-		CustomId:    articleUpdated.CustomId,
-		Name:        articleUpdated.Name,
-		Author:      articleUpdated.Author,
-		IsDraft:     articleUpdated.IsDraft,
-		Thumbnail:   articleUpdated.Thumbnail,
-		ArticleData: articleUpdated.ArticleData,
-	})
+	revision := models.NewRevision(articleUpdated, r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string))
+	if err := api.revisions.Add(r.Context(), &revision); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(articleUpdated.Version))
+	json.NewEncoder(w).Encode(articleUpdated.ToDTO())
 }
 
-func FindOneHandler(w http.ResponseWriter, r *http.Request) {
+func (api *Api) FindOneHandler(w http.ResponseWriter, r *http.Request) {
 	var query models.FindOneExpression
 
 	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
@@ -156,28 +241,22 @@ func FindOneHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// region Validation
-	if query.Query == "" {
-		http_result.WriteError(&w, models.InvalidFieldLength, "empty query string")
+	if query.Query == "" || len(query.Query) > int(models.FindQueryMaxLimit) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("query is empty or its length > ", models.FindQueryMaxLimit))
 		return
 	}
 	// endregion Validation
 
-	collection := utils.Mongo.Database("shuryakDb").Collection("articles")
-
-	filter := bson.D{{"name", bson.M{"$regex": query.Query, "$options": "im"}}}
-
-	var result models.MetaArticle
-
-	err := collection.FindOne(context.TODO(), filter).Decode(&result)
-	if err != nil {
+	results, _, err := api.runSearch(r, search.Query{Text: query.Query, Count: 1})
+	if err != nil || len(results) == 0 {
 		http_result.WriteEmpty(&w)
 		return
 	}
 
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(results[0])
 }
 
-func FindManyHandler(w http.ResponseWriter, r *http.Request) {
+func (api *Api) FindManyHandler(w http.ResponseWriter, r *http.Request) {
 	var query models.FindManyExpression
 
 	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
@@ -186,8 +265,8 @@ func FindManyHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// region Validation
-	if query.Query == "" {
-		http_result.WriteError(&w, models.InvalidFieldLength, "empty query string")
+	if query.Query == "" || len(query.Query) > int(models.FindQueryMaxLimit) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("query is empty or its length > ", models.FindQueryMaxLimit))
 		return
 	}
 
@@ -197,73 +276,193 @@ func FindManyHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	// endregion Validation
 
-	collection := utils.Mongo.Database("shuryakDb").Collection("articles")
-
-	options := options.Find()
-	options.SetLimit(int64(query.Count))
-	options.SetSkip(int64(query.Offset))
-	filter := bson.D{{"name", bson.M{"$regex": query.Query, "$options": "im"}}}
+	searchQuery, ok := api.toSearchQuery(w, r, query)
+	if !ok {
+		return
+	}
 
-	cur, err := collection.Find(context.TODO(), filter, options)
+	results, found, err := api.runSearch(r, searchQuery)
 	if err != nil {
 		http_result.WriteEmpty(&w)
 		return
 	}
 
-	var results []*models.MetaArticle
+	if !query.IsPaged() {
+		json.NewEncoder(w).Encode(results)
+		return
+	}
 
-	for cur.Next(context.TODO()) {
-		var document models.MetaArticle
-		err := cur.Decode(&document)
-		if err != nil {
-			http_result.WriteError(&w, models.InternalError, "internal error")
-			return
-		}
+	page := models.SearchPageDTO{Results: results}
+	if page.NextCursor, err = nextSearchCursor(found); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+	if query.WithTotalCount {
+		total := int64(found.Total)
+		page.TotalCount = &total
+	}
+
+	json.NewEncoder(w).Encode(page)
+}
+
+// SearchHandler is findMany with the total number of matches and the facets
+func (api *Api) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	var query models.FindManyExpression
+
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if query.Query == "" || len(query.Query) > int(models.FindQueryMaxLimit) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("query is empty or its length > ", models.FindQueryMaxLimit))
+		return
+	}
+
+	if query.Count > uint(models.FindMaxLimit) {
+		http_result.WriteError(&w, models.BadRequest, fmt.Sprint("count > ", models.FindMaxLimit))
+		return
+	}
+	// endregion Validation
 
-		results = append(results, &document)
+	searchQuery, ok := api.toSearchQuery(w, r, query)
+	if !ok {
+		return
 	}
+	searchQuery.WithTotals = true
 
-	if err := cur.Err(); err != nil {
+	results, found, err := api.runSearch(r, searchQuery)
+	if err != nil {
 		http_result.WriteError(&w, models.InternalError, "internal error")
 		return
 	}
 
-	cur.Close(context.TODO())
+	facets := map[string][]models.FacetDTO{}
+	for field, values := range found.Facets {
+		facets[field] = []models.FacetDTO{}
+		for _, facet := range values {
+			facets[field] = append(facets[field], models.FacetDTO{Value: facet.Value, Count: facet.Count})
+		}
+	}
+
+	nextCursor, err := nextSearchCursor(found)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
 
-	json.NewEncoder(w).Encode(results)
+	json.NewEncoder(w).Encode(models.SearchResponseDTO{
+		Results:    results,
+		NextCursor: nextCursor,
+		TotalCount: found.Total,
+		Facets:     facets,
+	})
 }
 
-func GetByCustomIdHandler(w http.ResponseWriter, r *http.Request) {
-	var dto models.ArticleCustomIdDTO
+func (api *Api) GetByCustomIdHandler(w http.ResponseWriter, r *http.Request) {
+	var query models.GetArticleExpression
 
-	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
 		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
 		return
 	}
 
 	// region Validation
-	if len(dto.CustomId) < int(models.ArticleIdMinLimit) || len(dto.CustomId) > int(models.ArticleIdMaxLimit) {
+	if len(query.CustomId) < int(models.ArticleIdMinLimit) || len(query.CustomId) > int(models.ArticleIdMaxLimit) {
 		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("id length < ", models.ArticleIdMinLimit, " or > ", models.ArticleIdMaxLimit))
 		return
 	}
+
+	if query.Format != "" && query.Format != "json" && !renderer.IsFormat(query.Format) {
+		http_result.WriteError(&w, models.BadRequest, "format must be json, html, markdown or text")
+		return
+	}
 	// endregion Validation
 
-	collection := utils.Mongo.Database("shuryakDb").Collection("articles")
+	article, err := api.articles.FindByCustomId(r.Context(), query.CustomId)
+	if err != nil {
+		http_result.WriteError(&w, models.BadRequest, "Article with this id doesn't exist")
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(article.Version))
 
-	filter := bson.D{{"custom_id", dto.CustomId}}
+	// Authors reading their drafts aren't readers
+	if repositories.IsPublished(article, time.Now()) {
+		api.counter.Count(article.CustomId, viewerOf(r), r.UserAgent())
+	}
 
-	var result models.ArticleDTO
+	if query.Format == "" || query.Format == "json" {
+		json.NewEncoder(w).Encode(article.ToDTO())
+		return
+	}
 
-	err := collection.FindOne(context.TODO(), filter).Decode(&result)
+	content, err := renderer.Render(query.Format, article.ArticleData)
 	if err != nil {
-		http_result.WriteError(&w, models.BadRequest, "Article with this id doesn't exist")
+		http_result.WriteError(&w, models.InvalidDocument, "article_data can't be rendered")
 		return
 	}
 
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(models.RenderedArticleDTO{
+		MetaArticle: article.ToMeta(),
+		Version:     article.Version,
+		Format:      query.Format,
+		Content:     content,
+	})
 }
 
-func GetDraftsListHandler(w http.ResponseWriter, r *http.Request) {
+// applyListExpression narrows the filter by the filters and the sort of the query, the author of
+// the filter can't be changed. It writes the error itself.
+func (api *Api) applyListExpression(w http.ResponseWriter, r *http.Request, query models.GetListExpression, filter *repositories.ArticleFilter) bool {
+	// region Validation
+	if query.Sort != "" && !query.Sort.IsValid() {
+		http_result.WriteError(&w, models.BadRequest, "sort isn't one of newest, oldest, popularity, name")
+		return false
+	}
+
+	if query.Author != "" && (len(query.Author) < int(models.NicknameMinLimit) || len(query.Author) > int(models.NicknameMaxLimit)) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("author length < ", models.NicknameMinLimit, " or > ", models.NicknameMaxLimit))
+		return false
+	}
+
+	if query.PublishedFrom != nil && query.PublishedTo != nil && !query.PublishedFrom.Before(*query.PublishedTo) {
+		http_result.WriteError(&w, models.BadRequest, "published_from must be before published_to")
+		return false
+	}
+	// endregion Validation
+
+	if query.Author != "" {
+		if filter.Author != "" && filter.Author != query.Author {
+			http_result.WriteError(&w, models.Forbidden, "you can only list your own articles here")
+			return false
+		}
+		filter.Author = query.Author
+	}
+
+	filter.Tag = models.NormalizeTag(query.Tag)
+	if query.Tag != "" && filter.Tag == "" {
+		http_result.WriteError(&w, models.BadRequest, "invalid tag")
+		return false
+	}
+
+	var ok bool
+	if filter.Categories, ok = api.categoryFilter(w, r, query.Category); !ok {
+		return false
+	}
+
+	filter.PublishedFrom = query.PublishedFrom
+	filter.PublishedTo = query.PublishedTo
+	filter.HasThumbnail = query.HasThumbnail
+	filter.Sort = query.Sort
+	if filter.Sort == "" {
+		filter.Sort = models.SortNewest
+	}
+
+	return true
+}
+
+func (api *Api) GetDraftsListHandler(w http.ResponseWriter, r *http.Request) {
 	var query models.GetListExpression
 
 	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
@@ -278,47 +477,77 @@ func GetDraftsListHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	// endregion Validation
 
-	collection := utils.Mongo.Database("shuryakDb").Collection("articles")
+	filter := repositories.ArticleFilter{
+		Author:  r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string),
+		IsDraft: repositories.Bool(true),
+	}
+	if !api.applyListExpression(w, r, query, &filter) {
+		return
+	}
+
+	api.writeList(w, r, filter, query.PageExpression, query.Count, query.Offset)
+}
 
-	options := options.Find()
-	options.SetLimit(int64(query.Count))
-	options.SetSkip(int64(query.Offset))
+func (api *Api) GetListHandler(w http.ResponseWriter, r *http.Request) {
+	var query models.GetListExpression
 
-	filter := bson.D{{"$and", []bson.D{
-		bson.D{{"is_draft", true}},
-		bson.D{{"author", r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string)}},
-	}}}
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
 
-	cur, err := collection.Find(context.TODO(), filter, options)
-	if err != nil {
-		http_result.WriteEmpty(&w)
+	// region Validation
+	if query.Count > uint(models.FindMaxLimit) {
+		http_result.WriteError(&w, models.BadRequest, fmt.Sprint("count > ", models.FindMaxLimit))
 		return
 	}
+	// endregion Validation
 
-	var results []*models.MetaArticle
+	filter := publishedFilter()
+	if !api.applyListExpression(w, r, query, &filter) {
+		return
+	}
 
-	for cur.Next(context.TODO()) {
-		var document models.MetaArticle
-		err := cur.Decode(&document)
-		if err != nil {
-			http_result.WriteError(&w, models.InternalError, "internal error")
-			return
-		}
+	api.writeList(w, r, filter, query.PageExpression, query.Count, query.Offset)
+}
+
+func (api *Api) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	var dto models.ArticleCustomIdDTO
 
-		results = append(results, &document)
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
 	}
 
-	if err := cur.Err(); err != nil {
-		http_result.WriteError(&w, models.InternalError, "internal error")
+	// region Validation
+	if len(dto.CustomId) < int(models.ArticleIdMinLimit) || len(dto.CustomId) > int(models.ArticleIdMaxLimit) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("id length < ", models.ArticleIdMinLimit, " or > ", models.ArticleIdMaxLimit))
+		return
+	}
+	// endregion Validation
+
+	article, err := api.articles.FindByCustomId(r.Context(), dto.CustomId)
+	if err != nil {
+		http_result.WriteError(&w, models.BadRequest, "article with this id doesn't exist")
 		return
 	}
 
-	cur.Close(context.TODO())
+	if !canDelete(r, article) {
+		http_result.WriteError(&w, models.Forbidden, "you're not the author of this article")
+		return
+	}
+
+	deletedAt := time.Now()
+	if err := api.articles.SoftDelete(r.Context(), article.CustomId, deletedAt); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+	article.DeletedAt = &deletedAt
 
-	json.NewEncoder(w).Encode(results)
+	json.NewEncoder(w).Encode(article.ToMeta())
 }
 
-func GetListHandler(w http.ResponseWriter, r *http.Request) {
+func (api *Api) GetTrashHandler(w http.ResponseWriter, r *http.Request) {
 	var query models.GetListExpression
 
 	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
@@ -333,39 +562,174 @@ func GetListHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	// endregion Validation
 
-	collection := utils.Mongo.Database("shuryakDb").Collection("articles")
+	claims := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)
+
+	// Admins see the whole trash
+	filter := repositories.ArticleFilter{Deleted: true}
+	if !models.RoleFromClaims(claims).Includes(models.RoleAdmin) {
+		filter.Author = claims["nickname"].(string)
+	}
+	if !api.applyListExpression(w, r, query, &filter) {
+		return
+	}
 
-	options := options.Find()
-	options.SetLimit(int64(query.Count))
-	options.SetSkip(int64(query.Offset))
+	api.writeList(w, r, filter, query.PageExpression, query.Count, query.Offset)
+}
 
-	filter := bson.D{{"is_draft", false}}
+func (api *Api) RestoreHandler(w http.ResponseWriter, r *http.Request) {
+	var dto models.ArticleCustomIdDTO
 
-	cur, err := collection.Find(context.TODO(), filter, options)
-	if err != nil {
-		http_result.WriteEmpty(&w)
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
 		return
 	}
 
-	var results []*models.MetaArticle
+	// region Validation
+	if len(dto.CustomId) < int(models.ArticleIdMinLimit) || len(dto.CustomId) > int(models.ArticleIdMaxLimit) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("id length < ", models.ArticleIdMinLimit, " or > ", models.ArticleIdMaxLimit))
+		return
+	}
+	// endregion Validation
 
-	for cur.Next(context.TODO()) {
-		var document models.MetaArticle
-		err := cur.Decode(&document)
-		if err != nil {
-			http_result.WriteError(&w, models.InternalError, "internal error")
-			return
-		}
+	article, err := api.articles.FindDeletedByCustomId(r.Context(), dto.CustomId)
+	if err != nil {
+		http_result.WriteError(&w, models.BadRequest, "article with this id isn't in the trash")
+		return
+	}
 
-		results = append(results, &document)
+	if !canDelete(r, article) {
+		http_result.WriteError(&w, models.Forbidden, "you're not the author of this article")
+		return
+	}
+
+	// The name could be taken while the article was in the trash
+	if _, err := api.articles.FindByName(r.Context(), article.Name); err == nil {
+		http_result.WriteError(&w, models.NotUniqueData, "article with this name already exists")
+		return
 	}
 
-	if err := cur.Err(); err != nil {
+	if err := api.articles.Restore(r.Context(), article.CustomId); err != nil {
 		http_result.WriteError(&w, models.InternalError, "internal error")
 		return
 	}
+	article.DeletedAt = nil
+
+	json.NewEncoder(w).Encode(article.ToMeta())
+}
+
+// canEdit allows editors and admins to edit or unpublish any article, and authors only their own
+func canEdit(r *http.Request, article *models.Article) bool {
+	claims := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)
+
+	if models.RoleFromClaims(claims).Includes(models.RoleEditor) {
+		return true
+	}
+
+	return article.Author == claims["nickname"].(string)
+}
+
+// canDelete allows admins to delete or restore any article, and authors only their own
+func canDelete(r *http.Request, article *models.Article) bool {
+	claims := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)
+
+	if models.RoleFromClaims(claims).Includes(models.RoleAdmin) {
+		return true
+	}
+
+	return article.Author == claims["nickname"].(string)
+}
+
+// publishedFilter matches the articles readers can see
+func publishedFilter() repositories.ArticleFilter {
+	now := time.Now()
 
-	cur.Close(context.TODO())
+	return repositories.ArticleFilter{
+		IsDraft:     repositories.Bool(false),
+		PublishedBy: &now,
+	}
+}
+
+// toSearchQuery writes the error itself if the tag or the category is bad
+func (api *Api) toSearchQuery(w http.ResponseWriter, r *http.Request, query models.FindManyExpression) (search.Query, bool) {
+	searchQuery := search.Query{
+		Text:       query.Query,
+		Fuzzy:      query.Fuzzy,
+		Author:     query.Author,
+		Tag:        models.NormalizeTag(query.Tag),
+		Count:      query.Count,
+		Offset:     query.Offset,
+		WithTotals: query.WithTotalCount,
+	}
+
+	if query.Tag != "" && searchQuery.Tag == "" {
+		http_result.WriteError(&w, models.BadRequest, "invalid tag")
+		return searchQuery, false
+	}
+
+	if !applySearchCursor(w, query.PageExpression, &searchQuery) {
+		return searchQuery, false
+	}
+
+	// Pages need a size
+	if query.IsPaged() && searchQuery.Count == 0 {
+		searchQuery.Count = uint(models.FindMaxLimit)
+	}
+
+	var ok bool
+	searchQuery.Categories, ok = api.categoryFilter(w, r, query.Category)
+	return searchQuery, ok
+}
+
+// runSearch returns the articles of the hits in their order. An index can lag behind the articles
+// for a moment, so the hits that are gone or aren't published anymore are skipped.
+func (api *Api) runSearch(r *http.Request, query search.Query) ([]models.SearchResultDTO, *search.Results, error) {
+	found, err := api.index.Search(r.Context(), query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	customIds := make([]string, len(found.Hits))
+	for i, hit := range found.Hits {
+		customIds[i] = hit.Id
+	}
+
+	articles, err := api.articles.FindByCustomIds(r.Context(), customIds)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byCustomId := make(map[string]*models.Article, len(articles))
+	for i := range articles {
+		byCustomId[articles[i].CustomId] = &articles[i]
+	}
+
+	now := time.Now()
+	terms := search.Terms(query.Text)
+	results := []models.SearchResultDTO{}
+
+	for _, hit := range found.Hits {
+		article, exists := byCustomId[hit.Id]
+		if !exists || !repositories.IsPublished(article, now) {
+			continue
+		}
+
+		results = append(results, models.SearchResultDTO{
+			MetaArticle: article.ToMeta(),
+			Score:       hit.Score,
+			Snippet:     search.Snippet(article.SearchText, terms, snippetLength),
+		})
+	}
+
+	return results, found, nil
+}
+
+func toMetaList(articles []models.Article) []*models.MetaArticle {
+	var results []*models.MetaArticle
+
+	for i := range articles {
+		meta := articles[i].ToMeta()
+		results = append(results, &meta)
+	}
 
-	json.NewEncoder(w).Encode(results)
+	return results
 }
@@ -0,0 +1,33 @@
+package articles
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// The article version is used as a strong ETag
+
+func formatETag(version uint64) string {
+	return fmt.Sprintf("\"%d\"", version)
+}
+
+// parseIfMatch returns ok == false if there is no If-Match header or it matches any version
+func parseIfMatch(r *http.Request) (version uint64, ok bool, err error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, false, nil
+	}
+
+	if strings.HasPrefix(header, "W/") || strings.Contains(header, ",") {
+		return 0, false, fmt.Errorf("If-Match must contain exactly one strong ETag")
+	}
+
+	version, err = strconv.ParseUint(strings.Trim(header, "\""), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid If-Match ETag")
+	}
+
+	return version, true, nil
+}
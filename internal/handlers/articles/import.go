@@ -0,0 +1,64 @@
+package articles
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/shuryak/shuryak-backend/internal/markdown"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/utils/http-result"
+	"net/http"
+)
+
+func (api *Api) ImportMarkdownHandler(w http.ResponseWriter, r *http.Request) {
+	var query models.ImportMarkdownExpression
+
+	// The markdown is limited once it's decoded, the body limit leaves room for the JSON escapes
+	r.Body = http.MaxBytesReader(w, r.Body, 6*int64(models.ArticleMarkdownMaxSize)+1024)
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if query.Markdown == "" || len(query.Markdown) > int(models.ArticleMarkdownMaxSize) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("markdown is empty or its length > ", models.ArticleMarkdownMaxSize))
+		return
+	}
+	// endregion Validation
+
+	result := markdown.Import(query.Markdown)
+
+	// Bad tags aren't worth failing the import, they're dropped like the other unsupported constructs
+	warnings := []models.ImportWarning{}
+	tags := []string{}
+	for _, tag := range result.FrontMatter.Tags {
+		normalized, message := normalizeTags(append(tags, tag.Name))
+		if message != "" {
+			warnings = append(warnings, models.ImportWarning{Line: tag.Line, Message: message + ", it's ignored"})
+			continue
+		}
+		tags = normalized
+	}
+	warnings = append(warnings, result.Warnings...)
+
+	dto := models.ArticleDTO{
+		CustomId:    result.FrontMatter.Id,
+		Name:        result.FrontMatter.Name,
+		Thumbnail:   result.FrontMatter.Thumbnail,
+		Tags:        tags,
+		IsDraft:     query.IsDraft,
+		ArticleData: result.Document.ToData(),
+	}
+
+	if query.DryRun {
+		json.NewEncoder(w).Encode(models.ImportedArticleDTO{Article: dto, Warnings: warnings})
+		return
+	}
+
+	article, ok := api.create(w, r, dto)
+	if !ok {
+		return
+	}
+
+	json.NewEncoder(w).Encode(models.ImportedArticleDTO{Article: article.ToDTO(), Warnings: warnings})
+}
@@ -0,0 +1,64 @@
+package articles
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/shuryak/shuryak-backend/internal/renderer"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var pageTemplate = template.Must(template.New("article").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Name}}</title>
+</head>
+<body>
+<article>
+<h1>{{.Name}}</h1>
+{{.Content}}</article>
+</body>
+</html>
+`))
+
+// pageContentSecurityPolicy is a second line of defence, the rendered HTML is already sanitized.
+// Frames are limited to the hosts the renderer embeds.
+func pageContentSecurityPolicy() string {
+	frameSources := "'none'"
+	if hosts := renderer.EmbedHosts(); len(hosts) != 0 {
+		frameSources = "https://" + strings.Join(hosts, " https://")
+	}
+
+	return "default-src 'none'; img-src http: https:; frame-src " + frameSources + "; style-src 'unsafe-inline'"
+}
+
+// PageHandler serves a published article as a standalone HTML page
+func (api *Api) PageHandler(w http.ResponseWriter, r *http.Request) {
+	article, err := api.articles.FindByCustomId(r.Context(), mux.Vars(r)["id"])
+	if err != nil || !repositories.IsPublished(article, time.Now()) {
+		http.NotFound(w, r)
+		return
+	}
+
+	content, err := renderer.Render(renderer.FormatHTML, article.ArticleData)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", pageContentSecurityPolicy())
+	w.Header().Set("ETag", formatETag(article.Version))
+
+	pageTemplate.Execute(w, struct {
+		Name    string
+		Content template.HTML
+	}{
+		Name:    article.Name,
+		Content: template.HTML(content),
+	})
+}
@@ -0,0 +1,151 @@
+package articles
+
+import (
+	"encoding/json"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+	"github.com/shuryak/shuryak-backend/internal/search"
+	"github.com/shuryak/shuryak-backend/internal/utils"
+	"github.com/shuryak/shuryak-backend/internal/utils/http-result"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"time"
+)
+
+// listCursor is the position of the last article of a page in the sort the page was listed in
+type listCursor struct {
+	Sort        models.ArticleSort `json:"s"`
+	PublishedAt *time.Time         `json:"p,omitempty"`
+	ViewCount   int64              `json:"v,omitempty"`
+	Name        string             `json:"n,omitempty"`
+	Id          primitive.ObjectID `json:"i"`
+}
+
+// searchCursor is the last hit of a page, the next page starts after it wherever it is now. Only
+// when the hit is gone the page starts at its score, then a few matches may repeat or be skipped.
+type searchCursor struct {
+	Score float64 `json:"c,omitempty"`
+	Id    string  `json:"i"`
+}
+
+// writeList lists a page of the articles matching the filter, as a bare array unless the client asked
+// for a cursor or the total count. It writes the response or the error itself.
+func (api *Api) writeList(w http.ResponseWriter, r *http.Request, filter repositories.ArticleFilter, page models.PageExpression, count uint, offset uint) {
+	if !page.IsPaged() {
+		found, err := api.articles.List(r.Context(), filter, count, offset)
+		if err != nil {
+			http_result.WriteEmpty(&w)
+			return
+		}
+
+		json.NewEncoder(w).Encode(toMetaList(found))
+		return
+	}
+
+	result := models.MetaPageDTO{Results: []*models.MetaArticle{}}
+	if filter.Sort == "" {
+		filter.Sort = models.SortNewest
+	}
+
+	// The total doesn't depend on the page
+	if page.WithTotalCount {
+		total, err := api.articles.Count(r.Context(), filter)
+		if err != nil {
+			http_result.WriteError(&w, models.InternalError, "internal error")
+			return
+		}
+		result.TotalCount = &total
+	}
+
+	if page.Cursor != nil && *page.Cursor != "" {
+		if offset != 0 {
+			http_result.WriteError(&w, models.BadRequest, "offset can't be used with cursor")
+			return
+		}
+
+		var cursor listCursor
+		if err := utils.ParseCursor(*page.Cursor, &cursor); err != nil || cursor.Sort == "" {
+			http_result.WriteError(&w, models.BadRequest, "invalid cursor")
+			return
+		}
+		if cursor.Sort != filter.Sort {
+			http_result.WriteError(&w, models.BadRequest, "cursor belongs to a different sort")
+			return
+		}
+
+		filter.After = &repositories.ArticlePosition{
+			Sort:        cursor.Sort,
+			PublishedAt: cursor.PublishedAt,
+			ViewCount:   cursor.ViewCount,
+			Name:        cursor.Name,
+			Id:          cursor.Id,
+		}
+	}
+
+	if count == 0 {
+		count = uint(models.FindMaxLimit)
+	}
+
+	// One more article tells if there is a next page
+	found, err := api.articles.List(r.Context(), filter, count+1, offset)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	if uint(len(found)) > count {
+		found = found[:count]
+		position := repositories.PositionOf(&found[count-1], filter.Sort)
+
+		next := listCursor{
+			Sort:        position.Sort,
+			PublishedAt: position.PublishedAt,
+			ViewCount:   position.ViewCount,
+			Name:        position.Name,
+			Id:          position.Id,
+		}
+		if result.NextCursor, err = utils.SignCursor(next); err != nil {
+			http_result.WriteError(&w, models.InternalError, "internal error")
+			return
+		}
+	}
+
+	for i := range found {
+		meta := found[i].ToMeta()
+		result.Results = append(result.Results, &meta)
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
+// applySearchCursor moves the query to the page of the cursor, it writes the error itself
+func applySearchCursor(w http.ResponseWriter, page models.PageExpression, query *search.Query) bool {
+	if page.Cursor == nil || *page.Cursor == "" {
+		return true
+	}
+
+	if query.Offset != 0 {
+		http_result.WriteError(&w, models.BadRequest, "offset can't be used with cursor")
+		return false
+	}
+
+	var cursor searchCursor
+	if err := utils.ParseCursor(*page.Cursor, &cursor); err != nil || cursor.Id == "" {
+		http_result.WriteError(&w, models.BadRequest, "invalid cursor")
+		return false
+	}
+
+	query.After = &search.Hit{Id: cursor.Id, Score: cursor.Score}
+
+	return true
+}
+
+// nextSearchCursor returns the cursor of the page after the found one, or an empty string on the last page
+func nextSearchCursor(found *search.Results) (string, error) {
+	if !found.More || len(found.Hits) == 0 {
+		return "", nil
+	}
+
+	last := found.Hits[len(found.Hits)-1]
+	return utils.SignCursor(searchCursor{Score: last.Score, Id: last.Id})
+}
@@ -0,0 +1,125 @@
+package articles
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+	"github.com/shuryak/shuryak-backend/internal/utils/http-result"
+	"net/http"
+	"time"
+)
+
+// ReactHandler is idempotent, reacting again with the same kind changes nothing
+func (api *Api) ReactHandler(w http.ResponseWriter, r *http.Request) {
+	query, ok := decodeReactExpression(w, r)
+	if !ok {
+		return
+	}
+
+	article, err := api.articles.FindByCustomId(r.Context(), query.CustomId)
+	if err != nil || !repositories.IsPublished(article, time.Now()) {
+		http_result.WriteError(&w, models.BadRequest, "published article with this id doesn't exist")
+		return
+	}
+
+	nickname := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string)
+
+	added, err := api.reactions.Add(r.Context(), &models.Reaction{
+		ArticleId: article.CustomId,
+		Nickname:  nickname,
+		Kind:      query.Kind,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	// A count left off by a failure here is fixed by the reaction recount job
+	if added {
+		if err := api.articles.AddReaction(r.Context(), article.CustomId, query.Kind, 1); err != nil {
+			http_result.WriteError(&w, models.InternalError, "internal error")
+			return
+		}
+	}
+
+	api.writeReactions(w, r, article.CustomId, nickname)
+}
+
+// UnreactHandler is idempotent like ReactHandler, it also works on articles that are no longer published
+func (api *Api) UnreactHandler(w http.ResponseWriter, r *http.Request) {
+	query, ok := decodeReactExpression(w, r)
+	if !ok {
+		return
+	}
+
+	article, err := api.articles.FindByCustomId(r.Context(), query.CustomId)
+	if err != nil {
+		http_result.WriteError(&w, models.BadRequest, "article with this id doesn't exist")
+		return
+	}
+
+	nickname := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string)
+
+	removed, err := api.reactions.Remove(r.Context(), article.CustomId, nickname, query.Kind)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	if removed {
+		if err := api.articles.AddReaction(r.Context(), article.CustomId, query.Kind, -1); err != nil {
+			http_result.WriteError(&w, models.InternalError, "internal error")
+			return
+		}
+	}
+
+	api.writeReactions(w, r, article.CustomId, nickname)
+}
+
+// decodeReactExpression writes the error itself
+func decodeReactExpression(w http.ResponseWriter, r *http.Request) (models.ReactExpression, bool) {
+	var query models.ReactExpression
+
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return query, false
+	}
+
+	// region Validation
+	if len(query.CustomId) < int(models.ArticleIdMinLimit) || len(query.CustomId) > int(models.ArticleIdMaxLimit) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("id length < ", models.ArticleIdMinLimit, " or > ", models.ArticleIdMaxLimit))
+		return query, false
+	}
+
+	if !query.Kind.IsValid() {
+		http_result.WriteError(&w, models.BadRequest, "kind must be like, love, insightful, funny or sad")
+		return query, false
+	}
+	// endregion Validation
+
+	return query, true
+}
+
+// writeReactions writes the counts of the article after the change and the kinds of the user
+func (api *Api) writeReactions(w http.ResponseWriter, r *http.Request, customId string, nickname string) {
+	article, err := api.articles.FindByCustomId(r.Context(), customId)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	kinds, err := api.reactions.Kinds(r.Context(), customId, nickname)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(models.ReactionsDTO{
+		Id:        article.CustomId,
+		Reactions: article.ToMeta().Reactions,
+		Mine:      kinds,
+	})
+}
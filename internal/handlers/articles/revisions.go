@@ -0,0 +1,197 @@
+package articles
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/shuryak/shuryak-backend/internal/blocks"
+	"github.com/shuryak/shuryak-backend/internal/jsondiff"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+	"github.com/shuryak/shuryak-backend/internal/utils/http-result"
+	"net/http"
+)
+
+func (api *Api) GetRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	var query models.GetRevisionsExpression
+
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if query.Count > uint(models.FindMaxLimit) {
+		http_result.WriteError(&w, models.BadRequest, fmt.Sprint("count > ", models.FindMaxLimit))
+		return
+	}
+	// endregion Validation
+
+	if _, ok := api.findEditable(w, r, query.CustomId); !ok {
+		return
+	}
+
+	revisions, err := api.revisions.List(r.Context(), query.CustomId, query.Count, query.Offset)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	results := []models.MetaRevisionDTO{}
+	for _, revision := range revisions {
+		results = append(results, revision.ToMeta())
+	}
+
+	json.NewEncoder(w).Encode(results)
+}
+
+func (api *Api) GetRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	var query models.RevisionExpression
+
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	if _, ok := api.findEditable(w, r, query.CustomId); !ok {
+		return
+	}
+
+	revision, err := api.revisions.Find(r.Context(), query.CustomId, query.Number)
+	if err != nil {
+		http_result.WriteError(&w, models.BadRequest, "revision with this number doesn't exist")
+		return
+	}
+
+	json.NewEncoder(w).Encode(revision.ToDTO())
+}
+
+func (api *Api) DiffRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	var query models.DiffRevisionsExpression
+
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	if _, ok := api.findEditable(w, r, query.CustomId); !ok {
+		return
+	}
+
+	from, err := api.revisions.Find(r.Context(), query.CustomId, query.From)
+	if err != nil {
+		http_result.WriteError(&w, models.BadRequest, "revision with the from number doesn't exist")
+		return
+	}
+
+	to, err := api.revisions.Find(r.Context(), query.CustomId, query.To)
+	if err != nil {
+		http_result.WriteError(&w, models.BadRequest, "revision with the to number doesn't exist")
+		return
+	}
+
+	json.NewEncoder(w).Encode(models.RevisionsDiffDTO{
+		CustomId: query.CustomId,
+		From:     from.Number,
+		To:       to.Number,
+		Changes:  jsondiff.Diff("", from.Content(), to.Content()),
+	})
+}
+
+// RollbackHandler doesn't rewrite the history, it makes a new revision with the old content
+func (api *Api) RollbackHandler(w http.ResponseWriter, r *http.Request) {
+	var query models.RevisionExpression
+
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	article, ok := api.findEditable(w, r, query.CustomId)
+	if !ok {
+		return
+	}
+
+	revision, err := api.revisions.Find(r.Context(), query.CustomId, query.Number)
+	if err != nil {
+		http_result.WriteError(&w, models.BadRequest, "revision with this number doesn't exist")
+		return
+	}
+
+	// Revisions made before the block schema was introduced can't be rolled back to
+	if fieldErrors := blocks.Validate(revision.ArticleData); len(fieldErrors) != 0 {
+		http_result.WriteFieldErrors(&w, models.InvalidDocument, "revision has invalid article_data", fieldErrors)
+		return
+	}
+
+	// The old name could be taken by another article since then
+	if sameName, err := api.articles.FindByName(r.Context(), revision.Name); err == nil && sameName.CustomId != article.CustomId {
+		http_result.WriteError(&w, models.NotUniqueData, "article with this name already exists")
+		return
+	}
+
+	article.Name = revision.Name
+	article.Thumbnail = revision.Thumbnail
+	article.ArticleData = revision.ArticleData
+
+	if err := api.articles.Update(r.Context(), article); err != nil {
+		if err == repositories.ErrConflict {
+			http_result.WriteError(&w, models.VersionConflict, "article was changed by someone else, try again")
+			return
+		}
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	newRevision := models.NewRevision(*article, r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string))
+	if err := api.revisions.Add(r.Context(), &newRevision); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(article.Version))
+	json.NewEncoder(w).Encode(article.ToDTO())
+}
+
+// findEditable writes the error itself if the article doesn't exist or the user can't edit it
+func (api *Api) findEditable(w http.ResponseWriter, r *http.Request, customId string) (*models.Article, bool) {
+	if len(customId) < int(models.ArticleIdMinLimit) || len(customId) > int(models.ArticleIdMaxLimit) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("id length < ", models.ArticleIdMinLimit, " or > ", models.ArticleIdMaxLimit))
+		return nil, false
+	}
+
+	article, err := api.articles.FindByCustomId(r.Context(), customId)
+	if err != nil {
+		http_result.WriteError(&w, models.BadRequest, "article with this id doesn't exist")
+		return nil, false
+	}
+
+	if !canEdit(r, article) {
+		http_result.WriteError(&w, models.Forbidden, "you're not the author of this article")
+		return nil, false
+	}
+
+	return article, true
+}
+
+// addInitialRevision stores the current content of an article created before the revisions were
+// introduced, so that its first update doesn't overwrite it without a trace. It writes the error itself.
+func (api *Api) addInitialRevision(w http.ResponseWriter, r *http.Request, article *models.Article) bool {
+	existing, err := api.revisions.List(r.Context(), article.CustomId, 1, 0)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return false
+	}
+
+	if len(existing) != 0 {
+		return true
+	}
+
+	revision := models.NewRevision(*article, article.Author)
+	if err := api.revisions.Add(r.Context(), &revision); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,151 @@
+package articles
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+	"github.com/shuryak/shuryak-backend/internal/utils/http-result"
+	"net/http"
+	"unicode/utf8"
+)
+
+func (api *Api) GetPopularTagsHandler(w http.ResponseWriter, r *http.Request) {
+	var query models.GetPopularTagsExpression
+
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if query.Count > uint(models.PopularTagsMaxLimit) {
+		http_result.WriteError(&w, models.BadRequest, fmt.Sprint("count > ", models.PopularTagsMaxLimit))
+		return
+	}
+	// endregion Validation
+
+	count := query.Count
+	if count == 0 {
+		count = uint(models.PopularTagsMaxLimit)
+	}
+
+	found, err := api.articles.PopularTags(r.Context(), publishedFilter(), count)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	results := []models.TagDTO{}
+	for _, tag := range found {
+		results = append(results, models.TagDTO{Tag: tag.Tag, Count: tag.Count})
+	}
+
+	json.NewEncoder(w).Encode(results)
+}
+
+func (api *Api) GetByTagHandler(w http.ResponseWriter, r *http.Request) {
+	var query models.GetByTagExpression
+
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	tag := models.NormalizeTag(query.Tag)
+	if tag == "" {
+		http_result.WriteError(&w, models.BadRequest, "invalid tag")
+		return
+	}
+
+	if query.Count > uint(models.FindMaxLimit) {
+		http_result.WriteError(&w, models.BadRequest, fmt.Sprint("count > ", models.FindMaxLimit))
+		return
+	}
+	// endregion Validation
+
+	filter := publishedFilter()
+	filter.Tag = tag
+
+	api.writeList(w, r, filter, query.PageExpression, query.Count, query.Offset)
+}
+
+// normalizeTags returns the tags as slugs without duplicates, or the message about the first bad tag
+func normalizeTags(tags []string) ([]string, string) {
+	normalized := []string{}
+
+	for _, tag := range tags {
+		slug := models.NormalizeTag(tag)
+		if slug == "" || utf8.RuneCountInString(slug) > int(models.TagMaxLimit) {
+			return nil, fmt.Sprintf("tag %q is empty or its length > %d", tag, models.TagMaxLimit)
+		}
+
+		if !contains(normalized, slug) {
+			normalized = append(normalized, slug)
+		}
+	}
+
+	if len(normalized) > int(models.ArticleTagsMaxCount) {
+		return nil, fmt.Sprint("number of tags > ", models.ArticleTagsMaxCount)
+	}
+
+	return normalized, ""
+}
+
+// checkTaxonomy normalizes the tags of the article and checks that its category exists,
+// it writes the error itself
+func (api *Api) checkTaxonomy(w http.ResponseWriter, r *http.Request, dto *models.ArticleDTO) bool {
+	tags, message := normalizeTags(dto.Tags)
+	if message != "" {
+		http_result.WriteError(&w, models.BadRequest, message)
+		return false
+	}
+	dto.Tags = tags
+
+	if dto.Category == "" {
+		return true
+	}
+
+	if _, err := api.categories.FindBySlug(r.Context(), dto.Category); err != nil {
+		if err == repositories.ErrNotFound {
+			http_result.WriteError(&w, models.BadRequest, "category with this id doesn't exist")
+			return false
+		}
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return false
+	}
+
+	return true
+}
+
+// categoryFilter returns the category with its subcategories for ArticleFilter.Categories,
+// it writes the error itself
+func (api *Api) categoryFilter(w http.ResponseWriter, r *http.Request, slug string) ([]string, bool) {
+	if slug == "" {
+		return nil, true
+	}
+
+	categories, err := api.categories.List(r.Context())
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return nil, false
+	}
+
+	if models.CategoryDepth(categories, slug) == 0 {
+		http_result.WriteError(&w, models.BadRequest, "category with this id doesn't exist")
+		return nil, false
+	}
+
+	return models.CategoryWithDescendants(categories, slug), true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
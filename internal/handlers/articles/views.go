@@ -0,0 +1,127 @@
+package articles
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+	"github.com/shuryak/shuryak-backend/internal/utils"
+	"github.com/shuryak/shuryak-backend/internal/utils/http-result"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func (api *Api) GetPopularHandler(w http.ResponseWriter, r *http.Request) {
+	var query models.GetPopularExpression
+
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	if query.Period == "" {
+		query.Period = "day"
+	}
+
+	// region Validation
+	period, exists := models.PopularPeriods[query.Period]
+	if !exists {
+		http_result.WriteError(&w, models.BadRequest, "period must be day, week or month")
+		return
+	}
+
+	if query.Count > uint(models.FindMaxLimit) {
+		http_result.WriteError(&w, models.BadRequest, fmt.Sprint("count > ", models.FindMaxLimit))
+		return
+	}
+	// endregion Validation
+
+	count := query.Count
+	if count == 0 {
+		count = uint(models.FindMaxLimit)
+	}
+
+	results, err := api.popularArticles(r, period, count)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(results)
+}
+
+// popularArticles returns up to count of the most viewed published articles. Some of the viewed articles
+// may have been unpublished or deleted since, so the ranking is read further until the page is full or
+// the ranking ends.
+func (api *Api) popularArticles(r *http.Request, period models.PopularPeriod, count uint) ([]models.PopularArticleDTO, error) {
+	now := time.Now()
+	results := []models.PopularArticleDTO{}
+	checked := make(map[string]bool)
+
+	for limit := 2 * count; ; limit *= 2 {
+		ranked, err := api.views.Popular(r.Context(), now.Add(-period.Window), period.HalfLife, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		// The earlier reads are the start of this one
+		var unchecked []repositories.ArticleViews
+		var customIds []string
+		for _, views := range ranked {
+			if !checked[views.ArticleId] {
+				checked[views.ArticleId] = true
+				unchecked = append(unchecked, views)
+				customIds = append(customIds, views.ArticleId)
+			}
+		}
+
+		found, err := api.articles.FindByCustomIds(r.Context(), customIds)
+		if err != nil {
+			return nil, err
+		}
+
+		articlesById := make(map[string]*models.Article, len(found))
+		for i := range found {
+			articlesById[found[i].CustomId] = &found[i]
+		}
+
+		for _, views := range unchecked {
+			if uint(len(results)) == count {
+				return results, nil
+			}
+
+			article, exists := articlesById[views.ArticleId]
+			if !exists || !repositories.IsPublished(article, now) {
+				continue
+			}
+
+			results = append(results, models.PopularArticleDTO{MetaArticle: article.ToMeta(), Score: views.Score})
+		}
+
+		if uint(len(results)) == count || uint(len(ranked)) < limit {
+			return results, nil
+		}
+	}
+}
+
+// viewerOf identifies the reader for the view counter, signed in readers by the nickname and the others
+// by the address and the user agent. The token isn't checked for revocation, a view is all it gives.
+func viewerOf(r *http.Request) string {
+	headerParts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(headerParts) == 2 && headerParts[0] == "Bearer" {
+		if claims, _, err := utils.GetClaimsFromToken(headerParts[1], utils.AccessTokenType); err == nil {
+			if nickname, ok := claims["nickname"].(string); ok {
+				return "user:" + nickname
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "guest:" + host + "\x00" + r.UserAgent()
+}
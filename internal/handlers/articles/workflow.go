@@ -0,0 +1,307 @@
+package articles
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/shuryak/shuryak-backend/internal/jsondiff"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+	"github.com/shuryak/shuryak-backend/internal/utils/http-result"
+	"net/http"
+	"time"
+)
+
+func (api *Api) SubmitForReviewHandler(w http.ResponseWriter, r *http.Request) {
+	var query models.ReviewExpression
+
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	article, ok := api.findEditable(w, r, query.CustomId)
+	if !ok {
+		return
+	}
+
+	// region Validation
+	if len(query.Comment) > int(models.ReviewCommentMaxLimit) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("comment length > ", models.ReviewCommentMaxLimit))
+		return
+	}
+
+	if query.Reviewer != "" {
+		reviewer, err := api.users.FindByNickname(r.Context(), query.Reviewer)
+		if err != nil {
+			http_result.WriteError(&w, models.BadRequest, "reviewer with this nickname doesn't exist")
+			return
+		}
+
+		if !reviewer.EffectiveRole().Includes(models.RoleEditor) {
+			http_result.WriteError(&w, models.BadRequest, "reviewer must be an editor")
+			return
+		}
+
+		if reviewer.Nickname == article.Author {
+			http_result.WriteError(&w, models.BadRequest, "author can't review their own article")
+			return
+		}
+	}
+	// endregion Validation
+
+	// A resubmitted article stays with its reviewer unless another one is assigned
+	if query.Reviewer != "" {
+		article.Reviewer = query.Reviewer
+	}
+
+	if !api.moveTo(w, r, article, models.StateInReview, query.Comment) {
+		return
+	}
+
+	json.NewEncoder(w).Encode(article.ToReviewDTO())
+}
+
+func (api *Api) ApproveHandler(w http.ResponseWriter, r *http.Request) {
+	var query models.ReviewExpression
+
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if len(query.Comment) > int(models.ReviewCommentMaxLimit) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("comment length > ", models.ReviewCommentMaxLimit))
+		return
+	}
+	// endregion Validation
+
+	article, ok := api.findReviewable(w, r, query.CustomId)
+	if !ok {
+		return
+	}
+
+	// Editors may publish a draft of their own directly, but approving is only for reviews
+	if article.EffectiveState() != models.StateInReview {
+		http_result.WriteError(&w, models.InvalidTransition, "article isn't in review")
+		return
+	}
+
+	if !api.moveTo(w, r, article, models.StateApproved, query.Comment) {
+		return
+	}
+
+	json.NewEncoder(w).Encode(article.ToReviewDTO())
+}
+
+func (api *Api) RequestChangesHandler(w http.ResponseWriter, r *http.Request) {
+	var query models.ReviewExpression
+
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if query.Comment == "" || len(query.Comment) > int(models.ReviewCommentMaxLimit) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("comment is empty or its length > ", models.ReviewCommentMaxLimit))
+		return
+	}
+	// endregion Validation
+
+	article, ok := api.findReviewable(w, r, query.CustomId)
+	if !ok {
+		return
+	}
+
+	// Only an article in review goes back to the author this way
+	if article.EffectiveState() != models.StateInReview {
+		http_result.WriteError(&w, models.InvalidTransition, "article isn't in review")
+		return
+	}
+
+	if !api.moveTo(w, r, article, models.StateDraft, query.Comment) {
+		return
+	}
+
+	json.NewEncoder(w).Encode(article.ToReviewDTO())
+}
+
+func (api *Api) PublishHandler(w http.ResponseWriter, r *http.Request) {
+	var dto models.ArticleCustomIdDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	article, ok := api.findEditable(w, r, dto.CustomId)
+	if !ok {
+		return
+	}
+
+	if article.IsScheduled(time.Now()) {
+		http_result.WriteError(&w, models.BadRequest, "article is scheduled, change publish_at to publish it now")
+		return
+	}
+
+	if !api.moveTo(w, r, article, models.StatePublished, "") {
+		return
+	}
+
+	json.NewEncoder(w).Encode(article.ToReviewDTO())
+}
+
+func (api *Api) ArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	var dto models.ArticleCustomIdDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	article, ok := api.findEditable(w, r, dto.CustomId)
+	if !ok {
+		return
+	}
+
+	if !api.moveTo(w, r, article, models.StateArchived, "") {
+		return
+	}
+
+	json.NewEncoder(w).Encode(article.ToReviewDTO())
+}
+
+func (api *Api) GetReviewHandler(w http.ResponseWriter, r *http.Request) {
+	var dto models.ArticleCustomIdDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if len(dto.CustomId) < int(models.ArticleIdMinLimit) || len(dto.CustomId) > int(models.ArticleIdMaxLimit) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("id length < ", models.ArticleIdMinLimit, " or > ", models.ArticleIdMaxLimit))
+		return
+	}
+	// endregion Validation
+
+	article, err := api.articles.FindByCustomId(r.Context(), dto.CustomId)
+	if err != nil {
+		http_result.WriteError(&w, models.BadRequest, "article with this id doesn't exist")
+		return
+	}
+
+	// Review comments are only for the author and the editors
+	if !canEdit(r, article) {
+		http_result.WriteError(&w, models.Forbidden, "you're not the author of this article")
+		return
+	}
+
+	json.NewEncoder(w).Encode(article.ToReviewDTO())
+}
+
+// requestedState maps is_draft of articles.create and articles.update onto the workflow,
+// an article that should be published but is scheduled waits for the scheduler in the approved state.
+// That includes a published article moved to a later publish_at, it isn't published until then.
+func requestedState(current models.ArticleState, isDraft bool, scheduled bool) models.ArticleState {
+	if isDraft {
+		if current == models.StatePublished {
+			return models.StateDraft
+		}
+		return current
+	}
+
+	if !scheduled {
+		return models.StatePublished
+	}
+
+	return models.StateApproved
+}
+
+// contentChanged compares what the revisions keep
+func contentChanged(from *models.Article, to *models.Article) bool {
+	return len(jsondiff.Diff("", from.Content(), to.Content())) != 0
+}
+
+// checkTransition writes the error itself if the workflow doesn't allow the transition for the user
+func (api *Api) checkTransition(w http.ResponseWriter, r *http.Request, from models.ArticleState, to models.ArticleState) bool {
+	role := models.RoleFromClaims(r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims))
+
+	if api.workflow.CanTransition(from, to, role) {
+		return true
+	}
+
+	if api.workflow.CanTransition(from, to, models.RoleAdmin) {
+		http_result.WriteError(&w, models.Forbidden, fmt.Sprint("your role doesn't allow moving the article from ", from, " to ", to))
+		return false
+	}
+
+	http_result.WriteError(&w, models.InvalidTransition, fmt.Sprint("article can't be moved from ", from, " to ", to))
+	return false
+}
+
+// moveTo saves the article in the new state with an optional review comment, it writes the error itself
+func (api *Api) moveTo(w http.ResponseWriter, r *http.Request, article *models.Article, state models.ArticleState, comment string) bool {
+	if !api.checkTransition(w, r, article.EffectiveState(), state) {
+		return false
+	}
+
+	if comment != "" {
+		article.ReviewComments = append(article.ReviewComments, models.ReviewComment{
+			Author:    r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string),
+			Text:      comment,
+			State:     state,
+			CreatedAt: time.Now(),
+		})
+	}
+	article.SetState(state)
+
+	if err := api.articles.Update(r.Context(), article); err != nil {
+		if err == repositories.ErrConflict {
+			http_result.WriteError(&w, models.VersionConflict, "article was changed by someone else, try again")
+			return false
+		}
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return false
+	}
+
+	w.Header().Set("ETag", formatETag(article.Version))
+	return true
+}
+
+// findReviewable writes the error itself if the article doesn't exist or the user can't review it,
+// admins can review any article, editors only the ones assigned to them or to nobody and not their own
+func (api *Api) findReviewable(w http.ResponseWriter, r *http.Request, customId string) (*models.Article, bool) {
+	if len(customId) < int(models.ArticleIdMinLimit) || len(customId) > int(models.ArticleIdMaxLimit) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("id length < ", models.ArticleIdMinLimit, " or > ", models.ArticleIdMaxLimit))
+		return nil, false
+	}
+
+	article, err := api.articles.FindByCustomId(r.Context(), customId)
+	if err != nil {
+		http_result.WriteError(&w, models.BadRequest, "article with this id doesn't exist")
+		return nil, false
+	}
+
+	claims := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)
+	if models.RoleFromClaims(claims).Includes(models.RoleAdmin) {
+		return article, true
+	}
+
+	nickname := claims["nickname"].(string)
+
+	if article.Author == nickname {
+		http_result.WriteError(&w, models.Forbidden, "you can't review your own article")
+		return nil, false
+	}
+
+	if article.Reviewer != "" && article.Reviewer != nickname {
+		http_result.WriteError(&w, models.Forbidden, "article is assigned to another reviewer")
+		return nil, false
+	}
+
+	return article, true
+}
@@ -0,0 +1,435 @@
+package bookmarks
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+	"github.com/shuryak/shuryak-backend/internal/utils/http-result"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type Api struct {
+	bookmarks    repositories.BookmarkRepository
+	readingLists repositories.ReadingListRepository
+	articles     repositories.ArticleRepository
+}
+
+func NewApi(bookmarks repositories.BookmarkRepository, readingLists repositories.ReadingListRepository, articles repositories.ArticleRepository) *Api {
+	return &Api{bookmarks: bookmarks, readingLists: readingLists, articles: articles}
+}
+
+// AddHandler is idempotent, adding an article that is already in the list keeps its place
+func (api *Api) AddHandler(w http.ResponseWriter, r *http.Request) {
+	var dto models.BookmarkDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if !validateArticleId(w, dto.ArticleId) {
+		return
+	}
+	// endregion Validation
+
+	nickname := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string)
+
+	listId, ok := api.findList(w, r, dto.ListId, nickname)
+	if !ok {
+		return
+	}
+
+	article, err := api.articles.FindByCustomId(r.Context(), dto.ArticleId)
+	if err != nil || !repositories.IsPublished(article, time.Now()) {
+		http_result.WriteError(&w, models.BadRequest, "published article with this id doesn't exist")
+		return
+	}
+
+	count, err := api.bookmarks.Count(r.Context(), nickname, listId)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	if count >= int64(models.ReadingListMaxArticles) {
+		http_result.WriteError(&w, models.BadRequest, fmt.Sprint("list can't have more than ", models.ReadingListMaxArticles, " articles"))
+		return
+	}
+
+	_, err = api.bookmarks.Add(r.Context(), &models.Bookmark{
+		Nickname:  nickname,
+		ListId:    listId,
+		ArticleId: article.CustomId,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(dto)
+}
+
+// RemoveHandler is idempotent like AddHandler, it also works on articles that are no longer available
+func (api *Api) RemoveHandler(w http.ResponseWriter, r *http.Request) {
+	var dto models.BookmarkDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if !validateArticleId(w, dto.ArticleId) {
+		return
+	}
+	// endregion Validation
+
+	nickname := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string)
+
+	listId, ok := api.findList(w, r, dto.ListId, nickname)
+	if !ok {
+		return
+	}
+
+	if _, err := api.bookmarks.Remove(r.Context(), nickname, listId, dto.ArticleId); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(dto)
+}
+
+// GetListHandler lists the articles of the plain bookmarks or of a reading list in the order set by the user
+func (api *Api) GetListHandler(w http.ResponseWriter, r *http.Request) {
+	var query models.GetBookmarksExpression
+
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if query.Count > uint(models.FindMaxLimit) {
+		http_result.WriteError(&w, models.BadRequest, fmt.Sprint("count > ", models.FindMaxLimit))
+		return
+	}
+	// endregion Validation
+
+	nickname := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string)
+
+	listId, ok := api.findList(w, r, query.ListId, nickname)
+	if !ok {
+		return
+	}
+
+	count := query.Count
+	if count == 0 {
+		count = uint(models.FindMaxLimit)
+	}
+
+	bookmarks, err := api.bookmarks.List(r.Context(), nickname, listId, count, query.Offset)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	now := time.Now()
+	results := []models.BookmarkedArticleDTO{}
+
+	for _, bookmark := range bookmarks {
+		// The cleanup runs after the article is saved, so it can lag behind
+		article, err := api.articles.FindByCustomId(r.Context(), bookmark.ArticleId)
+		if err == repositories.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			http_result.WriteError(&w, models.InternalError, "internal error")
+			return
+		}
+		if !repositories.IsPublished(article, now) {
+			continue
+		}
+
+		results = append(results, models.BookmarkedArticleDTO{MetaArticle: article.ToMeta(), AddedAt: bookmark.CreatedAt})
+	}
+
+	json.NewEncoder(w).Encode(results)
+}
+
+// ReorderHandler takes all the articles of the list in the new order, so that a client with a stale
+// copy of the list can't lose articles added elsewhere
+func (api *Api) ReorderHandler(w http.ResponseWriter, r *http.Request) {
+	var dto models.ReorderBookmarksDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	nickname := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string)
+
+	listId, ok := api.findList(w, r, dto.ListId, nickname)
+	if !ok {
+		return
+	}
+
+	bookmarks, err := api.bookmarks.List(r.Context(), nickname, listId, 0, 0)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	// region Validation
+	current := make(map[string]bool)
+	for _, bookmark := range bookmarks {
+		current[bookmark.ArticleId] = true
+	}
+
+	seen := make(map[string]bool)
+	for _, articleId := range dto.Articles {
+		if !current[articleId] || seen[articleId] {
+			http_result.WriteError(&w, models.BadRequest, "articles must be the articles of the list, each once")
+			return
+		}
+		seen[articleId] = true
+	}
+
+	if len(seen) != len(current) {
+		http_result.WriteError(&w, models.BadRequest, "articles must be the articles of the list, each once")
+		return
+	}
+	// endregion Validation
+
+	if err := api.bookmarks.Reorder(r.Context(), nickname, listId, dto.Articles); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(dto)
+}
+
+func (api *Api) CreateListHandler(w http.ResponseWriter, r *http.Request) {
+	var dto models.CreateReadingListDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if !validateName(w, dto.Name) {
+		return
+	}
+	// endregion Validation
+
+	nickname := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string)
+
+	lists, err := api.readingLists.ListByNickname(r.Context(), nickname)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	if len(lists) >= int(models.ReadingListsMaxCount) {
+		http_result.WriteError(&w, models.BadRequest, fmt.Sprint("you can't have more than ", models.ReadingListsMaxCount, " reading lists"))
+		return
+	}
+
+	if !checkNameIsFree(w, lists, dto.Name, primitive.NilObjectID) {
+		return
+	}
+
+	list := models.ReadingList{Nickname: nickname, Name: strings.TrimSpace(dto.Name), CreatedAt: time.Now()}
+	if err := api.readingLists.Create(r.Context(), &list); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(toListDTO(list, 0))
+}
+
+func (api *Api) RenameListHandler(w http.ResponseWriter, r *http.Request) {
+	var dto models.RenameReadingListDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if !validateName(w, dto.Name) {
+		return
+	}
+	// endregion Validation
+
+	nickname := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string)
+
+	listId, ok := api.findList(w, r, dto.Id, nickname)
+	if !ok {
+		return
+	}
+	if listId == nil {
+		http_result.WriteError(&w, models.BadRequest, "reading list with this id doesn't exist")
+		return
+	}
+
+	lists, err := api.readingLists.ListByNickname(r.Context(), nickname)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	if !checkNameIsFree(w, lists, dto.Name, *listId) {
+		return
+	}
+
+	list, err := api.readingLists.FindById(r.Context(), *listId)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	list.Name = strings.TrimSpace(dto.Name)
+	if err := api.readingLists.Update(r.Context(), list); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	count, err := api.bookmarks.Count(r.Context(), nickname, listId)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(toListDTO(*list, count))
+}
+
+// DeleteListHandler deletes the list with its bookmarks, the plain bookmarks of the articles stay
+func (api *Api) DeleteListHandler(w http.ResponseWriter, r *http.Request) {
+	var dto models.ReadingListIdDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	nickname := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string)
+
+	listId, ok := api.findList(w, r, dto.Id, nickname)
+	if !ok {
+		return
+	}
+	if listId == nil {
+		http_result.WriteError(&w, models.BadRequest, "reading list with this id doesn't exist")
+		return
+	}
+
+	if err := api.readingLists.Delete(r.Context(), *listId); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	if err := api.bookmarks.DeleteByList(r.Context(), nickname, *listId); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(dto)
+}
+
+func (api *Api) GetListsHandler(w http.ResponseWriter, r *http.Request) {
+	nickname := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string)
+
+	lists, err := api.readingLists.ListByNickname(r.Context(), nickname)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	results := []models.ReadingListDTO{}
+	for _, list := range lists {
+		count, err := api.bookmarks.Count(r.Context(), nickname, &list.Id)
+		if err != nil {
+			http_result.WriteError(&w, models.InternalError, "internal error")
+			return
+		}
+
+		results = append(results, toListDTO(list, count))
+	}
+
+	json.NewEncoder(w).Encode(results)
+}
+
+// findList returns nil for the plain bookmarks, a list of another user isn't found.
+// It writes the error itself.
+func (api *Api) findList(w http.ResponseWriter, r *http.Request, id string, nickname string) (*primitive.ObjectID, bool) {
+	if id == "" {
+		return nil, true
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		http_result.WriteError(&w, models.BadRequest, "reading list with this id doesn't exist")
+		return nil, false
+	}
+
+	list, err := api.readingLists.FindById(r.Context(), objectId)
+	if err == repositories.ErrNotFound || (err == nil && list.Nickname != nickname) {
+		http_result.WriteError(&w, models.BadRequest, "reading list with this id doesn't exist")
+		return nil, false
+	}
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return nil, false
+	}
+
+	return &list.Id, true
+}
+
+// checkNameIsFree compares the names case-insensitively, the list being renamed is skipped
+func checkNameIsFree(w http.ResponseWriter, lists []models.ReadingList, name string, skip primitive.ObjectID) bool {
+	for _, list := range lists {
+		if list.Id != skip && strings.EqualFold(list.Name, strings.TrimSpace(name)) {
+			http_result.WriteError(&w, models.NotUniqueData, "reading list with this name already exists")
+			return false
+		}
+	}
+
+	return true
+}
+
+func toListDTO(list models.ReadingList, count int64) models.ReadingListDTO {
+	return models.ReadingListDTO{
+		Id:           list.Id.Hex(),
+		Name:         list.Name,
+		ArticleCount: count,
+		CreatedAt:    list.CreatedAt,
+	}
+}
+
+func validateArticleId(w http.ResponseWriter, customId string) bool {
+	if len(customId) < int(models.ArticleIdMinLimit) || len(customId) > int(models.ArticleIdMaxLimit) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("article_id length < ", models.ArticleIdMinLimit, " or > ", models.ArticleIdMaxLimit))
+		return false
+	}
+
+	return true
+}
+
+func validateName(w http.ResponseWriter, name string) bool {
+	length := utf8.RuneCountInString(strings.TrimSpace(name))
+	if length < int(models.ReadingListNameMinLimit) || length > int(models.ReadingListNameMaxLimit) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("name length < ", models.ReadingListNameMinLimit, " or > ", models.ReadingListNameMaxLimit))
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,214 @@
+package categories
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+	"github.com/shuryak/shuryak-backend/internal/utils/http-result"
+	"net/http"
+	"regexp"
+	"unicode/utf8"
+)
+
+// Category ids appear in URLs of the frontend, so unlike tags they are ASCII
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type Api struct {
+	categories repositories.CategoryRepository
+	articles   repositories.ArticleRepository
+}
+
+func NewApi(categories repositories.CategoryRepository, articles repositories.ArticleRepository) *Api {
+	return &Api{categories: categories, articles: articles}
+}
+
+func (api *Api) GetTreeHandler(w http.ResponseWriter, r *http.Request) {
+	categories, err := api.categories.List(r.Context())
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(models.CategoryTree(categories))
+}
+
+func (api *Api) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	var dto models.CategoryDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if !validate(w, dto) {
+		return
+	}
+	// endregion Validation
+
+	categories, err := api.categories.List(r.Context())
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	if !checkParent(w, categories, dto.Parent, 1) {
+		return
+	}
+
+	category := models.Category{Slug: dto.Id, Name: dto.Name, Parent: dto.Parent, Order: dto.Order}
+	if err := api.categories.Create(r.Context(), &category); err != nil {
+		if err == repositories.ErrDuplicate {
+			http_result.WriteError(&w, models.NotUniqueData, "category with this id already exists")
+			return
+		}
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(category.ToDTO())
+}
+
+// UpdateHandler renames or moves the category, its subcategories move with it
+func (api *Api) UpdateHandler(w http.ResponseWriter, r *http.Request) {
+	var dto models.CategoryDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if !validate(w, dto) {
+		return
+	}
+	// endregion Validation
+
+	categories, err := api.categories.List(r.Context())
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	depth := models.CategoryDepth(categories, dto.Id)
+	if depth == 0 {
+		http_result.WriteError(&w, models.BadRequest, "category with this id doesn't exist")
+		return
+	}
+
+	subtree := models.CategoryWithDescendants(categories, dto.Id)
+	for _, slug := range subtree {
+		if slug == dto.Parent {
+			http_result.WriteError(&w, models.BadRequest, "category can't be moved under itself")
+			return
+		}
+	}
+
+	// The deepest subcategory must stay within the depth limit at the new place
+	height := 1
+	for _, slug := range subtree {
+		if levels := models.CategoryDepth(categories, slug) - depth + 1; levels > height {
+			height = levels
+		}
+	}
+
+	if !checkParent(w, categories, dto.Parent, height) {
+		return
+	}
+
+	category := models.Category{Slug: dto.Id, Name: dto.Name, Parent: dto.Parent, Order: dto.Order}
+	if err := api.categories.Update(r.Context(), &category); err != nil {
+		if err == repositories.ErrNotFound {
+			http_result.WriteError(&w, models.BadRequest, "category with this id doesn't exist")
+			return
+		}
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(category.ToDTO())
+}
+
+// DeleteHandler only deletes empty categories, so no article is left with a missing one
+func (api *Api) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	var query models.CategoryIdExpression
+
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	categories, err := api.categories.List(r.Context())
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	if models.CategoryDepth(categories, query.Id) == 0 {
+		http_result.WriteError(&w, models.BadRequest, "category with this id doesn't exist")
+		return
+	}
+
+	if len(models.CategoryWithDescendants(categories, query.Id)) > 1 {
+		http_result.WriteError(&w, models.BadRequest, "category has subcategories, move or delete them first")
+		return
+	}
+
+	// Drafts count too, the trash doesn't
+	used, err := api.articles.List(r.Context(), repositories.ArticleFilter{Categories: []string{query.Id}}, 1, 0)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+	if len(used) != 0 {
+		http_result.WriteError(&w, models.BadRequest, "category has articles, move them first")
+		return
+	}
+
+	if err := api.categories.Delete(r.Context(), query.Id); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(query)
+}
+
+// validate writes the error itself
+func validate(w http.ResponseWriter, dto models.CategoryDTO) bool {
+	if len(dto.Id) < int(models.CategoryIdMinLimit) || len(dto.Id) > int(models.CategoryIdMaxLimit) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("id length < ", models.CategoryIdMinLimit, " or > ", models.CategoryIdMaxLimit))
+		return false
+	}
+
+	if !slugPattern.MatchString(dto.Id) {
+		http_result.WriteError(&w, models.BadRequest, "id must consist of a-z, 0-9 and single dashes between them")
+		return false
+	}
+
+	if length := utf8.RuneCountInString(dto.Name); length < int(models.CategoryNameMinLimit) || length > int(models.CategoryNameMaxLimit) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("name length < ", models.CategoryNameMinLimit, " or > ", models.CategoryNameMaxLimit))
+		return false
+	}
+
+	return true
+}
+
+// checkParent checks that the parent exists and that height levels of categories fit under it,
+// it writes the error itself
+func checkParent(w http.ResponseWriter, categories []models.Category, parent string, height int) bool {
+	depth := 0
+	if parent != "" {
+		if depth = models.CategoryDepth(categories, parent); depth == 0 {
+			http_result.WriteError(&w, models.BadRequest, "parent category doesn't exist")
+			return false
+		}
+	}
+
+	if depth+height > int(models.CategoryMaxDepth) {
+		http_result.WriteError(&w, models.BadRequest, fmt.Sprint("categories can't be nested deeper than ", models.CategoryMaxDepth, " levels"))
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,526 @@
+package comments
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+	"github.com/shuryak/shuryak-backend/internal/utils"
+	"github.com/shuryak/shuryak-backend/internal/utils/http-result"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type Api struct {
+	comments repositories.CommentRepository
+	articles repositories.ArticleRepository
+}
+
+func NewApi(comments repositories.CommentRepository, articles repositories.ArticleRepository) *Api {
+	return &Api{comments: comments, articles: articles}
+}
+
+// threadCursor is the offset of the next page, the pinned comments move, so there is no stable position
+type threadCursor struct {
+	Offset uint `json:"o"`
+}
+
+func (api *Api) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	var dto models.CreateCommentDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if !validateArticleId(w, dto.ArticleId) || !validateText(w, dto.Text) {
+		return
+	}
+	// endregion Validation
+
+	article, ok := api.findArticle(w, r, dto.ArticleId)
+	if !ok {
+		return
+	}
+
+	claims := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)
+
+	if article.CommentsLocked && !canModerate(claims, article) {
+		http_result.WriteError(&w, models.Forbidden, "comments of this article are locked")
+		return
+	}
+
+	comment := models.Comment{
+		ArticleId: article.CustomId,
+		Ancestors: []primitive.ObjectID{},
+		Author:    claims["nickname"].(string),
+		Text:      strings.TrimSpace(dto.Text),
+		CreatedAt: time.Now(),
+	}
+
+	if dto.ParentId != "" {
+		parent, ok := api.findComment(w, r, dto.ParentId)
+		if !ok {
+			return
+		}
+
+		if parent.ArticleId != article.CustomId || parent.DeletedAt != nil {
+			http_result.WriteError(&w, models.BadRequest, "parent comment with this id doesn't exist")
+			return
+		}
+
+		if parent.Depth() >= int(models.CommentMaxDepth) {
+			http_result.WriteError(&w, models.BadRequest, fmt.Sprint("replies can't be nested deeper than ", models.CommentMaxDepth))
+			return
+		}
+
+		comment.ParentId = &parent.Id
+		comment.Ancestors = append(append(comment.Ancestors, parent.Ancestors...), parent.Id)
+	}
+
+	if err := api.comments.Create(r.Context(), &comment); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	if err := api.articles.AddComments(r.Context(), article.CustomId, 1); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(comment.ToDTO())
+}
+
+func (api *Api) EditHandler(w http.ResponseWriter, r *http.Request) {
+	var dto models.EditCommentDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if !validateText(w, dto.Text) {
+		return
+	}
+	// endregion Validation
+
+	comment, ok := api.findComment(w, r, dto.Id)
+	if !ok {
+		return
+	}
+
+	article, ok := api.findArticle(w, r, comment.ArticleId)
+	if !ok {
+		return
+	}
+
+	claims := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)
+
+	if comment.Author != claims["nickname"].(string) {
+		http_result.WriteError(&w, models.Forbidden, "you're not the author of this comment")
+		return
+	}
+
+	if article.CommentsLocked && !canModerate(claims, article) {
+		http_result.WriteError(&w, models.Forbidden, "comments of this article are locked")
+		return
+	}
+
+	if comment.DeletedAt != nil {
+		http_result.WriteError(&w, models.BadRequest, "comment is deleted")
+		return
+	}
+
+	editedAt := time.Now()
+	comment.Text = strings.TrimSpace(dto.Text)
+	comment.EditedAt = &editedAt
+
+	if err := api.comments.Update(r.Context(), comment); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(comment.ToDTO())
+}
+
+// DeleteHandler erases the text but keeps the comment in the thread, its replies stay in place
+func (api *Api) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	var dto models.CommentIdDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	comment, ok := api.findComment(w, r, dto.Id)
+	if !ok {
+		return
+	}
+
+	// Unpublished articles aren't found, but their comments can still be deleted
+	article, err := api.articles.FindByCustomId(r.Context(), comment.ArticleId)
+	if err != nil {
+		http_result.WriteError(&w, models.BadRequest, "comment with this id doesn't exist")
+		return
+	}
+
+	claims := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)
+
+	if comment.Author != claims["nickname"].(string) && !canModerate(claims, article) {
+		http_result.WriteError(&w, models.Forbidden, "you're not the author of this comment")
+		return
+	}
+
+	wasVisible := comment.IsVisible()
+
+	deletedAt := time.Now()
+	comment.DeletedAt = &deletedAt
+	comment.Text = ""
+	comment.Pinned = false
+
+	if !api.save(w, r, comment, wasVisible) {
+		return
+	}
+
+	json.NewEncoder(w).Encode(comment.ToDTO())
+}
+
+// HideHandler hides the text of the comment from everyone, only the article author and admins can do it
+func (api *Api) HideHandler(w http.ResponseWriter, r *http.Request) {
+	comment, ok := api.moderate(w, r, func(comment *models.Comment, value bool) bool {
+		comment.Hidden = value
+		return true
+	})
+	if ok {
+		json.NewEncoder(w).Encode(comment.ToDTO())
+	}
+}
+
+// PinHandler moves a top-level comment to the top of the thread
+func (api *Api) PinHandler(w http.ResponseWriter, r *http.Request) {
+	comment, ok := api.moderate(w, r, func(comment *models.Comment, value bool) bool {
+		if comment.ParentId != nil {
+			http_result.WriteError(&w, models.BadRequest, "only top-level comments can be pinned")
+			return false
+		}
+
+		comment.Pinned = value
+		return true
+	})
+	if ok {
+		json.NewEncoder(w).Encode(comment.ToDTO())
+	}
+}
+
+// LockThreadHandler stops everyone but the moderators from posting and editing comments of the article
+func (api *Api) LockThreadHandler(w http.ResponseWriter, r *http.Request) {
+	var dto models.LockCommentsDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if !validateArticleId(w, dto.ArticleId) {
+		return
+	}
+	// endregion Validation
+
+	article, err := api.articles.FindByCustomId(r.Context(), dto.ArticleId)
+	if err != nil {
+		http_result.WriteError(&w, models.BadRequest, "article with this id doesn't exist")
+		return
+	}
+
+	if !canModerate(r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims), article) {
+		http_result.WriteError(&w, models.Forbidden, "only the author of the article or an admin can moderate comments")
+		return
+	}
+
+	if err := api.articles.LockComments(r.Context(), article.CustomId, dto.Locked); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+	article.CommentsLocked = dto.Locked
+
+	json.NewEncoder(w).Encode(article.ToMeta())
+}
+
+// GetThreadHandler lists a page of the top-level comments, or of the replies of a comment, with their
+// replies nested up to the requested depth
+func (api *Api) GetThreadHandler(w http.ResponseWriter, r *http.Request) {
+	var query models.GetThreadExpression
+
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if !validateArticleId(w, query.ArticleId) {
+		return
+	}
+
+	if query.Count > uint(models.CommentsMaxLimit) {
+		http_result.WriteError(&w, models.BadRequest, fmt.Sprint("count > ", models.CommentsMaxLimit))
+		return
+	}
+
+	if query.Depth != nil && *query.Depth > uint(models.CommentMaxDepth) {
+		http_result.WriteError(&w, models.BadRequest, fmt.Sprint("depth > ", models.CommentMaxDepth))
+		return
+	}
+	// endregion Validation
+
+	article, ok := api.findArticle(w, r, query.ArticleId)
+	if !ok {
+		return
+	}
+
+	var parentId *primitive.ObjectID
+	level := 0
+	if query.ParentId != "" {
+		parent, ok := api.findComment(w, r, query.ParentId)
+		if !ok {
+			return
+		}
+		if parent.ArticleId != article.CustomId {
+			http_result.WriteError(&w, models.BadRequest, "parent comment with this id doesn't exist")
+			return
+		}
+
+		parentId = &parent.Id
+		level = parent.Depth() + 1
+	}
+
+	offset := query.Offset
+	if query.Cursor != nil && *query.Cursor != "" {
+		if offset != 0 {
+			http_result.WriteError(&w, models.BadRequest, "offset can't be used with cursor")
+			return
+		}
+
+		var cursor threadCursor
+		if err := utils.ParseCursor(*query.Cursor, &cursor); err != nil {
+			http_result.WriteError(&w, models.BadRequest, "invalid cursor")
+			return
+		}
+		offset = cursor.Offset
+	}
+
+	count := query.Count
+	if count == 0 {
+		count = uint(models.CommentsMaxLimit)
+	}
+
+	depth := uint(models.CommentMaxDepth)
+	if query.Depth != nil {
+		depth = *query.Depth
+	}
+
+	total, err := api.comments.CountChildren(r.Context(), article.CustomId, parentId)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	listed, err := api.comments.ListChildren(r.Context(), article.CustomId, parentId, count, offset)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	var ids []primitive.ObjectID
+	for _, comment := range listed {
+		ids = append(ids, comment.Id)
+	}
+
+	replies, err := api.comments.ListDescendants(r.Context(), ids)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	result := models.CommentPageDTO{
+		Results:    buildThread(listed, replies, level, depth),
+		TotalCount: total,
+		Locked:     article.CommentsLocked,
+	}
+
+	if next := offset + uint(len(listed)); len(listed) != 0 && int64(next) < total {
+		if result.NextCursor, err = utils.SignCursor(threadCursor{Offset: next}); err != nil {
+			http_result.WriteError(&w, models.InternalError, "internal error")
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
+// buildThread nests the replies, oldest first, under the listed comments of the level. Replies deeper
+// than depth levels below it are only counted.
+func buildThread(listed []models.Comment, replies []models.Comment, level int, depth uint) []*models.CommentDTO {
+	dtos := make(map[primitive.ObjectID]*models.CommentDTO)
+
+	results := []*models.CommentDTO{}
+	for i := range listed {
+		dto := listed[i].ToDTO()
+		dtos[listed[i].Id] = dto
+		results = append(results, dto)
+	}
+
+	// Parents come before their replies, both are oldest first
+	for i := range replies {
+		parent := dtos[*replies[i].ParentId]
+		if parent == nil {
+			continue
+		}
+		parent.ReplyCount++
+
+		if replies[i].Depth()-level > int(depth) {
+			continue
+		}
+
+		dto := replies[i].ToDTO()
+		dtos[replies[i].Id] = dto
+		parent.Replies = append(parent.Replies, dto)
+	}
+
+	return results
+}
+
+// moderate decodes the moderation request, checks that the user moderates the article and saves
+// the change made by apply. It writes the error itself.
+func (api *Api) moderate(w http.ResponseWriter, r *http.Request, apply func(comment *models.Comment, value bool) bool) (*models.Comment, bool) {
+	var dto models.ModerateCommentDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return nil, false
+	}
+
+	comment, ok := api.findComment(w, r, dto.Id)
+	if !ok {
+		return nil, false
+	}
+
+	article, err := api.articles.FindByCustomId(r.Context(), comment.ArticleId)
+	if err != nil {
+		http_result.WriteError(&w, models.BadRequest, "comment with this id doesn't exist")
+		return nil, false
+	}
+
+	if !canModerate(r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims), article) {
+		http_result.WriteError(&w, models.Forbidden, "only the author of the article or an admin can moderate comments")
+		return nil, false
+	}
+
+	if comment.DeletedAt != nil {
+		http_result.WriteError(&w, models.BadRequest, "comment is deleted")
+		return nil, false
+	}
+
+	wasVisible := comment.IsVisible()
+	if !apply(comment, dto.Value) {
+		return nil, false
+	}
+
+	if !api.save(w, r, comment, wasVisible) {
+		return nil, false
+	}
+
+	return comment, true
+}
+
+// save updates the comment and the comment count of the article if the comment was hidden,
+// shown or deleted. It writes the error itself.
+func (api *Api) save(w http.ResponseWriter, r *http.Request, comment *models.Comment, wasVisible bool) bool {
+	if err := api.comments.Update(r.Context(), comment); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return false
+	}
+
+	var delta int64
+	if wasVisible && !comment.IsVisible() {
+		delta = -1
+	} else if !wasVisible && comment.IsVisible() {
+		delta = 1
+	}
+
+	if delta != 0 {
+		if err := api.articles.AddComments(r.Context(), comment.ArticleId, delta); err != nil {
+			http_result.WriteError(&w, models.InternalError, "internal error")
+			return false
+		}
+	}
+
+	return true
+}
+
+// findArticle finds a published article, comments of the other ones can't be read or written.
+// It writes the error itself.
+func (api *Api) findArticle(w http.ResponseWriter, r *http.Request, customId string) (*models.Article, bool) {
+	article, err := api.articles.FindByCustomId(r.Context(), customId)
+	if err != nil || !repositories.IsPublished(article, time.Now()) {
+		http_result.WriteError(&w, models.BadRequest, "published article with this id doesn't exist")
+		return nil, false
+	}
+
+	return article, true
+}
+
+// findComment writes the error itself
+func (api *Api) findComment(w http.ResponseWriter, r *http.Request, id string) (*models.Comment, bool) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		http_result.WriteError(&w, models.BadRequest, "comment with this id doesn't exist")
+		return nil, false
+	}
+
+	comment, err := api.comments.FindById(r.Context(), objectId)
+	if err == repositories.ErrNotFound {
+		http_result.WriteError(&w, models.BadRequest, "comment with this id doesn't exist")
+		return nil, false
+	}
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return nil, false
+	}
+
+	return comment, true
+}
+
+// canModerate tells if the user is the author of the article or an admin
+func canModerate(claims jwt.MapClaims, article *models.Article) bool {
+	if models.RoleFromClaims(claims).Includes(models.RoleAdmin) {
+		return true
+	}
+
+	return article.Author == claims["nickname"].(string)
+}
+
+func validateArticleId(w http.ResponseWriter, customId string) bool {
+	if len(customId) < int(models.ArticleIdMinLimit) || len(customId) > int(models.ArticleIdMaxLimit) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("article_id length < ", models.ArticleIdMinLimit, " or > ", models.ArticleIdMaxLimit))
+		return false
+	}
+
+	return true
+}
+
+func validateText(w http.ResponseWriter, text string) bool {
+	length := utf8.RuneCountInString(strings.TrimSpace(text))
+	if length == 0 || length > int(models.CommentTextMaxLimit) {
+		http_result.WriteError(&w, models.InvalidFieldLength, fmt.Sprint("text length < 1 or > ", models.CommentTextMaxLimit))
+		return false
+	}
+
+	return true
+}
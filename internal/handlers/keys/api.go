@@ -0,0 +1,45 @@
+package keys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/utils"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+func JwksHandler(w http.ResponseWriter, r *http.Request) {
+	result := models.JwksDTO{Keys: []models.JwkDTO{}}
+
+	for _, key := range utils.Jwt.Keys.PublicKeys(time.Now()) {
+		jwk := models.JwkDTO{
+			KeyId:     key.Id,
+			Use:       "sig",
+			Algorithm: key.Method.Alg(),
+		}
+
+		switch publicKey := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.Modulus = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.Exponent = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+
+		result.Keys = append(result.Keys, jwk)
+	}
+
+	// Verifiers may cache the keys, upcoming keys are published before they start signing
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	json.NewEncoder(w).Encode(result)
+}
@@ -1,18 +1,31 @@
 package users
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
 	"github.com/shuryak/shuryak-backend/internal/utils"
 	"github.com/shuryak/shuryak-backend/internal/utils/http-result"
-	"go.mongodb.org/mongo-driver/bson"
+	"net"
 	"net/http"
+	"time"
 )
 
-func CreateHandler(w http.ResponseWriter, r *http.Request) {
+type Api struct {
+	users         repositories.UserRepository
+	sessions      repositories.SessionRepository
+	revokedTokens repositories.RevokedTokenRepository
+	articles      repositories.ArticleRepository
+	reactions     repositories.ReactionRepository
+}
+
+func NewApi(users repositories.UserRepository, sessions repositories.SessionRepository, revokedTokens repositories.RevokedTokenRepository, articles repositories.ArticleRepository, reactions repositories.ReactionRepository) *Api {
+	return &Api{users: users, sessions: sessions, revokedTokens: revokedTokens, articles: articles, reactions: reactions}
+}
+
+func (api *Api) CreateHandler(w http.ResponseWriter, r *http.Request) {
 	var dto models.UserRegisterDTO
 
 	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
@@ -49,10 +62,7 @@ func CreateHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Checking for the existence of a user with this nickname
-	var dbUser models.User
-	collection := utils.Mongo.Database("shuryakDb").Collection("users")
-	findFilter := bson.D{{"nickname", dto.Nickname}}
-	if err := collection.FindOne(context.TODO(), findFilter).Decode(&dbUser); err == nil {
+	if _, err := api.users.FindByNickname(r.Context(), dto.Nickname); err == nil {
 		http_result.WriteError(&w, models.NotUniqueData, "user with this nickname already exists")
 		return
 	}
@@ -64,13 +74,15 @@ func CreateHandler(w http.ResponseWriter, r *http.Request) {
 		LastName:     dto.LastName,
 		Nickname:     dto.Nickname,
 		IsAdmin:      false,
+		Role:         models.RoleAuthor,
 		PasswordHash: passwordHash,
 	}
 
-	collection = utils.Mongo.Database("shuryakDb").Collection("users")
-
-	_, err := collection.InsertOne(context.TODO(), user)
-	if err != nil {
+	if err := api.users.Create(r.Context(), &user); err != nil {
+		if err == repositories.ErrDuplicate {
+			http_result.WriteError(&w, models.NotUniqueData, "user with this nickname already exists")
+			return
+		}
 		http_result.WriteError(&w, models.InternalError, "internal error")
 		return
 	}
@@ -79,10 +91,11 @@ func CreateHandler(w http.ResponseWriter, r *http.Request) {
 		FirstName: user.FirstName,
 		LastName:  user.LastName,
 		Nickname:  user.Nickname,
+		Role:      user.Role,
 	})
 }
 
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
+func (api *Api) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	var dto models.UserLoginDTO
 
 	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
@@ -102,10 +115,8 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	// endregion Validation
 
-	var dbUser models.User
-	collection := utils.Mongo.Database("shuryakDb").Collection("users")
-	findFilter := bson.D{{"nickname", dto.Nickname}}
-	if err := collection.FindOne(context.TODO(), findFilter).Decode(&dbUser); err != nil {
+	dbUser, err := api.users.FindByNickname(r.Context(), dto.Nickname)
+	if err != nil {
 		http_result.WriteError(&w, models.BadAuth, "user with this nickname or password is not registered")
 		return
 	}
@@ -115,35 +126,88 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokenPair, err := dbUser.GenerateJWTBasedOn(30)
+	// Every login is a new session, so other devices stay logged in
+	sessionId := utils.NewTokenId()
+
+	tokenPair, err := dbUser.GenerateJWT(sessionId)
 	if err != nil {
 		http_result.WriteError(&w, models.InternalError, "internal error")
 		return
 	}
 
-	update := bson.D{{"$set", bson.D{{"refresh_token", tokenPair["refresh_token"]}}}}
+	now := time.Now()
+	session := models.Session{
+		Id:          sessionId,
+		Nickname:    dbUser.Nickname,
+		UserAgent:   r.UserAgent(),
+		Ip:          remoteIp(r),
+		CreatedAt:   now,
+		LastUsedAt:  now,
+		ExpiresAt:   now.Add(utils.Jwt.RefreshLifetime),
+		RefreshHash: utils.HashToken(tokenPair["refresh_token"].(string)),
+	}
 
-	if _, err := collection.UpdateOne(context.TODO(), findFilter, update); err != nil {
-		http_result.WriteError(&w, models.BadAuth, "internal error")
+	if err := api.sessions.Create(r.Context(), &session); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
 		return
 	}
 
-	json.NewEncoder(w).Encode(models.TokensDTO{
-		AccessToken:     tokenPair["access_token"].(string),
-		RefreshToken:    tokenPair["refresh_token"].(string),
-		AccessExpiresIn: tokenPair["access_expires_in"].(int64),
-	})
+	json.NewEncoder(w).Encode(toTokensDTO(tokenPair))
 }
 
-func GetUserInfoHandler(w http.ResponseWriter, r *http.Request) {
+func (api *Api) GetUserInfoHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(models.UserDTO{
 		Nickname:  r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string),
 		FirstName: r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["first_name"].(string),
 		LastName:  r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["last_name"].(string),
+		Role:      models.RoleFromClaims(r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)),
 	})
 }
 
-func RefreshTokenPairHandler(w http.ResponseWriter, r *http.Request) {
+func (api *Api) SetRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var dto models.UserRoleDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if !dto.Role.IsValid() {
+		http_result.WriteError(&w, models.BadRequest, "invalid role")
+		return
+	}
+
+	// Otherwise the last admin could lock everyone out of role management
+	if dto.Nickname == r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string) {
+		http_result.WriteError(&w, models.BadRequest, "you can't change your own role")
+		return
+	}
+	// endregion Validation
+
+	dbUser, err := api.users.FindByNickname(r.Context(), dto.Nickname)
+	if err != nil {
+		http_result.WriteError(&w, models.BadRequest, "user with this nickname doesn't exist")
+		return
+	}
+
+	dbUser.Role = dto.Role
+	dbUser.IsAdmin = dto.Role == models.RoleAdmin
+
+	if err := api.users.Update(r.Context(), dbUser); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	json.NewEncoder(w).Encode(models.UserDTO{
+		FirstName: dbUser.FirstName,
+		LastName:  dbUser.LastName,
+		Nickname:  dbUser.Nickname,
+		Role:      dbUser.EffectiveRole(),
+	})
+}
+
+func (api *Api) RefreshTokenPairHandler(w http.ResponseWriter, r *http.Request) {
 	var dto models.RefreshTokenDTO
 
 	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
@@ -151,7 +215,7 @@ func RefreshTokenPairHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if claims, isValid, err := utils.GetClaimsFromToken(dto.RefreshToken); err != nil {
+	if claims, isValid, err := utils.GetClaimsFromToken(dto.RefreshToken, utils.RefreshTokenType); err != nil {
 		if !isValid {
 			errorMessage := models.ErrorDTO{
 				ErrorCode: models.InvalidToken,
@@ -169,36 +233,183 @@ func RefreshTokenPairHandler(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(errorMessage)
 		return
 	} else {
-		var dbUser models.User
-		collection := utils.Mongo.Database("shuryakDb").Collection("users")
-		findFilter := bson.D{{"nickname", claims["nickname"]}}
-		if err := collection.FindOne(context.TODO(), findFilter).Decode(&dbUser); err != nil {
-			http_result.WriteError(&w, models.InvalidToken, "invalid refresh token")
+		nickname, _ := claims["nickname"].(string)
+		sessionId, _ := claims["fam"].(string)
+
+		session, err := api.sessions.FindById(r.Context(), sessionId)
+		if err != nil || session.Nickname != nickname {
+			http_result.WriteError(&w, models.BadRequest, "used or invalid refresh_token")
 			return
 		}
 
-		if dto.RefreshToken != dbUser.RefreshToken {
-			http_result.WriteError(&w, models.BadRequest, "used or invalid refresh_token")
+		dbUser, err := api.users.FindByNickname(r.Context(), nickname)
+		if err != nil {
+			http_result.WriteError(&w, models.InvalidToken, "invalid refresh token")
 			return
 		}
 
-		tokenPair, err := dbUser.GenerateJWTBasedOn(30)
+		tokenPair, err := dbUser.GenerateJWT(sessionId)
 		if err != nil {
 			http_result.WriteError(&w, models.InternalError, "internal error")
 			return
 		}
 
-		update := bson.D{{"$set", bson.D{{"refresh_token", tokenPair["refresh_token"]}}}}
+		oldHash := utils.HashToken(dto.RefreshToken)
+		newHash := utils.HashToken(tokenPair["refresh_token"].(string))
+		expiresAt := time.Now().Add(utils.Jwt.RefreshLifetime)
 
-		if _, err := collection.UpdateOne(context.TODO(), findFilter, update); err != nil {
-			http_result.WriteError(&w, models.BadAuth, "internal error")
+		err = api.sessions.Rotate(r.Context(), sessionId, oldHash, newHash, expiresAt)
+		if err == repositories.ErrNotFound {
+			// The token was already exchanged, so either the client or an attacker holds a stolen copy
+			if err := api.sessions.Delete(r.Context(), sessionId); err != nil && err != repositories.ErrNotFound {
+				http_result.WriteError(&w, models.InternalError, "internal error")
+				return
+			}
+			// The access tokens that were issued from the stolen copy are revoked with the session
+			if err := api.revokeSessionTokens(r, sessionId); err != nil {
+				http_result.WriteError(&w, models.InternalError, "internal error")
+				return
+			}
+			http_result.WriteError(&w, models.InvalidToken, "refresh_token reuse detected, log in again")
+			return
+		} else if err != nil {
+			http_result.WriteError(&w, models.InternalError, "internal error")
 			return
 		}
 
-		json.NewEncoder(w).Encode(models.TokensDTO{
-			AccessToken:     tokenPair["access_token"].(string),
-			RefreshToken:    tokenPair["refresh_token"].(string),
-			AccessExpiresIn: tokenPair["access_expires_in"].(int64),
-		})
+		json.NewEncoder(w).Encode(toTokensDTO(tokenPair))
+	}
+}
+
+func (api *Api) GetSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)
+	currentSessionId, _ := claims["sid"].(string)
+
+	sessions, err := api.sessions.ListByNickname(r.Context(), claims["nickname"].(string))
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	results := []models.SessionDTO{}
+	for _, session := range sessions {
+		results = append(results, session.ToDTO(currentSessionId))
+	}
+
+	json.NewEncoder(w).Encode(results)
+}
+
+func (api *Api) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	var dto models.SessionIdDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	session, err := api.sessions.FindById(r.Context(), dto.SessionId)
+	if err != nil || session.Nickname != r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string) {
+		http_result.WriteError(&w, models.BadRequest, "session with this id doesn't exist")
+		return
+	}
+
+	if err := api.sessions.Delete(r.Context(), session.Id); err != nil && err != repositories.ErrNotFound {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	if err := api.revokeSessionTokens(r, session.Id); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	http_result.WriteEmpty(&w)
+}
+
+func (api *Api) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)
+	sessionId, _ := claims["sid"].(string)
+
+	if err := api.sessions.Delete(r.Context(), sessionId); err != nil && err != repositories.ErrNotFound {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	if err := api.revokeAccessToken(r, claims); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	// The access tokens issued for the session before the last refresh are still valid too
+	if err := api.revokeSessionTokens(r, sessionId); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	http_result.WriteEmpty(&w)
+}
+
+func (api *Api) LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)
+
+	if err := api.sessions.DeleteByNickname(r.Context(), claims["nickname"].(string)); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	// The tokens issued within the current second stay valid, so the current one is revoked by its id
+	if err := api.revokeAccessToken(r, claims); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	// The tokens of the other sessions are revoked until the last of them expires
+	now := time.Now()
+	expiresAt := now.Add(utils.Jwt.AccessLifetime + utils.Jwt.Leeway)
+	if err := api.revokedTokens.RevokeUser(r.Context(), claims["nickname"].(string), now, expiresAt); err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	http_result.WriteEmpty(&w)
+}
+
+func (api *Api) revokeAccessToken(r *http.Request, claims jwt.MapClaims) error {
+	tokenId, _ := claims["jti"].(string)
+	exp, _ := claims["exp"].(float64)
+
+	// The token is accepted for the leeway after its expiration, so it must be denied for that long too
+	expiresAt := time.Unix(int64(exp), 0).Add(utils.Jwt.Leeway)
+
+	return api.revokedTokens.Add(r.Context(), tokenId, expiresAt)
+}
+
+// revokeSessionTokens revokes the access tokens issued for the session, the session has to be
+// deleted so that no more are issued
+func (api *Api) revokeSessionTokens(r *http.Request, sessionId string) error {
+	if sessionId == "" {
+		return nil
+	}
+
+	expiresAt := time.Now().Add(utils.Jwt.AccessLifetime + utils.Jwt.Leeway)
+	return api.revokedTokens.Add(r.Context(), sessionId, expiresAt)
+}
+
+// remoteIp is informational only, so proxy headers that clients can forge are ignored
+func remoteIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func toTokensDTO(tokenPair map[string]interface{}) models.TokensDTO {
+	return models.TokensDTO{
+		AccessToken:      tokenPair["access_token"].(string),
+		RefreshToken:     tokenPair["refresh_token"].(string),
+		AccessExpiresIn:  tokenPair["access_expires_in"].(int64),
+		RefreshExpiresIn: tokenPair["refresh_expires_in"].(int64),
 	}
 }
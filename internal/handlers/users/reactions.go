@@ -0,0 +1,73 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+	"github.com/shuryak/shuryak-backend/internal/utils/http-result"
+	"net/http"
+	"time"
+)
+
+// GetReactedArticlesHandler lists the articles the user has reacted to, the latest reaction first.
+// Articles that were unpublished or deleted since are left out, so a page may come out short.
+func (api *Api) GetReactedArticlesHandler(w http.ResponseWriter, r *http.Request) {
+	var query models.GetReactedArticlesExpression
+
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http_result.WriteError(&w, models.BadRequest, "bad JSON structure")
+		return
+	}
+
+	// region Validation
+	if query.Kind != "" && !query.Kind.IsValid() {
+		http_result.WriteError(&w, models.BadRequest, "kind must be like, love, insightful, funny or sad")
+		return
+	}
+
+	if query.Count > uint(models.FindMaxLimit) {
+		http_result.WriteError(&w, models.BadRequest, fmt.Sprint("count > ", models.FindMaxLimit))
+		return
+	}
+	// endregion Validation
+
+	count := query.Count
+	if count == 0 {
+		count = uint(models.FindMaxLimit)
+	}
+
+	nickname := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)["nickname"].(string)
+
+	reactions, err := api.reactions.ListByNickname(r.Context(), nickname, query.Kind, count, query.Offset)
+	if err != nil {
+		http_result.WriteError(&w, models.InternalError, "internal error")
+		return
+	}
+
+	now := time.Now()
+	results := []models.ReactedArticleDTO{}
+
+	for _, reaction := range reactions {
+		article, err := api.articles.FindByCustomId(r.Context(), reaction.ArticleId)
+		if err == repositories.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			http_result.WriteError(&w, models.InternalError, "internal error")
+			return
+		}
+		if !repositories.IsPublished(article, now) {
+			continue
+		}
+
+		results = append(results, models.ReactedArticleDTO{
+			MetaArticle: article.ToMeta(),
+			Kind:        reaction.Kind,
+			ReactedAt:   reaction.CreatedAt,
+		})
+	}
+
+	json.NewEncoder(w).Encode(results)
+}
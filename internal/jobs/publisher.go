@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+	"os"
+	"time"
+)
+
+const publisherLeaseName = "articles-publisher"
+
+// PublishScheduled publishes approved articles whose publish_at has come. Every replica runs it, but only
+// the holder of the lease does the work, the lease outlives a missed tick or two.
+func PublishScheduled(articles repositories.ArticleRepository, leases repositories.LeaseRepository, interval time.Duration) func(ctx context.Context) error {
+	holder := fmt.Sprintf("%s-%d-%d", hostname(), os.Getpid(), time.Now().UnixNano())
+
+	return func(ctx context.Context) error {
+		acquired, err := leases.Acquire(ctx, publisherLeaseName, holder, 3*interval)
+		if err != nil || !acquired {
+			return err
+		}
+
+		published, err := articles.PublishDue(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if len(published) != 0 {
+			fmt.Println("Published", len(published), "scheduled article(s)")
+		}
+
+		return nil
+	}
+}
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+
+	return name
+}
@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+)
+
+// RecountReactions fixes the reaction counts of the articles that drifted from the reactions. A count
+// is changed after the reaction is stored, so a failure in between leaves it off. A count changed
+// by a reaction during the run may be off again until the next one.
+func RecountReactions(articles repositories.ArticleRepository, reactions repositories.ReactionRepository) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		stored, err := articles.ReactionCounts(ctx)
+		if err != nil {
+			return err
+		}
+
+		actual, err := reactions.CountByArticle(ctx)
+		if err != nil {
+			return err
+		}
+
+		// The articles whose reactions were all taken back are only in the stored counts
+		for customId := range stored {
+			if actual[customId] == nil {
+				actual[customId] = models.ReactionCounts{}
+			}
+		}
+
+		fixed := 0
+		for customId, counts := range actual {
+			if sameCounts(stored[customId], counts) {
+				continue
+			}
+
+			// The reactions of purged articles are deleted by the trash purge
+			err := articles.SetReactions(ctx, customId, counts)
+			if err == repositories.ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			fixed++
+		}
+
+		if fixed != 0 {
+			fmt.Println("Recounted the reactions of", fixed, "article(s)")
+		}
+
+		return nil
+	}
+}
+
+// sameCounts ignores the kinds counted as zero, the stored counts keep the kinds that were taken back
+func sameCounts(a models.ReactionCounts, b models.ReactionCounts) bool {
+	for kind, count := range a {
+		if count != b[kind] {
+			return false
+		}
+	}
+	for kind, count := range b {
+		if count != a[kind] {
+			return false
+		}
+	}
+
+	return true
+}
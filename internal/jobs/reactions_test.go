@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+	"reflect"
+	"testing"
+)
+
+func TestRecountReactions(t *testing.T) {
+	tests := []struct {
+		name      string
+		stored    models.ReactionCounts
+		reactions []models.ReactionKind
+		want      models.ReactionCounts
+	}{
+		{"right counts", models.ReactionCounts{models.ReactionLike: 1}, []models.ReactionKind{models.ReactionLike}, models.ReactionCounts{models.ReactionLike: 1}},
+		{"taken back kinds", models.ReactionCounts{models.ReactionLike: 1, models.ReactionSad: 0}, []models.ReactionKind{models.ReactionLike},
+			models.ReactionCounts{models.ReactionLike: 1, models.ReactionSad: 0}},
+		{"missed increment", nil, []models.ReactionKind{models.ReactionLike, models.ReactionSad}, models.ReactionCounts{models.ReactionLike: 1, models.ReactionSad: 1}},
+		{"missed decrement", models.ReactionCounts{models.ReactionLike: 2}, []models.ReactionKind{models.ReactionLike}, models.ReactionCounts{models.ReactionLike: 1}},
+		{"all taken back", models.ReactionCounts{models.ReactionLike: 1}, nil, models.ReactionCounts{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			articles := repositories.NewMemoryArticleRepository()
+			reactions := repositories.NewMemoryReactionRepository()
+
+			articles.Create(ctx, &models.Article{CustomId: "article", Reactions: test.stored})
+			for _, kind := range test.reactions {
+				reactions.Add(ctx, &models.Reaction{ArticleId: "article", Nickname: "user", Kind: kind})
+			}
+			// The reactions of an article purged without them are skipped
+			reactions.Add(ctx, &models.Reaction{ArticleId: "purged", Nickname: "user", Kind: models.ReactionLike})
+
+			if err := RecountReactions(articles, reactions)(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			article, err := articles.FindByCustomId(ctx, "article")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(article.Reactions, test.want) {
+				t.Errorf("got %v, want %v", article.Reactions, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+	"time"
+)
+
+// PurgeTrash removes articles that have been in the trash for longer than the retention period
+// together with their revisions, reactions and comments
+func PurgeTrash(articles repositories.ArticleRepository, revisions repositories.RevisionRepository, reactions repositories.ReactionRepository, comments repositories.CommentRepository, retention time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		purged, err := articles.PurgeDeleted(ctx, time.Now().Add(-retention))
+		if err != nil {
+			return err
+		}
+
+		for _, customId := range purged {
+			if err := revisions.DeleteByArticle(ctx, customId); err != nil {
+				return err
+			}
+			if err := reactions.DeleteByArticle(ctx, customId); err != nil {
+				return err
+			}
+			if err := comments.DeleteByArticle(ctx, customId); err != nil {
+				return err
+			}
+		}
+
+		if len(purged) != 0 {
+			fmt.Println("Purged", len(purged), "article(s) from the trash")
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,151 @@
+package jsondiff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+)
+
+type Change struct {
+	Path string      `json:"path"`
+	Op   string      `json:"op"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// Diff compares two JSON-like values (maps with string keys, slices and scalars) and returns
+// the changes turning from into to, with paths like "article_data.blocks[3].text".
+// Slices are compared index by index, so an insertion shows up as a chain of replacements.
+func Diff(root string, from interface{}, to interface{}) []Change {
+	changes := []Change{}
+	diff(root, reflect.ValueOf(from), reflect.ValueOf(to), &changes)
+	return changes
+}
+
+func diff(path string, from reflect.Value, to reflect.Value, changes *[]Change) {
+	from, to = unwrap(from), unwrap(to)
+
+	if isMap(from) && isMap(to) {
+		diffMaps(path, from, to, changes)
+		return
+	}
+
+	if isSlice(from) && isSlice(to) {
+		diffSlices(path, from, to, changes)
+		return
+	}
+
+	if !equal(from, to) {
+		*changes = append(*changes, Change{Path: path, Op: OpReplace, Old: export(from), New: export(to)})
+	}
+}
+
+func diffMaps(path string, from reflect.Value, to reflect.Value, changes *[]Change) {
+	keySet := make(map[string]bool)
+	for _, key := range from.MapKeys() {
+		keySet[key.String()] = true
+	}
+	for _, key := range to.MapKeys() {
+		keySet[key.String()] = true
+	}
+
+	keys := make([]string, 0, len(keySet))
+	for key := range keySet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+
+		fromValue := from.MapIndex(reflect.ValueOf(key).Convert(from.Type().Key()))
+		toValue := to.MapIndex(reflect.ValueOf(key).Convert(to.Type().Key()))
+
+		if !fromValue.IsValid() {
+			*changes = append(*changes, Change{Path: keyPath, Op: OpAdd, New: export(toValue)})
+		} else if !toValue.IsValid() {
+			*changes = append(*changes, Change{Path: keyPath, Op: OpRemove, Old: export(fromValue)})
+		} else {
+			diff(keyPath, fromValue, toValue, changes)
+		}
+	}
+}
+
+func diffSlices(path string, from reflect.Value, to reflect.Value, changes *[]Change) {
+	for i := 0; i < from.Len() || i < to.Len(); i++ {
+		indexPath := fmt.Sprintf("%s[%d]", path, i)
+
+		if i >= from.Len() {
+			*changes = append(*changes, Change{Path: indexPath, Op: OpAdd, New: export(to.Index(i))})
+		} else if i >= to.Len() {
+			*changes = append(*changes, Change{Path: indexPath, Op: OpRemove, Old: export(from.Index(i))})
+		} else {
+			diff(indexPath, from.Index(i), to.Index(i), changes)
+		}
+	}
+}
+
+func unwrap(value reflect.Value) reflect.Value {
+	for value.IsValid() && (value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr) {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+
+	return value
+}
+
+func isMap(value reflect.Value) bool {
+	return value.IsValid() && value.Kind() == reflect.Map && value.Type().Key().Kind() == reflect.String
+}
+
+func isSlice(value reflect.Value) bool {
+	return value.IsValid() && (value.Kind() == reflect.Slice || value.Kind() == reflect.Array)
+}
+
+// equal treats numbers of different types as equal, because JSON and BSON decode them differently
+func equal(from reflect.Value, to reflect.Value) bool {
+	if !from.IsValid() || !to.IsValid() {
+		return from.IsValid() == to.IsValid()
+	}
+
+	fromNumber, fromIsNumber := toFloat(from)
+	toNumber, toIsNumber := toFloat(to)
+	if fromIsNumber && toIsNumber {
+		return fromNumber == toNumber
+	}
+
+	return reflect.DeepEqual(from.Interface(), to.Interface())
+}
+
+func toFloat(value reflect.Value) (float64, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+func export(value reflect.Value) interface{} {
+	value = unwrap(value)
+	if !value.IsValid() {
+		return nil
+	}
+
+	return value.Interface()
+}
@@ -0,0 +1,39 @@
+package jsondiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		from interface{}
+		to   interface{}
+		want []Change
+	}{
+		{"equal", map[string]interface{}{"a": "x"}, map[string]interface{}{"a": "x"}, []Change{}},
+		{"numbers of different types", map[string]interface{}{"a": int32(1)}, map[string]interface{}{"a": float64(1)}, []Change{}},
+		{"replaced", map[string]interface{}{"a": "x"}, map[string]interface{}{"a": "y"}, []Change{{Path: "a", Op: OpReplace, Old: "x", New: "y"}}},
+		{"added and removed", map[string]interface{}{"a": "x"}, map[string]interface{}{"b": "y"}, []Change{
+			{Path: "a", Op: OpRemove, Old: "x"},
+			{Path: "b", Op: OpAdd, New: "y"},
+		}},
+		{"nested", map[string]interface{}{"blocks": []interface{}{map[string]interface{}{"text": "x"}}},
+			map[string]interface{}{"blocks": []interface{}{map[string]interface{}{"text": "y"}, "z"}}, []Change{
+				{Path: "blocks[0].text", Op: OpReplace, Old: "x", New: "y"},
+				{Path: "blocks[1]", Op: OpAdd, New: "z"},
+			}},
+		{"type changed", map[string]interface{}{"a": "x"}, map[string]interface{}{"a": []interface{}{"x"}}, []Change{
+			{Path: "a", Op: OpReplace, Old: "x", New: []interface{}{"x"}},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Diff("", test.from, test.to); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+type FrontMatter struct {
+	Name      string
+	Id        string
+	Thumbnail string
+	Tags      []Tag
+}
+
+// Tag keeps the line of the tag, the caller validates the tags and warns about the bad ones
+type Tag struct {
+	Name string
+	Line int
+}
+
+var (
+	frontMatterField = regexp.MustCompile(`^([a-zA-Z_]+)\s*:\s*(.*)$`)
+	frontMatterItem  = regexp.MustCompile(`^\s+-\s+(.*)$`)
+)
+
+// parseFrontMatter reads the YAML-like block between the --- lines at the start of the document,
+// only "key: value" fields and lists of tags are understood. It returns the number of lines it took.
+func (p *parser) parseFrontMatter(lines []string) (FrontMatter, int) {
+	var frontMatter FrontMatter
+
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return frontMatter, 0
+	}
+
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if line := strings.TrimSpace(lines[i]); line == "---" || line == "..." {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		// Without the closing line it's a thematic break, not front matter
+		return frontMatter, 0
+	}
+
+	listField := ""
+	for i := 1; i < end; i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		if item := frontMatterItem.FindStringSubmatch(line); item != nil && listField == "tags" {
+			frontMatter.Tags = append(frontMatter.Tags, Tag{unquote(item[1]), i + 1})
+			continue
+		}
+
+		field := frontMatterField.FindStringSubmatch(line)
+		if field == nil {
+			p.warn(i+1, "front matter line isn't a \"key: value\" field, it's ignored")
+			continue
+		}
+
+		key, value := strings.ToLower(field[1]), strings.TrimSpace(field[2])
+		listField = ""
+
+		switch key {
+		case "name", "title":
+			frontMatter.Name = unquote(value)
+		case "id", "slug":
+			frontMatter.Id = unquote(value)
+		case "thumbnail", "image":
+			frontMatter.Thumbnail = unquote(value)
+		case "tags":
+			if value == "" {
+				listField = key
+			} else {
+				for _, tag := range inlineList(value) {
+					frontMatter.Tags = append(frontMatter.Tags, Tag{tag, i + 1})
+				}
+			}
+		default:
+			p.warn(i+1, "front matter field "+key+" isn't supported, it's ignored")
+		}
+	}
+
+	return frontMatter, end + 1
+}
+
+// inlineList parses "[a, b]" and "a, b"
+func inlineList(value string) []string {
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = unquote(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func unquote(value string) string {
+	value = strings.TrimSpace(value)
+
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+
+	return value
+}
@@ -0,0 +1,35 @@
+package markdown
+
+import (
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   FrontMatter
+		skip   int
+	}{
+		{"none", "# Name", FrontMatter{}, 0},
+		{"unclosed", "---\nname: a", FrontMatter{}, 0},
+		{"fields", "---\ntitle: \"Name\"\nslug: id\nimage: https://a.io/i.png\n---", FrontMatter{Name: "Name", Id: "id", Thumbnail: "https://a.io/i.png"}, 5},
+		{"inline tags", "---\nname: a\ntags: [go, 'web']\n---", FrontMatter{Name: "a", Tags: []Tag{{"go", 3}, {"web", 3}}}, 4},
+		{"tag list", "---\ntags:\n  - go\n\n  - web\n---", FrontMatter{Tags: []Tag{{"go", 3}, {"web", 5}}}, 6},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := &parser{refs: map[string]link{}, warned: map[models.ImportWarning]bool{}}
+
+			got, skip := p.parseFrontMatter(strings.Split(test.source, "\n"))
+
+			if !reflect.DeepEqual(got, test.want) || skip != test.skip {
+				t.Errorf("got %+v and %d lines, want %+v and %d", got, skip, test.want, test.skip)
+			}
+		})
+	}
+}
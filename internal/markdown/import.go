@@ -0,0 +1,149 @@
+// Package markdown imports CommonMark documents into article_data. Constructs that article_data
+// can't hold (tables, raw HTML, inline images and so on) become warnings instead of errors.
+package markdown
+
+import (
+	"github.com/shuryak/shuryak-backend/internal/blocks"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/renderer"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type Result struct {
+	FrontMatter FrontMatter
+	Document    blocks.Document
+	Warnings    []models.ImportWarning
+}
+
+// Limits that keep the import of hostile documents linear
+const (
+	maxNesting    = 8     // Blocks and inline syntax nested deeper are kept as text
+	maxDelimiters = 10000 // Inline delimiters of the document past this number are kept as text
+)
+
+type parser struct {
+	refs       map[string]link // Link reference definitions, used by the inline conversion
+	warnings   []models.ImportWarning
+	warned     map[models.ImportWarning]bool
+	nesting    int // Of the blocks being parsed
+	inlining   int // Nesting of the inline conversion
+	delimiters int // Inline delimiters seen in the document
+}
+
+// Import parses the source. Missing front matter fields are filled from the document: the name from
+// the first level 1 heading (which is then dropped from the blocks), the id from the name and the
+// thumbnail from the first image. The time it takes is linear in the size of the source, which the
+// callers have to limit.
+func Import(source string) Result {
+	source = strings.NewReplacer("\r\n", "\n", "\r", "\n", "\t", "    ").Replace(source)
+	lines := strings.Split(source, "\n")
+
+	p := &parser{refs: map[string]link{}, warned: map[models.ImportWarning]bool{}}
+
+	frontMatter, skip := p.parseFrontMatter(lines)
+	parsed := p.parseBlocks(lines[skip:], skip+1, 1)
+
+	document := blocks.Document{Blocks: []blocks.Block{}}
+	for _, block := range parsed {
+		p.convert(&block.Block, block.line)
+
+		if isEmpty(block.Block) {
+			p.warn(block.line, "empty "+block.Type+" blocks aren't supported, it's skipped")
+			continue
+		}
+
+		if frontMatter.Name == "" && block.Type == blocks.TypeHeading && block.Level == 1 {
+			frontMatter.Name = renderer.InlineText(block.Text)
+			continue
+		}
+
+		if frontMatter.Thumbnail == "" && block.Type == blocks.TypeImage {
+			frontMatter.Thumbnail = block.Url
+		}
+
+		document.Blocks = append(document.Blocks, block.Block)
+	}
+
+	if frontMatter.Id == "" {
+		frontMatter.Id = slug(frontMatter.Name)
+	}
+
+	// Inline warnings are found after the block ones
+	sort.SliceStable(p.warnings, func(i, j int) bool {
+		return p.warnings[i].Line < p.warnings[j].Line
+	})
+
+	return Result{FrontMatter: frontMatter, Document: document, Warnings: p.warnings}
+}
+
+// isEmpty tells if the block lacks the content article_data requires, like "#" or a fence without code
+func isEmpty(block blocks.Block) bool {
+	switch block.Type {
+	case blocks.TypeCode:
+		return block.Code == ""
+	case blocks.TypeHeading, blocks.TypeParagraph, blocks.TypeQuote:
+		return block.Text == ""
+	}
+
+	return false
+}
+
+func (p *parser) warn(line int, message string) {
+	warning := models.ImportWarning{Line: line, Message: message}
+	if p.warned[warning] {
+		return
+	}
+
+	p.warned[warning] = true
+	p.warnings = append(p.warnings, warning)
+}
+
+// convert turns the raw markdown of the text fields into text with inline tags
+func (p *parser) convert(block *blocks.Block, line int) {
+	block.Text = p.inline(block.Text, line)
+	block.Caption = p.inline(block.Caption, line)
+	p.convertItems(block.Items, line)
+}
+
+func (p *parser) convertItems(items []blocks.ListItem, line int) {
+	for i := range items {
+		items[i].Text = p.inline(items[i].Text, line)
+		p.convertItems(items[i].Items, line)
+	}
+}
+
+var transliteration = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z", 'и': "i",
+	'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+	'у': "u", 'ф': "f", 'х': "h", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "sch", 'ъ': "", 'ы': "y", 'ь': "",
+	'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// slug makes an article id from the name, Russian letters are transliterated
+func slug(name string) string {
+	var out strings.Builder
+	dash := false
+
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			out.WriteRune(r)
+			dash = false
+		case transliteration[r] != "":
+			out.WriteString(transliteration[r])
+			dash = false
+		case !dash && out.Len() != 0:
+			out.WriteByte('-')
+			dash = true
+		}
+	}
+
+	id := strings.TrimRight(out.String(), "-")
+	if len(id) > int(models.ArticleIdMaxLimit) {
+		id = strings.TrimRight(id[:models.ArticleIdMaxLimit], "-")
+	}
+
+	return id
+}
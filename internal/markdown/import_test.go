@@ -0,0 +1,46 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// The inputs took from seconds to minutes when the lookups of the closing delimiters were
+// quadratic and the nesting wasn't limited
+func TestImportHostileInput(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"brackets", strings.Repeat("[", 200000)},
+		{"unclosed destinations", strings.Repeat("[a](", 100000)},
+		{"unclosed references", strings.Repeat("[a][", 100000)},
+		{"nested links", strings.Repeat("[", 50000) + strings.Repeat("](https://a.io)", 50000)},
+		{"emphasis", strings.Repeat("*a _a ~~a ", 40000)},
+		{"code spans", "`" + strings.Repeat("``a", 100000)},
+		{"nested quotes", strings.Repeat("> ", 100000) + "a"},
+		{"nested lists", strings.Repeat("- ", 100000) + "a"},
+		{"fences", strings.Repeat("```\n", 100000)},
+		{"warnings", strings.Repeat("---\n\n", 50000)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start := time.Now()
+			Import(test.source)
+
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("took %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestImportNestingLimit(t *testing.T) {
+	result := Import(strings.Repeat("> ", 100) + "a")
+
+	if len(result.Document.Blocks) != 1 || !strings.HasSuffix(result.Document.Blocks[0].Text, "> a") {
+		t.Errorf("got blocks %+v, want one quote ending with the nested text", result.Document.Blocks)
+	}
+}
@@ -0,0 +1,442 @@
+package markdown
+
+import (
+	"fmt"
+	"github.com/shuryak/shuryak-backend/internal/renderer"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type link struct {
+	url   string
+	title string
+}
+
+var (
+	autolinkPattern   = regexp.MustCompile(`^<((?i:https?|mailto):[^ <>]+)>`)
+	emailPattern      = regexp.MustCompile(`^<([a-zA-Z0-9.!#$%&'*+/=?^_{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-.]*[a-zA-Z0-9])?)>`)
+	inlineHtmlPattern = regexp.MustCompile(`^</?([a-zA-Z][a-zA-Z0-9-]*)[^<>]*>`)
+	entityPattern     = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[a-zA-Z][a-zA-Z0-9]{1,31});`)
+)
+
+// maxLabelLength is the CommonMark limit of link labels, it also bounds the lookup of the destinations
+const maxLabelLength = 999
+
+// maxDestinationLength bounds the lookup of the closing parenthesis of a link
+const maxDestinationLength = 4096
+
+// inline converts markdown inline syntax into the inline tags of article_data text fields
+func (p *parser) inline(s string, line int) string {
+	if p.inlining >= maxNesting {
+		p.warn(line, fmt.Sprint("inline syntax nested deeper than ", maxNesting, " is kept as text"))
+		return s
+	}
+	p.inlining++
+	defer func() { p.inlining-- }()
+
+	var out strings.Builder
+	scan := newInlineScan(s)
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		if isDelimiter(c) && !p.countDelimiter(line) {
+			out.WriteByte(c)
+			i++
+			continue
+		}
+
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			out.WriteString("<br>")
+			i += 2
+		case c == '\\' && i+1 < len(s) && isPunctuation(s[i+1]):
+			out.WriteByte(s[i+1])
+			i += 2
+		case c == '\n':
+			out.WriteByte(' ')
+			i++
+		case c == '`':
+			n := run(s, i, '`')
+			end := scan.codeSpanEnd(i+n, n)
+			if end < 0 {
+				out.WriteString(s[i : i+n])
+				i += n
+				continue
+			}
+			code := strings.ReplaceAll(s[i+n:end], "\n", " ")
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+				code = code[1 : len(code)-1]
+			}
+			out.WriteString("<code>" + code + "</code>")
+			i = end + n
+		case c == '!' && i+1 < len(s) && s[i+1] == '[':
+			text, _, n, ok := p.link(scan, i+1)
+			if !ok {
+				out.WriteByte(c)
+				i++
+				continue
+			}
+			p.warn(line, "inline images aren't supported, they're replaced with their alt text")
+			out.WriteString(p.inline(text, line))
+			i += 1 + n
+		case c == '[':
+			text, href, n, ok := p.link(scan, i)
+			if !ok {
+				out.WriteByte(c)
+				i++
+				continue
+			}
+			out.WriteString(p.anchor(href, p.inline(text, line), line))
+			i += n
+		case c == '<':
+			if match := autolinkPattern.FindStringSubmatch(s[i:]); match != nil {
+				out.WriteString(p.anchor(match[1], match[1], line))
+				i += len(match[0])
+			} else if match := emailPattern.FindStringSubmatch(s[i:]); match != nil {
+				out.WriteString(p.anchor("mailto:"+match[1], match[1], line))
+				i += len(match[0])
+			} else if match := inlineHtmlPattern.FindStringSubmatch(s[i:]); match != nil {
+				if !renderer.IsInlineTag(match[1]) {
+					p.warn(line, "inline HTML tag "+match[1]+" isn't supported, it's kept as text")
+				}
+				out.WriteString(match[0])
+				i += len(match[0])
+			} else {
+				out.WriteByte(c)
+				i++
+			}
+		case c == '&':
+			if entity := entityPattern.FindString(s[i:]); entity != "" {
+				out.WriteString(html.UnescapeString(entity))
+				i += len(entity)
+			} else {
+				out.WriteByte(c)
+				i++
+			}
+		case c == '*' || c == '_' || c == '~':
+			tagged, n, ok := p.emphasis(scan, i, line)
+			if !ok {
+				n = run(s, i, c)
+				tagged = s[i : i+n]
+			}
+			out.WriteString(tagged)
+			i += n
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	return out.String()
+}
+
+var emphasisTags = map[int][2]string{
+	1: {"<em>", "</em>"},
+	2: {"<strong>", "</strong>"},
+	3: {"<strong><em>", "</em></strong>"},
+}
+
+// emphasis converts the delimiter run at i with its closing run, it returns the length it took
+func (p *parser) emphasis(scan *inlineScan, i int, line int) (string, int, bool) {
+	s := scan.s
+	c := s[i]
+	n := run(s, i, c)
+
+	if c == '~' {
+		if n != 2 {
+			return "", 0, false
+		}
+		end := scan.delimiter(i+n, c, 2)
+		if end < 0 {
+			return "", 0, false
+		}
+		return "<s>" + p.inline(s[i+2:end], line) + "</s>", end + 2 - i, true
+	}
+
+	if i+n >= len(s) || s[i+n] == ' ' || s[i+n] == '\n' {
+		return "", 0, false
+	}
+	// Underscores inside words aren't emphasis
+	if c == '_' && i > 0 && isWordByte(s[i-1]) {
+		return "", 0, false
+	}
+
+	if n > 3 {
+		n = 3
+	}
+	for ; n > 0; n-- {
+		if end := scan.delimiter(i+n, c, n); end >= 0 {
+			tags := emphasisTags[n]
+			return tags[0] + p.inline(s[i+n:end], line) + tags[1], end + n - i, true
+		}
+	}
+
+	return "", 0, false
+}
+
+func (p *parser) countDelimiter(line int) bool {
+	p.delimiters++
+	if p.delimiters > maxDelimiters {
+		p.warn(line, fmt.Sprint("inline syntax past the first ", maxDelimiters, " delimiters is kept as text"))
+		return false
+	}
+
+	return true
+}
+
+type delimiterRun struct {
+	c byte
+	n int
+}
+
+// inlineScan remembers the closing delimiters of a text. Looking them up from scratch for every
+// opening one is quadratic on texts like "[[[[" or "*a *a *a".
+type inlineScan struct {
+	s        string
+	brackets map[int]int // Opening bracket -> its closing one, unclosed brackets are missing
+	// Positions from which the lookups found nothing, the later lookups from further can't find
+	// anything either
+	codeSpanMisses  map[int]int
+	delimiterMisses map[delimiterRun]int
+}
+
+func newInlineScan(s string) *inlineScan {
+	scan := &inlineScan{
+		s:               s,
+		brackets:        map[int]int{},
+		codeSpanMisses:  map[int]int{},
+		delimiterMisses: map[delimiterRun]int{},
+	}
+
+	var open []int
+	for j := 0; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			open = append(open, j)
+		case ']':
+			if len(open) != 0 {
+				scan.brackets[open[len(open)-1]] = j
+				open = open[:len(open)-1]
+			}
+		}
+	}
+
+	return scan
+}
+
+// delimiter finds a closing run of exactly n characters c, skipping escapes and code spans
+func (scan *inlineScan) delimiter(from int, c byte, n int) int {
+	key := delimiterRun{c, n}
+	if miss, ok := scan.delimiterMisses[key]; ok && from >= miss {
+		return -1
+	}
+
+	s := scan.s
+	for j := from; j < len(s); {
+		switch s[j] {
+		case '\\':
+			j += 2
+			continue
+		case '`':
+			m := run(s, j, '`')
+			if end := scan.codeSpanEnd(j+m, m); end >= 0 {
+				j = end + m
+				continue
+			}
+			j += m
+			continue
+		}
+
+		if s[j] != c {
+			j++
+			continue
+		}
+
+		m := run(s, j, c)
+		closes := m == n && j > from && s[j-1] != ' ' && s[j-1] != '\n'
+		if c == '_' && j+m < len(s) && isWordByte(s[j+m]) {
+			closes = false
+		}
+		if closes {
+			return j
+		}
+		j += m
+	}
+
+	scan.delimiterMisses[key] = from
+	return -1
+}
+
+func (scan *inlineScan) codeSpanEnd(from int, n int) int {
+	if miss, ok := scan.codeSpanMisses[n]; ok && from >= miss {
+		return -1
+	}
+
+	s := scan.s
+	for j := from; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+
+		m := run(s, j, '`')
+		if m == n {
+			return j
+		}
+		j += m
+	}
+
+	scan.codeSpanMisses[n] = from
+	return -1
+}
+
+// link parses [text](url "title"), [text][label], [text][] and [label] starting at the bracket at i.
+// It returns the text, the URL and the length of the link.
+func (p *parser) link(scan *inlineScan, i int) (string, string, int, bool) {
+	close, ok := scan.brackets[i]
+	if !ok {
+		return "", "", 0, false
+	}
+
+	s := scan.s[i:]
+	end := close - i
+	text := s[1:end]
+	rest := s[end+1:]
+
+	if strings.HasPrefix(rest, "(") {
+		if href, n, ok := parseDestination(rest); ok {
+			return text, href, end + 1 + n, true
+		}
+	}
+
+	label, n := text, 0
+	if strings.HasPrefix(rest, "[") {
+		window := rest
+		if len(window) > maxLabelLength+2 {
+			window = window[:maxLabelLength+2]
+		}
+		if close := strings.IndexByte(window, ']'); close > 0 {
+			if close > 1 {
+				label = rest[1:close]
+			}
+			n = close + 1
+		}
+	}
+
+	if len(label) > maxLabelLength {
+		return "", "", 0, false
+	}
+
+	if ref, ok := p.refs[normalizeLabel(label)]; ok {
+		return text, ref.url, end + 1 + n, true
+	}
+
+	return "", "", 0, false
+}
+
+// parseDestination parses (url "title"), the title is dropped since article_data has no place for it
+func parseDestination(s string) (string, int, bool) {
+	if len(s) > maxDestinationLength {
+		s = s[:maxDestinationLength]
+	}
+
+	j := 1
+	for j < len(s) && s[j] == ' ' {
+		j++
+	}
+
+	var href string
+	if j < len(s) && s[j] == '<' {
+		close := strings.IndexByte(s[j:], '>')
+		if close < 0 {
+			return "", 0, false
+		}
+		href = s[j+1 : j+close]
+		j += close + 1
+	} else {
+		start, parens := j, 0
+		for ; j < len(s) && s[j] != ' ' && s[j] != '\n'; j++ {
+			if s[j] == '(' {
+				parens++
+			} else if s[j] == ')' {
+				if parens == 0 {
+					break
+				}
+				parens--
+			}
+		}
+		href = s[start:j]
+	}
+
+	for j < len(s) && (s[j] == ' ' || s[j] == '\n') {
+		j++
+	}
+
+	if j < len(s) && (s[j] == '"' || s[j] == '\'' || s[j] == '(') {
+		quote := s[j]
+		if quote == '(' {
+			quote = ')'
+		}
+		close := strings.IndexByte(s[j+1:], quote)
+		if close < 0 {
+			return "", 0, false
+		}
+		j += close + 2
+		for j < len(s) && (s[j] == ' ' || s[j] == '\n') {
+			j++
+		}
+	}
+
+	if j >= len(s) || s[j] != ')' {
+		return "", 0, false
+	}
+
+	return href, j + 1, true
+}
+
+// anchor makes the a tag, links the renderer wouldn't keep become plain text
+func (p *parser) anchor(href string, text string, line int) string {
+	parsed, err := url.Parse(href)
+	scheme := ""
+	if err == nil {
+		scheme = strings.ToLower(parsed.Scheme)
+	}
+
+	if err != nil || !(scheme == "http" || scheme == "https") && scheme != "mailto" || scheme != "mailto" && parsed.Host == "" {
+		p.warn(line, "only absolute http, https and mailto links are supported, "+href+" is kept as text")
+		return text
+	}
+
+	return `<a href="` + strings.NewReplacer(`"`, "%22", "<", "%3C", ">", "%3E").Replace(parsed.String()) + `">` + text + "</a>"
+}
+
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+func run(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+
+	return n
+}
+
+func isDelimiter(c byte) bool {
+	return c == '[' || c == '`' || c == '*' || c == '_' || c == '~'
+}
+
+func isPunctuation(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+// Bytes of multibyte characters count as letters
+func isWordByte(c byte) bool {
+	return c >= 0x80 || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,52 @@
+package markdown
+
+import (
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestInline(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"emphasis", "*a* **b** ***c*** _d_ ~~e~~", "<em>a</em> <strong>b</strong> <strong><em>c</em></strong> <em>d</em> <s>e</s>"},
+		{"nested emphasis", "**bold *inner* bold**", "<strong>bold <em>inner</em> bold</strong>"},
+		{"underscores inside words", "snake_case_name", "snake_case_name"},
+		{"code spans", "`code *x*` and ``a`b``", "<code>code *x*</code> and <code>a`b</code>"},
+		{"links", `[link](https://a.io) [t](https://a.io "title")`, `<a href="https://a.io">link</a> <a href="https://a.io">t</a>`},
+		{"reference links", "[ref][r] [r] [r][]", `<a href="https://r.io">ref</a> <a href="https://r.io">r</a> <a href="https://r.io">r</a>`},
+		{"brackets around a link", "[[nested](https://a.io)]", `[<a href="https://a.io">nested</a>]`},
+		{"unsupported link", "[a](javascript:alert(1))", "a"},
+		{"image", "![img](https://a.io/i.png)", "img"},
+		{"unclosed delimiters", "*unclosed and [unclosed `unclosed", "*unclosed and [unclosed `unclosed"},
+		{"escapes", `\*escaped\*`, "*escaped*"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := &parser{refs: map[string]link{"r": {url: "https://r.io"}}, warned: map[models.ImportWarning]bool{}}
+
+			if got := p.inline(test.source, 1); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestInlineDelimiterLimit(t *testing.T) {
+	p := &parser{refs: map[string]link{}, warned: map[models.ImportWarning]bool{}}
+	// The closing delimiters aren't counted
+	source := strings.Repeat("*a* ", 2*maxDelimiters)
+
+	got := p.inline(source, 1)
+
+	if want := strings.Repeat("<em>a</em> ", maxDelimiters) + strings.Repeat("*a* ", maxDelimiters); got != want {
+		t.Errorf("got %.40q..., want %.40q...", got, want)
+	}
+	if len(p.warnings) != 1 {
+		t.Errorf("got warnings %v, want one", p.warnings)
+	}
+}
@@ -0,0 +1,444 @@
+package markdown
+
+import (
+	"fmt"
+	"github.com/shuryak/shuryak-backend/internal/blocks"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"regexp"
+	"strings"
+)
+
+type parsedBlock struct {
+	blocks.Block
+	line int // Where the block starts in the source, for the warnings
+}
+
+var (
+	fencePattern          = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ ]*([^`]*)$")
+	headingPattern        = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ ]+(.*?))?(?:[ ]+#+)?[ ]*$`)
+	thematicBreakPattern  = regexp.MustCompile(`^ {0,3}(?:(?:\*[ ]*){3,}|(?:-[ ]*){3,}|(?:_[ ]*){3,})$`)
+	quotePattern          = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+	listItemPattern       = regexp.MustCompile(`^( {0,3})([-*+]|[0-9]{1,9}[.)])(?:( +)(.*))?$`)
+	htmlBlockPattern      = regexp.MustCompile(`^ {0,3}<(?:/?[a-zA-Z][a-zA-Z0-9-]*(?:[ />]|$)|!--)`)
+	setextPattern         = regexp.MustCompile(`^ {0,3}(=+|-+)[ ]*$`)
+	linkDefinitionPattern = regexp.MustCompile(`^ {0,3}\[([^\]]+)\]:[ ]*<?([^ >]+)>?(?:[ ]+["'(](.*)["')])?[ ]*$`)
+	tableDelimiterPattern = regexp.MustCompile(`^[ ]*\|?[ ]*:?-+:?[ ]*(?:\|[ ]*:?-+:?[ ]*)+\|?[ ]*$`)
+	imagePattern          = regexp.MustCompile(`^!\[([^\]]*)\]\([ ]*<?([^ >)]+)>?(?:[ ]+"([^"]*)")?[ ]*\)$`)
+)
+
+// parseBlocks parses the lines starting at the source line firstLine, the text fields of the
+// blocks keep their markdown until convert. Hard line breaks are marked as "\\\n".
+func (p *parser) parseBlocks(lines []string, firstLine int, depth int) []parsedBlock {
+	var parsed []parsedBlock
+
+	// Quotes and list items parse their lines again, deep nesting would make it quadratic
+	if p.nesting >= maxNesting {
+		p.warn(firstLine, fmt.Sprint("blocks nested deeper than ", maxNesting, " are kept as text"))
+		return textBlock(lines, firstLine)
+	}
+	p.nesting++
+	defer func() { p.nesting-- }()
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		lineNumber := firstLine + i
+
+		if strings.TrimSpace(line) == "" {
+			i++
+			continue
+		}
+
+		if match := fencePattern.FindStringSubmatch(line); match != nil {
+			block, next := p.parseFence(lines, i, firstLine, match)
+			parsed = append(parsed, parsedBlock{block, lineNumber})
+			i = next
+			continue
+		}
+
+		if match := headingPattern.FindStringSubmatch(line); match != nil {
+			parsed = append(parsed, parsedBlock{blocks.Block{Type: blocks.TypeHeading, Level: len(match[1]), Text: match[2]}, lineNumber})
+			i++
+			continue
+		}
+
+		if thematicBreakPattern.MatchString(line) {
+			p.warn(lineNumber, "thematic breaks aren't supported, it's skipped")
+			i++
+			continue
+		}
+
+		if quotePattern.MatchString(line) {
+			block, next := p.parseQuote(lines, i, firstLine, depth)
+			if block.Text != "" {
+				parsed = append(parsed, parsedBlock{block, lineNumber})
+			}
+			i = next
+			continue
+		}
+
+		if listItemPattern.MatchString(line) {
+			block, next := p.parseList(lines, i, firstLine, depth)
+			if len(block.Items) != 0 {
+				parsed = append(parsed, parsedBlock{block, lineNumber})
+			}
+			i = next
+			continue
+		}
+
+		if indentation(line) >= 4 {
+			block, next := parseIndentedCode(lines, i)
+			parsed = append(parsed, parsedBlock{block, lineNumber})
+			i = next
+			continue
+		}
+
+		if htmlBlockPattern.MatchString(line) {
+			p.warn(lineNumber, "raw HTML blocks aren't supported, it's skipped")
+			for i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+				i++
+			}
+			continue
+		}
+
+		if match := linkDefinitionPattern.FindStringSubmatch(line); match != nil {
+			label := normalizeLabel(match[1])
+			if _, ok := p.refs[label]; !ok {
+				p.refs[label] = link{url: match[2], title: match[3]}
+			}
+			i++
+			continue
+		}
+
+		block, next := p.parseParagraph(lines, i, firstLine)
+		parsed = append(parsed, parsedBlock{block, lineNumber})
+		i = next
+	}
+
+	return parsed
+}
+
+// textBlock joins the lines into one paragraph without looking at their syntax
+func textBlock(lines []string, firstLine int) []parsedBlock {
+	var text []string
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			text = append(text, line)
+		}
+	}
+
+	if len(text) == 0 {
+		return nil
+	}
+
+	return []parsedBlock{{blocks.Block{Type: blocks.TypeParagraph, Text: strings.Join(text, "\n")}, firstLine}}
+}
+
+func (p *parser) parseFence(lines []string, i int, firstLine int, match []string) (blocks.Block, int) {
+	indent, fence := len(match[1]), match[2]
+
+	language := ""
+	if info := strings.Fields(match[3]); len(info) != 0 {
+		language = strings.ToLower(info[0])
+		if !blocks.IsLanguage(language) {
+			p.warn(firstLine+i, "code language "+info[0]+" isn't supported, it's dropped")
+			language = ""
+		}
+	}
+
+	var code []string
+	j := i + 1
+	for ; j < len(lines) && !closesFence(lines[j], fence); j++ {
+		code = append(code, trimIndentation(lines[j], indent))
+	}
+
+	// An unclosed fence runs to the end of the document
+	if j < len(lines) {
+		j++
+	}
+
+	return blocks.Block{Type: blocks.TypeCode, Language: language, Code: strings.Join(code, "\n")}, j
+}
+
+// closesFence tells if the line is a run of at least as many fence characters
+func closesFence(line string, fence string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return false
+	}
+
+	n := run(trimmed, 0, fence[0])
+	return n >= len(fence) && strings.TrimRight(trimmed[n:], " ") == ""
+}
+
+func parseIndentedCode(lines []string, i int) (blocks.Block, int) {
+	var code []string
+
+	j := i
+	for ; j < len(lines) && (indentation(lines[j]) >= 4 || strings.TrimSpace(lines[j]) == ""); j++ {
+		code = append(code, trimIndentation(lines[j], 4))
+	}
+
+	for len(code) != 0 && strings.TrimSpace(code[len(code)-1]) == "" {
+		code = code[:len(code)-1]
+	}
+
+	return blocks.Block{Type: blocks.TypeCode, Code: strings.Join(code, "\n")}, j
+}
+
+// parseQuote keeps the paragraphs of the quote, a last paragraph starting with a dash is its caption
+func (p *parser) parseQuote(lines []string, i int, firstLine int, depth int) (blocks.Block, int) {
+	var inner []string
+
+	j := i
+	for ; j < len(lines); j++ {
+		match := quotePattern.FindStringSubmatch(lines[j])
+		if match == nil {
+			break
+		}
+		inner = append(inner, match[1])
+	}
+
+	var paragraphs []string
+	for _, block := range p.parseBlocks(inner, firstLine+i, depth) {
+		switch block.Type {
+		case blocks.TypeParagraph:
+			paragraphs = append(paragraphs, block.Text)
+		case blocks.TypeQuote:
+			p.warn(block.line, "nested quotes aren't supported, they're merged into the outer one")
+			paragraphs = append(paragraphs, block.Text)
+		default:
+			p.warn(block.line, "only paragraphs are supported in quotes, the "+block.Type+" is skipped")
+		}
+	}
+
+	block := blocks.Block{Type: blocks.TypeQuote}
+	if n := len(paragraphs); n > 1 {
+		for _, dash := range []string{"— ", "-- ", "– "} {
+			if strings.HasPrefix(paragraphs[n-1], dash) {
+				block.Caption = strings.TrimPrefix(paragraphs[n-1], dash)
+				paragraphs = paragraphs[:n-1]
+				break
+			}
+		}
+	}
+	block.Text = strings.Join(paragraphs, "\\\n\\\n")
+
+	return block, j
+}
+
+// parseList reads the items of one list, an item is its lines indented to the content of the
+// first one, parsed as a document of its own
+func (p *parser) parseList(lines []string, i int, firstLine int, depth int) (blocks.Block, int) {
+	first := listItemPattern.FindStringSubmatch(lines[i])
+	kind := listMarkerKind(first[2])
+
+	style := "unordered"
+	if kind == "." || kind == ")" {
+		style = "ordered"
+	}
+	list := blocks.Block{Type: blocks.TypeList, Style: style}
+
+	j := i
+	for j < len(lines) {
+		match := listItemPattern.FindStringSubmatch(lines[j])
+		if match == nil || listMarkerKind(match[2]) != kind || thematicBreakPattern.MatchString(lines[j]) {
+			break
+		}
+
+		contentColumn := len(match[1]) + len(match[2]) + len(match[3])
+		if len(match[3]) > 4 || match[4] == "" {
+			contentColumn = len(match[1]) + len(match[2]) + 1
+		}
+
+		itemStart := j
+		// A bare marker starts an empty item
+		itemLines := []string{""}
+		if match[3] != "" {
+			itemLines[0] = strings.Repeat(" ", len(match[3])-(contentColumn-len(match[1])-len(match[2]))) + match[4]
+		}
+
+		for j++; j < len(lines); j++ {
+			line := lines[j]
+
+			if strings.TrimSpace(line) == "" {
+				// A blank line continues the item only if the next line is indented into it
+				k := j
+				for k < len(lines) && strings.TrimSpace(lines[k]) == "" {
+					k++
+				}
+				if k == len(lines) || indentation(lines[k]) < contentColumn {
+					break
+				}
+				itemLines = append(itemLines, "")
+				continue
+			}
+
+			if indentation(line) >= contentColumn {
+				itemLines = append(itemLines, line[contentColumn:])
+				continue
+			}
+
+			// Lazy continuation of the item's paragraph, the next item ends it
+			previous := itemLines[len(itemLines)-1]
+			if strings.TrimSpace(previous) != "" && !startsBlock(line) && !listItemPattern.MatchString(line) {
+				itemLines = append(itemLines, strings.TrimSpace(line))
+				continue
+			}
+
+			break
+		}
+
+		item, nested := p.parseListItem(itemLines, firstLine+itemStart, depth)
+		if item.Text != "" || len(item.Items) != 0 {
+			list.Items = append(list.Items, item)
+		}
+		list.Items = append(list.Items, nested...)
+
+		// Blank lines between the items
+		for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+			k := j
+			for k < len(lines) && strings.TrimSpace(lines[k]) == "" {
+				k++
+			}
+			if k == len(lines) || !listItemPattern.MatchString(lines[k]) {
+				return list, j
+			}
+			j = k
+		}
+	}
+
+	return list, j
+}
+
+// parseListItem returns the item and, past the nesting limit, the flattened items of its nested lists
+func (p *parser) parseListItem(lines []string, firstLine int, depth int) (blocks.ListItem, []blocks.ListItem) {
+	var item blocks.ListItem
+	var paragraphs []string
+	var flattened []blocks.ListItem
+
+	for _, block := range p.parseBlocks(lines, firstLine, depth+1) {
+		switch block.Type {
+		case blocks.TypeParagraph, blocks.TypeHeading:
+			paragraphs = append(paragraphs, block.Text)
+		case blocks.TypeList:
+			if depth >= int(models.ArticleListMaxDepth) {
+				p.warn(block.line, fmt.Sprint("lists nested deeper than ", models.ArticleListMaxDepth, " are flattened"))
+				flattened = append(flattened, flatten(block.Items)...)
+			} else {
+				item.Items = append(item.Items, block.Items...)
+			}
+		default:
+			p.warn(block.line, "only text and nested lists are supported in list items, the "+block.Type+" is skipped")
+		}
+	}
+
+	item.Text = strings.Join(paragraphs, "\\\n\\\n")
+	return item, flattened
+}
+
+func (p *parser) parseParagraph(lines []string, i int, firstLine int) (blocks.Block, int) {
+	paragraph := []string{strings.TrimSpace(lines[i])}
+	hardBreak := []bool{hasHardBreak(lines[i])}
+
+	j := i + 1
+	for ; j < len(lines); j++ {
+		line := lines[j]
+
+		if match := setextPattern.FindStringSubmatch(line); match != nil {
+			level := 1
+			if match[1][0] == '-' {
+				level = 2
+			}
+			return blocks.Block{Type: blocks.TypeHeading, Level: level, Text: strings.Join(paragraph, "\n")}, j + 1
+		}
+
+		if strings.TrimSpace(line) == "" || startsBlock(line) {
+			break
+		}
+
+		paragraph = append(paragraph, strings.TrimSpace(line))
+		hardBreak = append(hardBreak, hasHardBreak(line))
+	}
+
+	if len(paragraph) == 1 {
+		if match := imagePattern.FindStringSubmatch(paragraph[0]); match != nil {
+			if !strings.HasPrefix(match[2], "http://") && !strings.HasPrefix(match[2], "https://") {
+				p.warn(firstLine+i, "only http and https images are supported, the image is skipped")
+				return blocks.Block{}, j
+			}
+			return blocks.Block{Type: blocks.TypeImage, Url: match[2], Alt: match[1], Caption: match[3]}, j
+		}
+	}
+
+	// Tables are kept as lines of text
+	if len(paragraph) > 1 && strings.Contains(paragraph[0], "|") && tableDelimiterPattern.MatchString(paragraph[1]) {
+		p.warn(firstLine+i, "tables aren't supported, their rows are kept as lines of text")
+		paragraph = append(paragraph[:1], paragraph[2:]...)
+		for k := range hardBreak {
+			hardBreak[k] = true
+		}
+	}
+
+	var text strings.Builder
+	for k, line := range paragraph {
+		if k != 0 {
+			if hardBreak[k-1] {
+				text.WriteString("\\")
+			}
+			text.WriteString("\n")
+		}
+		text.WriteString(strings.TrimSuffix(line, "\\"))
+		if k == len(paragraph)-1 && strings.HasSuffix(line, "\\") {
+			// A backslash at the end of the paragraph is just a backslash
+			text.WriteString("\\\\")
+		}
+	}
+
+	return blocks.Block{Type: blocks.TypeParagraph, Text: text.String()}, j
+}
+
+// startsBlock tells if the line interrupts a paragraph
+func startsBlock(line string) bool {
+	if headingPattern.MatchString(line) || fencePattern.MatchString(line) || quotePattern.MatchString(line) ||
+		thematicBreakPattern.MatchString(line) || htmlBlockPattern.MatchString(line) {
+		return true
+	}
+
+	// Only non-empty items interrupt, and ordered ones only when they start with 1
+	match := listItemPattern.FindStringSubmatch(line)
+	return match != nil && strings.TrimSpace(match[4]) != "" && (listMarkerKind(match[2]) == match[2] || strings.TrimLeft(match[2], "0") == "1"+listMarkerKind(match[2]))
+}
+
+func hasHardBreak(line string) bool {
+	return strings.HasSuffix(line, "  ") || strings.HasSuffix(line, "\\")
+}
+
+// listMarkerKind is the bullet itself or the delimiter of an ordered list
+func listMarkerKind(marker string) string {
+	return marker[len(marker)-1:]
+}
+
+func flatten(items []blocks.ListItem) []blocks.ListItem {
+	var flat []blocks.ListItem
+
+	for _, item := range items {
+		nested := item.Items
+		item.Items = nil
+		flat = append(flat, item)
+		flat = append(flat, flatten(nested)...)
+	}
+
+	return flat
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func trimIndentation(line string, width int) string {
+	if n := indentation(line); n < width {
+		width = n
+	}
+
+	return line[width:]
+}
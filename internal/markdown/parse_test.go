@@ -0,0 +1,86 @@
+package markdown
+
+import (
+	"github.com/shuryak/shuryak-backend/internal/blocks"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestImportLists(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []blocks.ListItem
+	}{
+		// Empty items can't be stored, they're dropped along with the lists left empty
+		{"bare asterisk", "*", nil},
+		{"bare dash", "-", nil},
+		{"bare marker with a space", "- ", nil},
+		{"bare ordered marker", "1.", nil},
+		{"empty item in the middle", "- a\n-\n- b", []blocks.ListItem{{Text: "a"}, {Text: "b"}}},
+		{"empty item at the end", "- a\n-", []blocks.ListItem{{Text: "a"}}},
+		{"items", "- a\n- b", []blocks.ListItem{{Text: "a"}, {Text: "b"}}},
+		{"nested", "- a\n  - b", []blocks.ListItem{{Text: "a", Items: []blocks.ListItem{{Text: "b"}}}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := Import(test.source)
+
+			if test.want == nil {
+				if len(result.Document.Blocks) != 0 {
+					t.Errorf("got blocks %+v, want none", result.Document.Blocks)
+				}
+				return
+			}
+			if len(result.Document.Blocks) != 1 || result.Document.Blocks[0].Type != blocks.TypeList {
+				t.Fatalf("got blocks %+v, want one list", result.Document.Blocks)
+			}
+			if got := result.Document.Blocks[0].Items; !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got items %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestImportEmptyBlocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		warning models.ImportWarning
+	}{
+		{"heading", "a\n\n#\n\nb", models.ImportWarning{Line: 3, Message: "empty heading blocks aren't supported, it's skipped"}},
+		{"fence", "a\n\n```\n```", models.ImportWarning{Line: 3, Message: "empty code blocks aren't supported, it's skipped"}},
+		{"unclosed fence", "a\n\n~~~", models.ImportWarning{Line: 3, Message: "empty code blocks aren't supported, it's skipped"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := Import(test.source)
+
+			for _, block := range result.Document.Blocks {
+				if block.Type != blocks.TypeParagraph {
+					t.Errorf("got block %+v, want only the paragraphs", block)
+				}
+			}
+			if !reflect.DeepEqual(result.Warnings, []models.ImportWarning{test.warning}) {
+				t.Errorf("got warnings %v, want %v", result.Warnings, test.warning)
+			}
+		})
+	}
+}
+
+func TestImportWarningLines(t *testing.T) {
+	source := "---\nname: Name\n---\n\n> ```{.python}\n> code\n> ```"
+
+	result := Import(source)
+
+	want := []models.ImportWarning{
+		{Line: 5, Message: "code language {.python} isn't supported, it's dropped"},
+		{Line: 5, Message: "only paragraphs are supported in quotes, the code is skipped"},
+	}
+	if !reflect.DeepEqual(result.Warnings, want) {
+		t.Errorf("got warnings %v, want %v", result.Warnings, want)
+	}
+}
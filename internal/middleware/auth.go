@@ -3,13 +3,24 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"github.com/dgrijalva/jwt-go"
 	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
 	"github.com/shuryak/shuryak-backend/internal/utils"
+	"github.com/shuryak/shuryak-backend/internal/utils/http-result"
 	"net/http"
 	"strings"
 )
 
-func IsAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+type Auth struct {
+	revokedTokens repositories.RevokedTokenRepository
+}
+
+func NewAuth(revokedTokens repositories.RevokedTokenRepository) *Auth {
+	return &Auth{revokedTokens: revokedTokens}
+}
+
+func (auth *Auth) IsAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// https://medium.com/@zhashkevych/jwt-авторизация-для-вашего-api-на-go-80325de8691b
 		authHeader := r.Header.Get("Authorization")
@@ -48,7 +59,7 @@ func IsAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		// 	return
 		// }
 
-		if claims, isValid, err := utils.GetClaimsFromToken(headerParts[1]); err != nil {
+		if claims, isValid, err := utils.GetClaimsFromToken(headerParts[1], utils.AccessTokenType); err != nil {
 			if !isValid {
 				errorMessage := models.ErrorDTO{
 					ErrorCode: models.InvalidToken,
@@ -66,8 +77,64 @@ func IsAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			json.NewEncoder(w).Encode(errorMessage)
 			return
 		} else {
+			tokenId, _ := claims["jti"].(string)
+
+			isRevoked, err := auth.revokedTokens.IsRevoked(r.Context(), tokenId)
+			if err != nil {
+				http_result.WriteError(&w, models.InternalError, "internal error")
+				return
+			}
+
+			if isRevoked {
+				http_result.WriteError(&w, models.InvalidToken, "token is revoked")
+				return
+			}
+
+			// Revoking a session revokes the access tokens issued for it, the ids don't overlap
+			if sessionId, _ := claims["sid"].(string); sessionId != "" {
+				isRevoked, err = auth.revokedTokens.IsRevoked(r.Context(), sessionId)
+				if err != nil {
+					http_result.WriteError(&w, models.InternalError, "internal error")
+					return
+				}
+
+				if isRevoked {
+					http_result.WriteError(&w, models.InvalidToken, "token is revoked")
+					return
+				}
+			}
+
+			// Logging out of all the sessions revokes every access token issued before it
+			nickname, _ := claims["nickname"].(string)
+			issuedAt, _ := claims["iat"].(float64)
+
+			revokedBefore, err := auth.revokedTokens.RevokedBefore(r.Context(), nickname)
+			if err != nil {
+				http_result.WriteError(&w, models.InternalError, "internal error")
+				return
+			}
+
+			if int64(issuedAt) < revokedBefore.Unix() {
+				http_result.WriteError(&w, models.InvalidToken, "token is revoked")
+				return
+			}
+
 			ctx := context.WithValue(context.Background(), models.JwtClaimsKey, claims)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		}
 	}
 }
+
+// RequireRole lets through only users whose role includes the given one
+func (auth *Auth) RequireRole(role models.Role, next http.HandlerFunc) http.HandlerFunc {
+	return auth.IsAuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value(models.JwtClaimsKey).(jwt.MapClaims)
+
+		if !models.RoleFromClaims(claims).Includes(role) {
+			http_result.WriteError(&w, models.Forbidden, "role "+string(role)+" is required")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -2,43 +2,188 @@ package models
 
 import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
 )
 
 type MetaArticle struct {
-	Id        string `json:"id" bson:"custom_id"`
-	Author    string `json:"author"`
-	Name      string `json:"name"`
-	IsDraft   bool   `json:"is_draft" bson:"is_draft"`
-	Thumbnail string `json:"thumbnail"`
+	Id             string         `json:"id" bson:"custom_id"`
+	Author         string         `json:"author"`
+	Name           string         `json:"name"`
+	IsDraft        bool           `json:"is_draft" bson:"is_draft"` // Derived from the state
+	State          ArticleState   `json:"state" bson:"state"`
+	Thumbnail      string         `json:"thumbnail"`
+	Tags           []string       `json:"tags"`
+	Category       string         `json:"category"`
+	PublishAt      *time.Time     `json:"publish_at,omitempty" bson:"publish_at"`
+	PublishedAt    *time.Time     `json:"published_at,omitempty" bson:"published_at"`
+	ViewCount      int64          `json:"view_count" bson:"view_count"`
+	Reactions      ReactionCounts `json:"reactions" bson:"reactions"`         // Kinds nobody reacted with are left out
+	CommentCount   int64          `json:"comment_count" bson:"comment_count"` // Neither deleted nor hidden
+	CommentsLocked bool           `json:"comments_locked" bson:"comments_locked"`
+	DeletedAt      *time.Time     `json:"deleted_at,omitempty" bson:"deleted_at"`
 }
 
 type ArticleCustomIdDTO struct {
 	CustomId string `json:"id"`
 }
 
+type GetArticleExpression struct {
+	CustomId string `json:"id"`
+	Format   string `json:"format"` // json (default), html, markdown or text
+}
+
+type ImportMarkdownExpression struct {
+	Markdown string `json:"markdown"` // Front matter sets name, id, thumbnail and tags
+	IsDraft  bool   `json:"is_draft"`
+	DryRun   bool   `json:"dry_run"` // Only convert, don't create the article
+}
+
+type ImportWarning struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+type ImportedArticleDTO struct {
+	Article  ArticleDTO      `json:"article"`
+	Warnings []ImportWarning `json:"warnings"`
+}
+
+// RenderedArticleDTO replaces article_data with its rendering in the requested format
+type RenderedArticleDTO struct {
+	MetaArticle
+	Version uint64 `json:"version"`
+	Format  string `json:"format"`
+	Content string `json:"content"`
+}
+
 type ArticleDTO struct {
 	CustomId    string                 `json:"id" bson:"custom_id"`
 	Name        string                 `json:"name" bson:"name"`
 	Author      string                 `json:"author" bson:"author"`
 	IsDraft     bool                   `json:"is_draft" bson:"is_draft"`
+	State       ArticleState           `json:"state" bson:"state"` // Only returned, it's changed with the workflow endpoints
 	Thumbnail   string                 `json:"thumbnail" bson:"thumbnail"`
+	Tags        []string               `json:"tags" bson:"tags"`         // Normalized on save, see NormalizeTag
+	Category    string                 `json:"category" bson:"category"` // Id of a category, empty for none
 	ArticleData map[string]interface{} `json:"article_data" bson:"article_data"`
+	PublishAt   *time.Time             `json:"publish_at,omitempty" bson:"publish_at"`     // A future time keeps the article a draft until then
+	PublishedAt *time.Time             `json:"published_at,omitempty" bson:"published_at"` // Only returned
+	Version     uint64                 `json:"version" bson:"version"`                     // On update, the version that was edited, required unless If-Match is sent
 	// https://medium.com/rungo/working-with-json-in-go-7e3a37c5a07b
 }
 
 type Article struct {
-	Id          primitive.ObjectID     `bson:"_id"`
-	CustomId    string                 `bson:"custom_id"`
-	Author      string                 `bson:"author"`
-	Name        string                 `bson:"name"`
-	IsDraft     bool                   `bson:"is_draft"`
-	Thumbnail   string                 `bson:"thumbnail"`
-	ArticleData map[string]interface{} `bson:"article_data"`
+	Id             primitive.ObjectID     `bson:"_id"`
+	CustomId       string                 `bson:"custom_id"`
+	Author         string                 `bson:"author"`
+	Name           string                 `bson:"name"`
+	IsDraft        bool                   `bson:"is_draft"` // Kept equal to State != StatePublished by SetState
+	State          ArticleState           `bson:"state"`
+	Reviewer       string                 `bson:"reviewer"`
+	Thumbnail      string                 `bson:"thumbnail"`
+	Tags           []string               `bson:"tags"`
+	Category       string                 `bson:"category"`
+	ArticleData    map[string]interface{} `bson:"article_data"`
+	ReviewComments []ReviewComment        `bson:"review_comments"`
+	SearchText     string                 `bson:"search_text"`   // Plain text of the content for the text index, set by the repositories
+	PublishAt      *time.Time             `bson:"publish_at"`    // When the scheduler publishes or has published the draft
+	PublishedAt    *time.Time             `bson:"published_at"`  // When the article was last published, the lists are sorted by it
+	ViewCount      int64                  `bson:"view_count"`    // Only changed by the view counter, Update keeps it
+	Reactions      ReactionCounts         `bson:"reactions"`     // Only changed with the reactions, Update keeps it
+	CommentCount   int64                  `bson:"comment_count"` // Only changed with the comments, Update keeps it
+	CommentsLocked bool                   `bson:"comments_locked"`
+	DeletedAt      *time.Time             `bson:"deleted_at"` // nil unless the article is in the trash
+	Version        uint64                 `bson:"version"`    // Incremented on every update
 	// https://medium.com/rungo/working-with-json-in-go-7e3a37c5a07b
 }
 
+type SearchResultDTO struct {
+	MetaArticle
+	Score   float64 `json:"score"`   // 0 for the articles found by the beginning of the name
+	Snippet string  `json:"snippet"` // HTML-escaped, the matching words are wrapped in <mark>
+}
+
+type SearchResponseDTO struct {
+	Results    []SearchResultDTO     `json:"results"`
+	NextCursor string                `json:"next_cursor,omitempty"` // Empty on the last page
+	TotalCount int                   `json:"total_count"`
+	Facets     map[string][]FacetDTO `json:"facets"` // "author" and "tag" values among all matches
+}
+
+// MetaPageDTO is the response of the lists paged with a cursor or asked for the total count
+type MetaPageDTO struct {
+	Results    []*MetaArticle `json:"results"`
+	NextCursor string         `json:"next_cursor,omitempty"` // Empty on the last page
+	TotalCount *int64         `json:"total_count,omitempty"`
+}
+
+type SearchPageDTO struct {
+	Results    []SearchResultDTO `json:"results"`
+	NextCursor string            `json:"next_cursor,omitempty"` // Empty on the last page
+	TotalCount *int64            `json:"total_count,omitempty"`
+}
+
+type FacetDTO struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
 type GetArticlesListExpression struct {
 	GetDrafts bool `json:"get_drafts"`
 	Count     uint `json:"count"`
 	Offset    uint `json:"offset"`
 }
+
+func (article Article) ToMeta() MetaArticle {
+	return MetaArticle{
+		Id:             article.CustomId,
+		Author:         article.Author,
+		Name:           article.Name,
+		IsDraft:        article.IsDraft,
+		State:          article.EffectiveState(),
+		Thumbnail:      article.Thumbnail,
+		Tags:           article.Tags,
+		Category:       article.Category,
+		PublishAt:      article.PublishAt,
+		PublishedAt:    article.PublishedAt,
+		ViewCount:      article.ViewCount,
+		Reactions:      reactionCounts(article.Reactions),
+		CommentCount:   article.CommentCount,
+		CommentsLocked: article.CommentsLocked,
+		DeletedAt:      article.DeletedAt,
+	}
+}
+
+// reactionCounts drops the kinds whose reactions were all taken back
+func reactionCounts(counts ReactionCounts) ReactionCounts {
+	result := make(ReactionCounts)
+	for kind, count := range counts {
+		if count > 0 {
+			result[kind] = count
+		}
+	}
+
+	return result
+}
+
+func (article Article) ToDTO() ArticleDTO {
+	return ArticleDTO{
+		CustomId:    article.CustomId,
+		Name:        article.Name,
+		Author:      article.Author,
+		IsDraft:     article.IsDraft,
+		State:       article.EffectiveState(),
+		Thumbnail:   article.Thumbnail,
+		Tags:        article.Tags,
+		Category:    article.Category,
+		ArticleData: article.ArticleData,
+		PublishAt:   article.PublishAt,
+		PublishedAt: article.PublishedAt,
+		Version:     article.Version,
+	}
+}
+
+// IsScheduled reports whether the article waits for the scheduler to be published
+func (article Article) IsScheduled(now time.Time) bool {
+	return article.PublishAt != nil && article.PublishAt.After(now)
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Bookmark is an article saved by a user, either to the plain bookmarks or to one of the reading lists
+type Bookmark struct {
+	Nickname  string              `bson:"nickname"`
+	ListId    *primitive.ObjectID `bson:"list_id"`    // nil for the plain bookmarks
+	ArticleId string              `bson:"article_id"` // Custom id of the article
+	Position  int64               `bson:"position"`   // The order within the list, set by the user
+	CreatedAt time.Time           `bson:"created_at"`
+}
+
+type ReadingList struct {
+	Id        primitive.ObjectID `bson:"_id"`
+	Nickname  string             `bson:"nickname"`
+	Name      string             `bson:"name"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+type BookmarkDTO struct {
+	ArticleId string `json:"article_id"`
+	ListId    string `json:"list_id"` // Empty for the plain bookmarks
+}
+
+type GetBookmarksExpression struct {
+	ListId string `json:"list_id"` // Empty for the plain bookmarks
+	Count  uint   `json:"count"`
+	Offset uint   `json:"offset"`
+}
+
+type BookmarkedArticleDTO struct {
+	MetaArticle
+	AddedAt time.Time `json:"added_at"`
+}
+
+type ReadingListDTO struct {
+	Id           string    `json:"id"`
+	Name         string    `json:"name"`
+	ArticleCount int64     `json:"article_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type CreateReadingListDTO struct {
+	Name string `json:"name"`
+}
+
+type RenameReadingListDTO struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type ReadingListIdDTO struct {
+	Id string `json:"id"`
+}
+
+type ReorderBookmarksDTO struct {
+	ListId   string   `json:"list_id"`  // Empty for the plain bookmarks
+	Articles []string `json:"articles"` // Custom ids of all the articles of the list in the new order
+}
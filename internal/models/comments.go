@@ -0,0 +1,113 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Comment is a comment on an article or a reply to another comment. Deleted comments keep their place
+// in the thread so that their replies stay readable.
+type Comment struct {
+	Id        primitive.ObjectID   `bson:"_id"`
+	ArticleId string               `bson:"article_id"` // Custom id of the article
+	ParentId  *primitive.ObjectID  `bson:"parent_id"`  // nil for top-level comments
+	Ancestors []primitive.ObjectID `bson:"ancestors"`  // From the top-level comment to the parent
+	Author    string               `bson:"author"`
+	Text      string               `bson:"text"`
+	CreatedAt time.Time            `bson:"created_at"`
+	EditedAt  *time.Time           `bson:"edited_at"`
+	DeletedAt *time.Time           `bson:"deleted_at"`
+	Hidden    bool                 `bson:"hidden"` // By a moderator, the text isn't shown
+	Pinned    bool                 `bson:"pinned"` // Only top-level comments, pinned ones go first
+}
+
+// Depth is 0 for top-level comments
+func (comment *Comment) Depth() int {
+	return len(comment.Ancestors)
+}
+
+// IsVisible tells if the comment is counted in the comment count of the article
+func (comment *Comment) IsVisible() bool {
+	return comment.DeletedAt == nil && !comment.Hidden
+}
+
+type CommentDTO struct {
+	Id         string        `json:"id"`
+	ParentId   string        `json:"parent_id,omitempty"`
+	Author     string        `json:"author,omitempty"` // Empty for deleted comments
+	Text       string        `json:"text"`             // Empty for deleted and hidden comments
+	CreatedAt  time.Time     `json:"created_at"`
+	EditedAt   *time.Time    `json:"edited_at,omitempty"`
+	IsDeleted  bool          `json:"is_deleted"`
+	IsHidden   bool          `json:"is_hidden"`
+	IsPinned   bool          `json:"is_pinned"`
+	ReplyCount int           `json:"reply_count"` // Direct replies, including the ones deeper than the requested depth
+	Replies    []*CommentDTO `json:"replies"`
+}
+
+func (comment *Comment) ToDTO() *CommentDTO {
+	dto := &CommentDTO{
+		Id:        comment.Id.Hex(),
+		Author:    comment.Author,
+		Text:      comment.Text,
+		CreatedAt: comment.CreatedAt,
+		EditedAt:  comment.EditedAt,
+		IsDeleted: comment.DeletedAt != nil,
+		IsHidden:  comment.Hidden,
+		IsPinned:  comment.Pinned,
+		Replies:   []*CommentDTO{},
+	}
+	if comment.ParentId != nil {
+		dto.ParentId = comment.ParentId.Hex()
+	}
+	if comment.DeletedAt != nil {
+		dto.Author = ""
+		dto.Text = ""
+	}
+	if comment.Hidden {
+		dto.Text = ""
+	}
+
+	return dto
+}
+
+type CreateCommentDTO struct {
+	ArticleId string `json:"article_id"`
+	ParentId  string `json:"parent_id"` // Empty for a top-level comment
+	Text      string `json:"text"`
+}
+
+type EditCommentDTO struct {
+	Id   string `json:"id"`
+	Text string `json:"text"`
+}
+
+type CommentIdDTO struct {
+	Id string `json:"id"`
+}
+
+type ModerateCommentDTO struct {
+	Id    string `json:"id"`
+	Value bool   `json:"value"` // Hides or pins with true, reverts with false
+}
+
+type LockCommentsDTO struct {
+	ArticleId string `json:"article_id"`
+	Locked    bool   `json:"locked"`
+}
+
+type GetThreadExpression struct {
+	PageExpression
+	ArticleId string `json:"article_id"`
+	ParentId  string `json:"parent_id"` // Lists the replies of the comment instead of the top-level comments
+	Depth     *uint  `json:"depth"`     // Levels of replies below the listed comments, CommentMaxDepth by default
+	Count     uint   `json:"count"`
+	Offset    uint   `json:"offset"`
+}
+
+type CommentPageDTO struct {
+	Results    []*CommentDTO `json:"results"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	TotalCount int64         `json:"total_count"`
+	Locked     bool          `json:"locked"` // New comments can't be posted
+}
@@ -1,18 +1,24 @@
 package models
 
+import "time"
+
 type ErrorCode int
 type Limit int
 type CtxKey uint
 
 const (
-	BadRequest         ErrorCode = 0 // Bad request (user error)
-	InternalError      ErrorCode = 1 // Server error (╯°□°）╯︵ ┻━┻
-	BadAuth            ErrorCode = 2 // Bad login details (user error)
-	NotAuthorized      ErrorCode = 3 // To perform the action, you must pass an access token (user error)
-	InvalidToken       ErrorCode = 4 // Invalid token (user error)
-	ExpiredToken       ErrorCode = 5 // Expired token (user error)
-	NotUniqueData      ErrorCode = 6 // Data is not unique when needed (user error)
-	InvalidFieldLength ErrorCode = 7 // Invalid field length (user error)
+	BadRequest         ErrorCode = 0  // Bad request (user error)
+	InternalError      ErrorCode = 1  // Server error (╯°□°）╯︵ ┻━┻
+	BadAuth            ErrorCode = 2  // Bad login details (user error)
+	NotAuthorized      ErrorCode = 3  // To perform the action, you must pass an access token (user error)
+	InvalidToken       ErrorCode = 4  // Invalid token (user error)
+	ExpiredToken       ErrorCode = 5  // Expired token (user error)
+	NotUniqueData      ErrorCode = 6  // Data is not unique when needed (user error)
+	InvalidFieldLength ErrorCode = 7  // Invalid field length (user error)
+	Forbidden          ErrorCode = 8  // The role of the user doesn't allow the action (user error)
+	VersionConflict    ErrorCode = 9  // The document was changed since the passed version was read (user error)
+	InvalidTransition  ErrorCode = 10 // The workflow doesn't allow moving the article to the requested state (user error)
+	InvalidDocument    ErrorCode = 11 // The article_data doesn't match the block schema, see fields (user error)
 )
 
 const (
@@ -30,29 +36,115 @@ const (
 	ArticleNameMinLimit Limit = 3
 	ArticleNameMaxLimit Limit = 100
 
-	FindMaxLimit Limit = 10
+	ReviewCommentMaxLimit Limit = 2000
+
+	ArticleDataMaxSize       Limit = 512 * 1024 // Bytes of article_data encoded as JSON
+	ArticleDataMaxBlocks     Limit = 1000       // Also the maximal number of items in a list
+	ArticleBlockTextMaxLimit Limit = 20000
+	ArticleListMaxDepth      Limit = 4
+	ArticleMarkdownMaxSize   Limit = 512 * 1024
+
+	TagMaxLimit          Limit = 32 // In runes after the normalization
+	ArticleTagsMaxCount  Limit = 10
+	CategoryIdMinLimit   Limit = 2
+	CategoryIdMaxLimit   Limit = 32
+	CategoryNameMinLimit Limit = 2
+	CategoryNameMaxLimit Limit = 64
+	CategoryMaxDepth     Limit = 3
+	PopularTagsMaxLimit  Limit = 100
+
+	CommentTextMaxLimit Limit = 4000 // In runes
+	CommentMaxDepth     Limit = 5    // Levels of replies below a top-level comment
+	CommentsMaxLimit    Limit = 50   // Comments of one level in a page of a thread
+
+	ReadingListNameMinLimit Limit = 1 // In runes
+	ReadingListNameMaxLimit Limit = 64
+	ReadingListsMaxCount    Limit = 50  // Per user
+	ReadingListMaxArticles  Limit = 500 // Also the maximal number of bookmarks outside of the lists
+
+	FindMaxLimit      Limit = 10
+	FindQueryMaxLimit Limit = 200
 )
 
 const (
 	JwtClaimsKey CtxKey = 0
 )
 
+// RoleFromClaims returns RoleReader for tokens issued before roles were introduced
+func RoleFromClaims(claims map[string]interface{}) Role {
+	role, _ := claims["role"].(string)
+	if !Role(role).IsValid() {
+		return RoleReader
+	}
+
+	return Role(role)
+}
+
 type ErrorDTO struct {
-	ErrorCode ErrorCode `json:"error_code"`
-	Message   string    `json:"message"`
+	ErrorCode ErrorCode    `json:"error_code"`
+	Message   string       `json:"message"`
+	Fields    []FieldError `json:"fields,omitempty"`
+}
+
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
 }
 
 type FindOneExpression struct {
 	Query string `json:"query"`
 }
 
+// PageExpression switches a list from offsets to cursors. Lists sent without it keep returning
+// a bare array, with it they return a page object with next_cursor.
+type PageExpression struct {
+	Cursor         *string `json:"cursor"`      // "" asks for the first page, then pass next_cursor of the previous page
+	WithTotalCount bool    `json:"total_count"` // Count all matches, it costs an extra query
+}
+
+func (page PageExpression) IsPaged() bool {
+	return page.Cursor != nil || page.WithTotalCount
+}
+
 type FindManyExpression struct {
-	Query  string `json:"query"`
-	Count  uint   `json:"count"`
-	Offset uint   `json:"offset"`
+	PageExpression
+	Query    string `json:"query"`
+	Fuzzy    bool   `json:"fuzzy"`    // Also match words with typos, only the local search backend supports it
+	Author   string `json:"author"`   // Empty string matches any author
+	Tag      string `json:"tag"`      // Empty string matches any tag
+	Category string `json:"category"` // Also matches the subcategories, empty string matches any category
+	Count    uint   `json:"count"`
+	Offset   uint   `json:"offset"`
 }
 
 type GetListExpression struct {
-	Count  uint `json:"count"`
-	Offset uint `json:"offset"`
+	PageExpression
+	Author        string      `json:"author"`         // Empty string matches any author
+	Tag           string      `json:"tag"`            // Empty string matches any tag
+	Category      string      `json:"category"`       // Also matches the subcategories, empty string matches any category
+	PublishedFrom *time.Time  `json:"published_from"` // Inclusive
+	PublishedTo   *time.Time  `json:"published_to"`   // Exclusive
+	HasThumbnail  *bool       `json:"has_thumbnail"`  // null matches articles with and without a thumbnail
+	Sort          ArticleSort `json:"sort"`           // newest (default), oldest, popularity or name
+	Count         uint        `json:"count"`
+	Offset        uint        `json:"offset"`
+}
+
+// ArticleSort is an order of the article lists, ties are broken by the creation order
+type ArticleSort string
+
+const (
+	SortNewest     ArticleSort = "newest" // By published_at, unpublished articles go last
+	SortOldest     ArticleSort = "oldest" // By published_at, unpublished articles go first
+	SortPopularity ArticleSort = "popularity"
+	SortName       ArticleSort = "name"
+)
+
+func (sort ArticleSort) IsValid() bool {
+	switch sort {
+	case SortNewest, SortOldest, SortPopularity, SortName:
+		return true
+	}
+
+	return false
 }
@@ -0,0 +1,17 @@
+package models
+
+// JwkDTO is a public key in the JSON Web Key format (RFC 7517)
+type JwkDTO struct {
+	KeyType   string `json:"kty"`
+	KeyId     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Modulus   string `json:"n,omitempty"`   // RSA
+	Exponent  string `json:"e,omitempty"`   // RSA
+	Curve     string `json:"crv,omitempty"` // Ed25519
+	X         string `json:"x,omitempty"`   // Ed25519
+}
+
+type JwksDTO struct {
+	Keys []JwkDTO `json:"keys"`
+}
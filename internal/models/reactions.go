@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+type ReactionKind string
+
+// ReactionCounts is the number of reactions of an article by kind
+type ReactionCounts map[ReactionKind]int64
+
+const (
+	ReactionLike       ReactionKind = "like"
+	ReactionLove       ReactionKind = "love"
+	ReactionInsightful ReactionKind = "insightful"
+	ReactionFunny      ReactionKind = "funny"
+	ReactionSad        ReactionKind = "sad"
+)
+
+func (kind ReactionKind) IsValid() bool {
+	switch kind {
+	case ReactionLike, ReactionLove, ReactionInsightful, ReactionFunny, ReactionSad:
+		return true
+	}
+
+	return false
+}
+
+// Reaction is one kind of reaction of a user to an article, a user may react with several kinds
+type Reaction struct {
+	ArticleId string       `bson:"article_id"` // Custom id of the article
+	Nickname  string       `bson:"nickname"`
+	Kind      ReactionKind `bson:"kind"`
+	CreatedAt time.Time    `bson:"created_at"`
+}
+
+type ReactExpression struct {
+	CustomId string       `json:"id"`
+	Kind     ReactionKind `json:"kind"`
+}
+
+type ReactionsDTO struct {
+	Id        string         `json:"id"`
+	Reactions ReactionCounts `json:"reactions"`
+	Mine      []ReactionKind `json:"mine"` // The kinds the user has reacted with
+}
+
+type GetReactedArticlesExpression struct {
+	Kind   ReactionKind `json:"kind"` // Empty string matches any kind
+	Count  uint         `json:"count"`
+	Offset uint         `json:"offset"`
+}
+
+type ReactedArticleDTO struct {
+	MetaArticle
+	Kind      ReactionKind `json:"kind"`
+	ReactedAt time.Time    `json:"reacted_at"`
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"github.com/shuryak/shuryak-backend/internal/jsondiff"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// ArticleRevision is an immutable snapshot of an article saved on every change
+type ArticleRevision struct {
+	Id          primitive.ObjectID     `bson:"_id"`
+	ArticleId   string                 `bson:"article_id"`
+	Number      uint                   `bson:"number"`
+	Author      string                 `bson:"author"` // The user who made the change, not necessarily the article author
+	CreatedAt   time.Time              `bson:"created_at"`
+	Name        string                 `bson:"name"`
+	Thumbnail   string                 `bson:"thumbnail"`
+	ArticleData map[string]interface{} `bson:"article_data"`
+}
+
+type MetaRevisionDTO struct {
+	ArticleId string    `json:"id"`
+	Number    uint      `json:"number"`
+	Author    string    `json:"author"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+}
+
+type ArticleRevisionDTO struct {
+	ArticleId   string                 `json:"id"`
+	Number      uint                   `json:"number"`
+	Author      string                 `json:"author"`
+	CreatedAt   time.Time              `json:"created_at"`
+	Name        string                 `json:"name"`
+	Thumbnail   string                 `json:"thumbnail"`
+	ArticleData map[string]interface{} `json:"article_data"`
+}
+
+type GetRevisionsExpression struct {
+	CustomId string `json:"id"`
+	Count    uint   `json:"count"`
+	Offset   uint   `json:"offset"`
+}
+
+type RevisionExpression struct {
+	CustomId string `json:"id"`
+	Number   uint   `json:"number"`
+}
+
+type DiffRevisionsExpression struct {
+	CustomId string `json:"id"`
+	From     uint   `json:"from"`
+	To       uint   `json:"to"`
+}
+
+type RevisionsDiffDTO struct {
+	CustomId string            `json:"id"`
+	From     uint              `json:"from"`
+	To       uint              `json:"to"`
+	Changes  []jsondiff.Change `json:"changes"`
+}
+
+func NewRevision(article Article, author string) ArticleRevision {
+	return ArticleRevision{
+		Id:          primitive.NewObjectID(),
+		ArticleId:   article.CustomId,
+		Author:      author,
+		CreatedAt:   time.Now(),
+		Name:        article.Name,
+		Thumbnail:   article.Thumbnail,
+		ArticleData: article.ArticleData,
+	}
+}
+
+func (revision ArticleRevision) ToMeta() MetaRevisionDTO {
+	return MetaRevisionDTO{
+		ArticleId: revision.ArticleId,
+		Number:    revision.Number,
+		Author:    revision.Author,
+		CreatedAt: revision.CreatedAt,
+		Name:      revision.Name,
+	}
+}
+
+func (revision ArticleRevision) ToDTO() ArticleRevisionDTO {
+	return ArticleRevisionDTO{
+		ArticleId:   revision.ArticleId,
+		Number:      revision.Number,
+		Author:      revision.Author,
+		CreatedAt:   revision.CreatedAt,
+		Name:        revision.Name,
+		Thumbnail:   revision.Thumbnail,
+		ArticleData: revision.ArticleData,
+	}
+}
+
+// Content is the part of the revision compared by articles.diffRevisions
+// Content is also what Article.Content returns
+func (revision ArticleRevision) Content() map[string]interface{} {
+	return map[string]interface{}{
+		"name":         revision.Name,
+		"thumbnail":    revision.Thumbnail,
+		"article_data": revision.ArticleData,
+	}
+}
+
+// Content is the part of the article its revisions keep
+func (article Article) Content() map[string]interface{} {
+	return NewRevision(article, "").Content()
+}
@@ -0,0 +1,32 @@
+package models
+
+type Role string
+
+const (
+	RoleReader Role = "reader" // Can only read and use reader features
+	RoleAuthor Role = "author" // Can write and edit own articles
+	RoleEditor Role = "editor" // Can edit and unpublish any article
+	RoleAdmin  Role = "admin"  // Can do everything, including managing roles
+)
+
+var roleRanks = map[Role]int{
+	RoleReader: 1,
+	RoleAuthor: 2,
+	RoleEditor: 3,
+	RoleAdmin:  4,
+}
+
+func (role Role) IsValid() bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// Includes reports whether the role has every permission of the other role
+func (role Role) Includes(other Role) bool {
+	return role.IsValid() && other.IsValid() && roleRanks[role] >= roleRanks[other]
+}
+
+type UserRoleDTO struct {
+	Nickname string `json:"nickname"`
+	Role     Role   `json:"role"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Session is a login on one device, its id is also the rotation family of its refresh tokens
+type Session struct {
+	Id          string    `bson:"_id"`
+	Nickname    string    `bson:"nickname"`
+	UserAgent   string    `bson:"user_agent"`
+	Ip          string    `bson:"ip"`
+	CreatedAt   time.Time `bson:"created_at"`
+	LastUsedAt  time.Time `bson:"last_used_at"`
+	ExpiresAt   time.Time `bson:"expires_at"`
+	RefreshHash string    `bson:"refresh_hash"` // SHA-256 of the only refresh token that may be used
+}
+
+type SessionDTO struct {
+	Id         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	Ip         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	IsCurrent  bool      `json:"is_current"`
+}
+
+type SessionIdDTO struct {
+	SessionId string `json:"session_id"`
+}
+
+func (session Session) ToDTO(currentSessionId string) SessionDTO {
+	return SessionDTO{
+		Id:         session.Id,
+		UserAgent:  session.UserAgent,
+		Ip:         session.Ip,
+		CreatedAt:  session.CreatedAt,
+		LastUsedAt: session.LastUsedAt,
+		IsCurrent:  session.Id == currentSessionId,
+	}
+}
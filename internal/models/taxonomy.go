@@ -0,0 +1,166 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Category is a node of the category tree managed by editors, the slug is its id
+type Category struct {
+	Slug   string `bson:"_id"`
+	Name   string `bson:"name"`
+	Parent string `bson:"parent"` // Empty for top-level categories
+	Order  int    `bson:"order"`  // Position among the siblings, then they are sorted by name
+}
+
+type CategoryDTO struct {
+	Id     string `json:"id"`
+	Name   string `json:"name"`
+	Parent string `json:"parent"`
+	Order  int    `json:"order"`
+}
+
+type CategoryTreeDTO struct {
+	Id       string            `json:"id"`
+	Name     string            `json:"name"`
+	Children []CategoryTreeDTO `json:"children"`
+}
+
+type CategoryIdExpression struct {
+	Id string `json:"id"`
+}
+
+type TagDTO struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"` // Number of published articles with the tag
+}
+
+type GetPopularTagsExpression struct {
+	Count uint `json:"count"`
+}
+
+type GetByTagExpression struct {
+	PageExpression
+	Tag    string `json:"tag"`
+	Count  uint   `json:"count"`
+	Offset uint   `json:"offset"`
+}
+
+func (category Category) ToDTO() CategoryDTO {
+	return CategoryDTO{
+		Id:     category.Slug,
+		Name:   category.Name,
+		Parent: category.Parent,
+		Order:  category.Order,
+	}
+}
+
+// NormalizeTag turns a free-form tag into a slug: lowercase letters and digits of any script
+// separated by single dashes, so "Go Lang", "go_lang" and "#go-lang" are the same tag.
+// It returns an empty string if nothing is left.
+func NormalizeTag(tag string) string {
+	var out strings.Builder
+	dash := false
+
+	for _, r := range strings.ToLower(tag) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && out.Len() != 0 {
+				out.WriteByte('-')
+			}
+			out.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+
+	return out.String()
+}
+
+// CategoryTree arranges the categories under their parents, siblings are sorted by order and then by name.
+// Categories whose parent is missing are put at the top level so they can't get lost.
+func CategoryTree(categories []Category) []CategoryTreeDTO {
+	exists := map[string]bool{}
+	for _, category := range categories {
+		exists[category.Slug] = true
+	}
+
+	children := map[string][]Category{}
+	for _, category := range categories {
+		parent := category.Parent
+		if !exists[parent] {
+			parent = ""
+		}
+		children[parent] = append(children[parent], category)
+	}
+
+	var build func(parent string) []CategoryTreeDTO
+	build = func(parent string) []CategoryTreeDTO {
+		siblings := children[parent]
+		sort.Slice(siblings, func(i, j int) bool {
+			if siblings[i].Order != siblings[j].Order {
+				return siblings[i].Order < siblings[j].Order
+			}
+			return siblings[i].Name < siblings[j].Name
+		})
+
+		nodes := []CategoryTreeDTO{}
+		for _, category := range siblings {
+			nodes = append(nodes, CategoryTreeDTO{Id: category.Slug, Name: category.Name, Children: build(category.Slug)})
+		}
+
+		return nodes
+	}
+
+	return build("")
+}
+
+// CategoryWithDescendants returns the slug and the slugs of all the categories under it
+func CategoryWithDescendants(categories []Category, slug string) []string {
+	result := []string{slug}
+
+	// The tree is shallow, so a pass per level is cheap
+	for added := true; added; {
+		added = false
+		for _, category := range categories {
+			if !containsString(result, category.Slug) && containsString(result, category.Parent) {
+				result = append(result, category.Slug)
+				added = true
+			}
+		}
+	}
+
+	return result
+}
+
+// CategoryDepth returns the level of the category, 1 for the top level, or 0 if it is missing
+// or there is a cycle
+func CategoryDepth(categories []Category, slug string) int {
+	parents := map[string]string{}
+	for _, category := range categories {
+		parents[category.Slug] = category.Parent
+	}
+
+	depth := 0
+	for slug != "" {
+		parent, exists := parents[slug]
+		if !exists || depth > len(categories) {
+			return 0
+		}
+		depth++
+		slug = parent
+	}
+
+	return depth
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
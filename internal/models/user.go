@@ -8,15 +8,16 @@ type User struct {
 	FirstName    string `bson:"first_name"`
 	LastName     string `bson:"last_name"`
 	Nickname     string `bson:"nickname"`
-	IsAdmin      bool   `bson:"is_admin"`
+	IsAdmin      bool   `bson:"is_admin"` // Kept in sync with Role, true grants RoleAdmin to users without a role
+	Role         Role   `bson:"role"`
 	PasswordHash string `bson:"password_hash"`
-	RefreshToken string `bson:"refresh_token"`
 }
 
 type UserDTO struct {
 	FirstName string `json:"first_name" bson:"first_name"`
 	LastName  string `json:"last_name" bson:"last_name"`
 	Nickname  string `json:"nickname" bson:"nickname"`
+	Role      Role   `json:"role" bson:"role"`
 }
 
 type UserRegisterDTO struct {
@@ -62,6 +63,19 @@ func CheckRegistrationFieldsLength(dto *UserRegisterDTO) bool {
 	return true
 }
 
-func (user User) GenerateJWTBasedOn(accessMinutes uint) (map[string]interface{}, error) {
-	return utils.GenerateJWT(user.FirstName, user.LastName, user.Nickname, accessMinutes)
+// EffectiveRole also covers users registered before roles were introduced
+func (user User) EffectiveRole() Role {
+	if user.IsAdmin {
+		return RoleAdmin
+	}
+
+	if !user.Role.IsValid() {
+		return RoleAuthor
+	}
+
+	return user.Role
+}
+
+func (user User) GenerateJWT(sessionId string) (map[string]interface{}, error) {
+	return utils.GenerateJWT(user.FirstName, user.LastName, user.Nickname, string(user.EffectiveRole()), sessionId)
 }
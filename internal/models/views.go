@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// PopularPeriod is a window of articles.getPopular, views lose half of their weight every HalfLife
+// so that the articles read right now outrank the ones that were popular at the start of the window
+type PopularPeriod struct {
+	Window   time.Duration
+	HalfLife time.Duration
+}
+
+var PopularPeriods = map[string]PopularPeriod{
+	"day":   {Window: 24 * time.Hour, HalfLife: 6 * time.Hour},
+	"week":  {Window: 7 * 24 * time.Hour, HalfLife: 42 * time.Hour},
+	"month": {Window: 30 * 24 * time.Hour, HalfLife: 180 * time.Hour},
+}
+
+// ViewRetention is how long the hourly view counts are kept, it covers the longest period
+const ViewRetention = 31 * 24 * time.Hour
+
+type GetPopularExpression struct {
+	Period string `json:"period"` // day (default), week or month
+	Count  uint   `json:"count"`
+}
+
+type PopularArticleDTO struct {
+	MetaArticle
+	Score float64 `json:"score"` // Time-decayed views within the period
+}
@@ -0,0 +1,131 @@
+package models
+
+import "time"
+
+type ArticleState string
+
+const (
+	StateDraft     ArticleState = "draft"
+	StateInReview  ArticleState = "in_review"
+	StateApproved  ArticleState = "approved" // Also a scheduled article waiting for its publish_at
+	StatePublished ArticleState = "published"
+	StateArchived  ArticleState = "archived"
+)
+
+// stateTransitions maps allowed transitions to the minimal role that may make them,
+// staying in the same state is always allowed. Workflow can relax the roles.
+var stateTransitions = map[ArticleState]map[ArticleState]Role{
+	StateDraft: {
+		StateInReview:  RoleAuthor,
+		StateApproved:  RoleEditor,
+		StatePublished: RoleEditor,
+	},
+	StateInReview: {
+		StateDraft:     RoleAuthor, // Withdrawn by the author or changes requested by the reviewer
+		StateApproved:  RoleEditor,
+		StatePublished: RoleEditor,
+	},
+	StateApproved: {
+		StateDraft:     RoleAuthor,
+		StateInReview:  RoleAuthor, // The content has changed since the approval
+		StatePublished: RoleAuthor,
+	},
+	StatePublished: {
+		StateDraft:    RoleAuthor,
+		StateApproved: RoleAuthor, // Rescheduled, the scheduler publishes it again
+		StateArchived: RoleAuthor,
+	},
+	StateArchived: {
+		StateDraft: RoleAuthor,
+	},
+}
+
+func (state ArticleState) IsValid() bool {
+	_, ok := stateTransitions[state]
+	return ok
+}
+
+// Workflow holds the configurable part of the transition rules
+type Workflow struct {
+	// Lets authors publish and schedule their drafts without a review, as before the workflow
+	// existed. The review is then optional and approvals don't bind the authors.
+	AuthorsCanPublish bool
+}
+
+func (workflow Workflow) CanTransition(from ArticleState, to ArticleState, role Role) bool {
+	if from == to {
+		return true
+	}
+
+	minRole, ok := stateTransitions[from][to]
+	if ok && workflow.AuthorsCanPublish && from == StateDraft {
+		minRole = RoleAuthor
+	}
+
+	return ok && role.Includes(minRole)
+}
+
+// CanApprove tells if the role may approve articles one way or another, content changes made
+// by other roles send approved articles back to review
+func (workflow Workflow) CanApprove(role Role) bool {
+	return workflow.CanTransition(StateInReview, StateApproved, role) || workflow.CanTransition(StateDraft, StateApproved, role)
+}
+
+type ReviewComment struct {
+	Author    string       `json:"author" bson:"author"`
+	Text      string       `json:"text" bson:"text"`
+	State     ArticleState `json:"state" bson:"state"` // The state the article was moved to with this comment
+	CreatedAt time.Time    `json:"created_at" bson:"created_at"`
+}
+
+type ReviewExpression struct {
+	CustomId string `json:"id"`
+	Reviewer string `json:"reviewer"` // Optional on articles.submitForReview
+	Comment  string `json:"comment"`  // Required on articles.requestChanges
+}
+
+type ReviewDTO struct {
+	CustomId string          `json:"id"`
+	State    ArticleState    `json:"state"`
+	Reviewer string          `json:"reviewer,omitempty"`
+	Comments []ReviewComment `json:"comments"`
+}
+
+// EffectiveState also covers articles created before the workflow was introduced
+func (article Article) EffectiveState() ArticleState {
+	if article.State.IsValid() {
+		return article.State
+	}
+
+	if article.IsDraft {
+		return StateDraft
+	}
+
+	return StatePublished
+}
+
+// SetState keeps is_draft in sync for clients and queries that only know about it,
+// and dates the publication when the article becomes published
+func (article *Article) SetState(state ArticleState) {
+	if state == StatePublished && (article.PublishedAt == nil || article.EffectiveState() != StatePublished) {
+		now := time.Now()
+		article.PublishedAt = &now
+	}
+
+	article.State = state
+	article.IsDraft = state != StatePublished
+}
+
+func (article Article) ToReviewDTO() ReviewDTO {
+	comments := article.ReviewComments
+	if comments == nil {
+		comments = []ReviewComment{}
+	}
+
+	return ReviewDTO{
+		CustomId: article.CustomId,
+		State:    article.EffectiveState(),
+		Reviewer: article.Reviewer,
+		Comments: comments,
+	}
+}
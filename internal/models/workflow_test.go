@@ -0,0 +1,94 @@
+package models
+
+import "testing"
+
+func TestWorkflowCanTransition(t *testing.T) {
+	tests := []struct {
+		name              string
+		authorsCanPublish bool
+		from              ArticleState
+		to                ArticleState
+		role              Role
+		want              bool
+	}{
+		{"staying in the state", false, StateApproved, StateApproved, RoleReader, true},
+		{"submitting", false, StateDraft, StateInReview, RoleAuthor, true},
+		{"submitting as a reader", false, StateDraft, StateInReview, RoleReader, false},
+		{"publishing a draft", false, StateDraft, StatePublished, RoleAuthor, false},
+		{"scheduling a draft", false, StateDraft, StateApproved, RoleAuthor, false},
+		{"publishing a draft as an editor", false, StateDraft, StatePublished, RoleEditor, true},
+		{"approving", false, StateInReview, StateApproved, RoleAuthor, false},
+		{"approving as an editor", false, StateInReview, StateApproved, RoleEditor, true},
+		{"approving as an admin", false, StateInReview, StateApproved, RoleAdmin, true},
+		{"publishing an approved article", false, StateApproved, StatePublished, RoleAuthor, true},
+		{"sending an approved article back to review", false, StateApproved, StateInReview, RoleAuthor, true},
+		{"rescheduling a published article", false, StatePublished, StateApproved, RoleAuthor, true},
+		{"archiving a draft", false, StateDraft, StateArchived, RoleAdmin, false},
+		{"unknown state", false, ArticleState("deleted"), StateDraft, RoleAdmin, false},
+
+		{"publishing a draft, authors can publish", true, StateDraft, StatePublished, RoleAuthor, true},
+		{"scheduling a draft, authors can publish", true, StateDraft, StateApproved, RoleAuthor, true},
+		{"publishing a draft as a reader, authors can publish", true, StateDraft, StatePublished, RoleReader, false},
+		{"approving, authors can publish", true, StateInReview, StateApproved, RoleAuthor, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			workflow := Workflow{AuthorsCanPublish: test.authorsCanPublish}
+
+			if got := workflow.CanTransition(test.from, test.to, test.role); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWorkflowCanApprove(t *testing.T) {
+	tests := []struct {
+		name              string
+		authorsCanPublish bool
+		role              Role
+		want              bool
+	}{
+		{"author", false, RoleAuthor, false},
+		{"editor", false, RoleEditor, true},
+		{"author, authors can publish", true, RoleAuthor, true},
+		{"reader, authors can publish", true, RoleReader, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			workflow := Workflow{AuthorsCanPublish: test.authorsCanPublish}
+
+			if got := workflow.CanApprove(test.role); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestArticleSetState(t *testing.T) {
+	tests := []struct {
+		state         ArticleState
+		wantDraft     bool
+		wantPublished bool
+	}{
+		{StateDraft, true, false},
+		{StateInReview, true, false},
+		{StateApproved, true, false},
+		{StatePublished, false, true},
+		{StateArchived, true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.state), func(t *testing.T) {
+			article := Article{IsDraft: true}
+
+			article.SetState(test.state)
+
+			if article.IsDraft != test.wantDraft || (article.PublishedAt != nil) != test.wantPublished {
+				t.Errorf("got is_draft %v and published_at %v", article.IsDraft, article.PublishedAt)
+			}
+		})
+	}
+}
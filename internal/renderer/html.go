@@ -0,0 +1,152 @@
+package renderer
+
+import (
+	"fmt"
+	"github.com/shuryak/shuryak-backend/internal/blocks"
+	"html"
+	"net/url"
+	"strings"
+)
+
+// embedHosts are the hosts whose embeds are rendered as frames, nothing is framed until they're configured
+var embedHosts []string
+
+// ConfigureEmbeds sets the hosts whose embeds are rendered as frames, embeds from other hosts
+// are rendered as links
+func ConfigureEmbeds(hosts []string) {
+	embedHosts = make([]string, len(hosts))
+	for i, host := range hosts {
+		embedHosts[i] = strings.ToLower(host)
+	}
+}
+
+// EmbedHosts returns the hosts whose embeds are rendered as frames, for the frame-src of a page policy
+func EmbedHosts() []string {
+	return embedHosts
+}
+
+func isEmbedHost(src string) bool {
+	parsed, err := url.Parse(src)
+	if err != nil {
+		return false
+	}
+
+	// The host keeps the port, so the frame can't be loaded from a port the policy doesn't allow
+	host := strings.ToLower(parsed.Host)
+	for _, allowed := range embedHosts {
+		if host == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+func HTML(document blocks.Document) string {
+	var out strings.Builder
+
+	for _, block := range document.Blocks {
+		switch block.Type {
+		case blocks.TypeParagraph:
+			fmt.Fprintf(&out, "<p>%s</p>\n", inlineHTML(block.Text))
+		case blocks.TypeHeading:
+			level := clampLevel(block.Level)
+			fmt.Fprintf(&out, "<h%d>%s</h%d>\n", level, inlineHTML(block.Text), level)
+		case blocks.TypeImage:
+			src := safeUrl(block.Url, "http", "https")
+			if src == "" {
+				continue
+			}
+			fmt.Fprintf(&out, "<figure><img src=\"%s\" alt=\"%s\">%s</figure>\n", html.EscapeString(src), html.EscapeString(block.Alt), captionHTML(block.Caption))
+		case blocks.TypeCode:
+			class := ""
+			if block.Language != "" {
+				class = fmt.Sprintf(" class=\"language-%s\"", html.EscapeString(block.Language))
+			}
+			fmt.Fprintf(&out, "<pre><code%s>%s</code></pre>\n", class, html.EscapeString(block.Code))
+		case blocks.TypeQuote:
+			footer := ""
+			if block.Caption != "" {
+				footer = fmt.Sprintf("<footer>%s</footer>", inlineHTML(block.Caption))
+			}
+			fmt.Fprintf(&out, "<blockquote><p>%s</p>%s</blockquote>\n", inlineHTML(block.Text), footer)
+		case blocks.TypeList:
+			listHTML(&out, block.Style == "ordered", block.Items)
+			out.WriteString("\n")
+		case blocks.TypeEmbed:
+			src := safeUrl(block.Url, "https")
+			if src == "" {
+				continue
+			}
+			if !isEmbedHost(src) {
+				fmt.Fprintf(&out, "<figure class=\"embed\"><a href=\"%s\" rel=\"nofollow noopener noreferrer\">%s</a>%s</figure>\n",
+					html.EscapeString(src), html.EscapeString(src), captionHTML(block.Caption))
+				continue
+			}
+			// The sandbox keeps the embedded page away from ours
+			fmt.Fprintf(&out, "<figure class=\"embed\"><iframe src=\"%s\" sandbox=\"allow-scripts allow-same-origin allow-presentation allow-popups\" loading=\"lazy\" allowfullscreen></iframe>%s</figure>\n",
+				html.EscapeString(src), captionHTML(block.Caption))
+		}
+	}
+
+	return out.String()
+}
+
+func listHTML(out *strings.Builder, ordered bool, items []blocks.ListItem) {
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+
+	fmt.Fprintf(out, "<%s>", tag)
+	for _, item := range items {
+		fmt.Fprintf(out, "<li>%s", inlineHTML(item.Text))
+		if len(item.Items) != 0 {
+			listHTML(out, ordered, item.Items)
+		}
+		out.WriteString("</li>")
+	}
+	fmt.Fprintf(out, "</%s>", tag)
+}
+
+func captionHTML(caption string) string {
+	if caption == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("<figcaption>%s</figcaption>", inlineHTML(caption))
+}
+
+func inlineHTML(s string) string {
+	var out strings.Builder
+
+	for _, t := range tokenize(s) {
+		switch t.kind {
+		case textToken:
+			out.WriteString(html.EscapeString(t.text))
+		case breakToken:
+			out.WriteString("<br>")
+		case openToken:
+			if t.tag == "a" {
+				fmt.Fprintf(&out, "<a href=\"%s\" rel=\"nofollow noopener noreferrer\">", html.EscapeString(t.href))
+			} else {
+				fmt.Fprintf(&out, "<%s>", t.tag)
+			}
+		case closeToken:
+			fmt.Fprintf(&out, "</%s>", t.tag)
+		}
+	}
+
+	return out.String()
+}
+
+func clampLevel(level int) int {
+	if level < 1 {
+		return 1
+	}
+	if level > 6 {
+		return 6
+	}
+
+	return level
+}
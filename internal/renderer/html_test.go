@@ -0,0 +1,71 @@
+package renderer
+
+import (
+	"github.com/shuryak/shuryak-backend/internal/blocks"
+	"testing"
+)
+
+func TestInlineHTML(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"text is escaped", "plain & <script>alert(1)</script>", "plain &amp; &lt;script&gt;alert(1)&lt;/script&gt;"},
+		{"allowed tags", "<b>b</b> <i>i</i> <u>u</u> <s>s</s> <code>c</code> <B>B</B>", "<strong>b</strong> <em>i</em> <u>u</u> <s>s</s> <code>c</code> <strong>B</strong>"},
+		{"unknown tags", "<img src=x onerror=alert(1)>", "&lt;img src=x onerror=alert(1)&gt;"},
+		{"breaks", "a<br>b<br/>c", "a<br>b<br>c"},
+		{"link", `<a href="https://a.io">x</a>`, `<a href="https://a.io" rel="nofollow noopener noreferrer">x</a>`},
+		{"link attributes besides href", `<a href="https://a.io" onclick="x()">x</a>`, `<a href="https://a.io" rel="nofollow noopener noreferrer">x</a>`},
+		{"href is escaped", `<a href="https://a.io/?q=&quot;x">q</a>`, `<a href="https://a.io/?q=&amp;quot;x" rel="nofollow noopener noreferrer">q</a>`},
+		{"javascript link", `<a href="javascript:alert(1)">x</a>`, "x"},
+		{"data link", `<a href='data:text/html,x'>x</a>`, "x"},
+		{"relative link", `<a href="/admin">x</a>`, "x"},
+		{"nested links", `<a href="https://a.io"><a href="https://b.io">x</a></a>`, `<a href="https://a.io" rel="nofollow noopener noreferrer">x</a>`},
+		{"unclosed tags", "<b>a <i>b", "<strong>a <em>b</em></strong>"},
+		{"stray closing tag", "</b>a", "a"},
+		{"overlapping tags", "<b>a <i>b</b> c</i>", "<strong>a <em>b</em></strong> c"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := inlineHTML(test.source); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		block blocks.Block
+		want  string
+	}{
+		{"paragraph", blocks.Block{Type: blocks.TypeParagraph, Text: "<b>a</b>"}, "<p><strong>a</strong></p>\n"},
+		{"heading level is clamped", blocks.Block{Type: blocks.TypeHeading, Level: 9, Text: "a"}, "<h6>a</h6>\n"},
+		{"image", blocks.Block{Type: blocks.TypeImage, Url: "https://a.io/i.png", Alt: `"a"`}, "<figure><img src=\"https://a.io/i.png\" alt=\"&#34;a&#34;\"></figure>\n"},
+		{"javascript image", blocks.Block{Type: blocks.TypeImage, Url: "javascript:alert(1)"}, ""},
+		{"code is escaped", blocks.Block{Type: blocks.TypeCode, Language: "go", Code: "<b>"}, "<pre><code class=\"language-go\">&lt;b&gt;</code></pre>\n"},
+		{"quote", blocks.Block{Type: blocks.TypeQuote, Text: "a", Caption: "b"}, "<blockquote><p>a</p><footer>b</footer></blockquote>\n"},
+		{"list", blocks.Block{Type: blocks.TypeList, Style: "ordered", Items: []blocks.ListItem{{Text: "a", Items: []blocks.ListItem{{Text: "b"}}}}}, "<ol><li>a<ol><li>b</li></ol></li></ol>\n"},
+		{"http embed", blocks.Block{Type: blocks.TypeEmbed, Url: "http://www.youtube.com/embed/v"}, ""},
+		{"embed", blocks.Block{Type: blocks.TypeEmbed, Url: "https://WWW.YouTube.com/embed/v"},
+			"<figure class=\"embed\"><iframe src=\"https://WWW.YouTube.com/embed/v\" sandbox=\"allow-scripts allow-same-origin allow-presentation allow-popups\" loading=\"lazy\" allowfullscreen></iframe></figure>\n"},
+		{"embed from another host", blocks.Block{Type: blocks.TypeEmbed, Url: "https://a.io/v", Caption: "a"},
+			"<figure class=\"embed\"><a href=\"https://a.io/v\" rel=\"nofollow noopener noreferrer\">https://a.io/v</a><figcaption>a</figcaption></figure>\n"},
+		{"embed from another port", blocks.Block{Type: blocks.TypeEmbed, Url: "https://www.youtube.com:8443/v"},
+			"<figure class=\"embed\"><a href=\"https://www.youtube.com:8443/v\" rel=\"nofollow noopener noreferrer\">https://www.youtube.com:8443/v</a></figure>\n"},
+	}
+
+	ConfigureEmbeds([]string{"www.YouTube.com"})
+	defer ConfigureEmbeds(nil)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := HTML(blocks.Document{Blocks: []blocks.Block{test.block}}); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
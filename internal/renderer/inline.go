@@ -0,0 +1,153 @@
+package renderer
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Text fields of the blocks are plain text with a few inline tags. Everything that isn't
+// on this allow-list is kept as literal text, so it ends up escaped in HTML.
+var inlineTags = map[string]string{
+	"b":      "strong",
+	"strong": "strong",
+	"i":      "em",
+	"em":     "em",
+	"u":      "u",
+	"s":      "s",
+	"code":   "code",
+	"a":      "a",
+	"br":     "br",
+}
+
+var (
+	tagPattern  = regexp.MustCompile(`^<(/?)([a-zA-Z]+)((?:\s+[a-zA-Z-]+\s*=\s*(?:"[^"<>]*"|'[^'<>]*'))*)\s*/?>`)
+	hrefPattern = regexp.MustCompile(`(?i)\bhref\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+func IsInlineTag(name string) bool {
+	_, ok := inlineTags[strings.ToLower(name)]
+	return ok
+}
+
+type tokenKind int
+
+const (
+	textToken tokenKind = iota
+	openToken
+	closeToken
+	breakToken
+)
+
+type token struct {
+	kind tokenKind
+	tag  string // Normalized, b becomes strong and i becomes em
+	text string
+	href string // Only for the opening a tag
+}
+
+// tokenize parses the inline tags, drops the ones that can't be balanced and closes the unclosed ones
+func tokenize(s string) []token {
+	var tokens []token
+	var stack []string
+	var text strings.Builder
+
+	flush := func() {
+		if text.Len() != 0 {
+			tokens = append(tokens, token{kind: textToken, text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); {
+		var match []string
+		if s[i] == '<' {
+			match = tagPattern.FindStringSubmatch(s[i:])
+		}
+		if match == nil {
+			text.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		tag, known := inlineTags[strings.ToLower(match[2])]
+		if !known {
+			text.WriteByte(s[i])
+			i++
+			continue
+		}
+		i += len(match[0])
+
+		closing := match[1] == "/"
+
+		switch {
+		case tag == "br":
+			flush()
+			tokens = append(tokens, token{kind: breakToken})
+		case closing:
+			depth := indexOf(stack, tag)
+			if depth < 0 {
+				continue
+			}
+			flush()
+			for len(stack) > depth {
+				tokens = append(tokens, token{kind: closeToken, tag: stack[len(stack)-1]})
+				stack = stack[:len(stack)-1]
+			}
+		default:
+			href := ""
+			if tag == "a" {
+				href = safeUrl(attribute(match[3]), "http", "https", "mailto")
+				if href == "" || indexOf(stack, "a") >= 0 {
+					continue
+				}
+			}
+			flush()
+			stack = append(stack, tag)
+			tokens = append(tokens, token{kind: openToken, tag: tag, href: href})
+		}
+	}
+
+	flush()
+	for len(stack) != 0 {
+		tokens = append(tokens, token{kind: closeToken, tag: stack[len(stack)-1]})
+		stack = stack[:len(stack)-1]
+	}
+
+	return tokens
+}
+
+func attribute(attributes string) string {
+	match := hrefPattern.FindStringSubmatch(attributes)
+	if match == nil {
+		return ""
+	}
+
+	return match[1] + match[2]
+}
+
+// safeUrl returns the URL if its scheme is allowed, so javascript: and data: URLs never get through
+func safeUrl(raw string, schemes ...string) string {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || parsed.Host == "" && parsed.Scheme != "mailto" {
+		return ""
+	}
+
+	for _, scheme := range schemes {
+		if strings.ToLower(parsed.Scheme) == scheme {
+			return parsed.String()
+		}
+	}
+
+	return ""
+}
+
+func indexOf(stack []string, tag string) int {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] == tag {
+			return i
+		}
+	}
+
+	return -1
+}
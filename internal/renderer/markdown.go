@@ -0,0 +1,140 @@
+package renderer
+
+import (
+	"fmt"
+	"github.com/shuryak/shuryak-backend/internal/blocks"
+	"strings"
+)
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`, "~", `\~`,
+)
+
+func Markdown(document blocks.Document) string {
+	var parts []string
+
+	for _, block := range document.Blocks {
+		switch block.Type {
+		case blocks.TypeParagraph:
+			parts = append(parts, inlineMarkdown(block.Text))
+		case blocks.TypeHeading:
+			parts = append(parts, strings.Repeat("#", clampLevel(block.Level))+" "+inlineMarkdown(block.Text))
+		case blocks.TypeImage:
+			src := safeUrl(block.Url, "http", "https")
+			if src == "" {
+				continue
+			}
+			image := fmt.Sprintf("![%s](<%s>)", markdownEscaper.Replace(block.Alt), markdownUrl(src))
+			if block.Caption != "" {
+				image += "\n" + inlineMarkdown(block.Caption)
+			}
+			parts = append(parts, image)
+		case blocks.TypeCode:
+			fence := codeFence(block.Code)
+			parts = append(parts, fence+block.Language+"\n"+block.Code+"\n"+fence)
+		case blocks.TypeQuote:
+			quote := inlineMarkdown(block.Text)
+			if block.Caption != "" {
+				quote += "\n\n— " + inlineMarkdown(block.Caption)
+			}
+			parts = append(parts, "> "+strings.ReplaceAll(quote, "\n", "\n> "))
+		case blocks.TypeList:
+			var list strings.Builder
+			listMarkdown(&list, block.Style == "ordered", block.Items, 0)
+			parts = append(parts, strings.TrimSuffix(list.String(), "\n"))
+		case blocks.TypeEmbed:
+			src := safeUrl(block.Url, "https")
+			if src == "" {
+				continue
+			}
+			title := markdownEscaper.Replace(src)
+			if block.Caption != "" {
+				title = inlineMarkdown(block.Caption)
+			}
+			parts = append(parts, fmt.Sprintf("[%s](<%s>)", title, markdownUrl(src)))
+		}
+	}
+
+	return strings.Join(parts, "\n\n")
+}
+
+func listMarkdown(out *strings.Builder, ordered bool, items []blocks.ListItem, depth int) {
+	indent := strings.Repeat("    ", depth)
+
+	for i, item := range items {
+		marker := "-"
+		if ordered {
+			marker = fmt.Sprint(i+1, ".")
+		}
+
+		text := strings.ReplaceAll(inlineMarkdown(item.Text), "\n", "\n"+indent+"    ")
+		fmt.Fprintf(out, "%s%s %s\n", indent, marker, text)
+		listMarkdown(out, ordered, item.Items, depth+1)
+	}
+}
+
+// markdownUrl makes the URL safe inside <>, which allows spaces and parentheses in it
+var markdownUrl = strings.NewReplacer("<", "%3C", ">", "%3E").Replace
+
+// codeFence is longer than any run of backticks in the code
+func codeFence(code string) string {
+	longest, run := 0, 0
+	for _, c := range code {
+		if c == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+
+	if longest < 3 {
+		return "```"
+	}
+
+	return strings.Repeat("`", longest+1)
+}
+
+var markdownMarks = map[string]string{"strong": "**", "em": "*", "s": "~~", "code": "`"}
+
+func inlineMarkdown(s string) string {
+	var out strings.Builder
+	var hrefs []string
+	inCode := false
+
+	for _, t := range tokenize(s) {
+		switch t.kind {
+		case textToken:
+			if inCode {
+				out.WriteString(strings.ReplaceAll(t.text, "`", "'"))
+			} else {
+				out.WriteString(markdownEscaper.Replace(t.text))
+			}
+		case breakToken:
+			out.WriteString("\\\n")
+		case openToken:
+			if t.tag == "a" {
+				hrefs = append(hrefs, t.href)
+				out.WriteString("[")
+			} else {
+				out.WriteString(markdownMarks[t.tag])
+			}
+			inCode = inCode || t.tag == "code"
+		case closeToken:
+			if t.tag == "a" {
+				fmt.Fprintf(&out, "](<%s>)", markdownUrl(hrefs[len(hrefs)-1]))
+				hrefs = hrefs[:len(hrefs)-1]
+			} else {
+				out.WriteString(markdownMarks[t.tag])
+			}
+			if t.tag == "code" {
+				inCode = false
+			}
+		}
+	}
+
+	return out.String()
+}
@@ -0,0 +1,41 @@
+// Package renderer converts article_data into HTML, Markdown and plain text.
+// The HTML is safe to embed into a page as is: text is escaped, inline tags come from
+// an allow-list and URLs are limited to http, https and mailto.
+package renderer
+
+import (
+	"errors"
+	"github.com/shuryak/shuryak-backend/internal/blocks"
+)
+
+const (
+	FormatHTML     = "html"
+	FormatMarkdown = "markdown"
+	FormatText     = "text"
+)
+
+var ErrUnknownFormat = errors.New("unknown format")
+
+func IsFormat(format string) bool {
+	return format == FormatHTML || format == FormatMarkdown || format == FormatText
+}
+
+func Render(format string, data map[string]interface{}) (string, error) {
+	if !IsFormat(format) {
+		return "", ErrUnknownFormat
+	}
+
+	document, err := blocks.Parse(data)
+	if err != nil {
+		return "", err
+	}
+
+	switch format {
+	case FormatHTML:
+		return HTML(document), nil
+	case FormatMarkdown:
+		return Markdown(document), nil
+	default:
+		return PlainText(document), nil
+	}
+}
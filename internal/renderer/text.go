@@ -0,0 +1,68 @@
+package renderer
+
+import (
+	"fmt"
+	"github.com/shuryak/shuryak-backend/internal/blocks"
+	"strings"
+)
+
+func PlainText(document blocks.Document) string {
+	var parts []string
+
+	for _, block := range document.Blocks {
+		switch block.Type {
+		case blocks.TypeParagraph, blocks.TypeHeading:
+			parts = append(parts, InlineText(block.Text))
+		case blocks.TypeImage, blocks.TypeEmbed:
+			if block.Caption != "" {
+				parts = append(parts, InlineText(block.Caption))
+			} else if block.Alt != "" {
+				parts = append(parts, block.Alt)
+			}
+		case blocks.TypeCode:
+			parts = append(parts, block.Code)
+		case blocks.TypeQuote:
+			quote := InlineText(block.Text)
+			if block.Caption != "" {
+				quote += "\n— " + InlineText(block.Caption)
+			}
+			parts = append(parts, quote)
+		case blocks.TypeList:
+			var list strings.Builder
+			listText(&list, block.Style == "ordered", block.Items, 0)
+			parts = append(parts, strings.TrimSuffix(list.String(), "\n"))
+		}
+	}
+
+	return strings.Join(parts, "\n\n")
+}
+
+func listText(out *strings.Builder, ordered bool, items []blocks.ListItem, depth int) {
+	indent := strings.Repeat("  ", depth)
+
+	for i, item := range items {
+		marker := "-"
+		if ordered {
+			marker = fmt.Sprint(i+1, ".")
+		}
+
+		fmt.Fprintf(out, "%s%s %s\n", indent, marker, InlineText(item.Text))
+		listText(out, ordered, item.Items, depth+1)
+	}
+}
+
+// InlineText drops the inline tags of a text field
+func InlineText(s string) string {
+	var out strings.Builder
+
+	for _, t := range tokenize(s) {
+		switch t.kind {
+		case textToken:
+			out.WriteString(t.text)
+		case breakToken:
+			out.WriteString("\n")
+		}
+	}
+
+	return out.String()
+}
@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"log"
+	"time"
+)
+
+// BookmarkedArticleRepository removes the articles saved through it from the bookmarks and the reading
+// lists once they are deleted or unpublished. Like in IndexedArticleRepository the errors are only
+// logged, the bookmarks of unavailable articles are skipped when they are listed anyway.
+type BookmarkedArticleRepository struct {
+	ArticleRepository
+	bookmarks BookmarkRepository
+}
+
+func NewBookmarkedArticleRepository(inner ArticleRepository, bookmarks BookmarkRepository) *BookmarkedArticleRepository {
+	return &BookmarkedArticleRepository{ArticleRepository: inner, bookmarks: bookmarks}
+}
+
+func (repo *BookmarkedArticleRepository) Update(ctx context.Context, article *models.Article) error {
+	if err := repo.ArticleRepository.Update(ctx, article); err != nil {
+		return err
+	}
+
+	if !IsPublished(article, time.Now()) {
+		repo.remove(ctx, article.CustomId)
+	}
+	return nil
+}
+
+func (repo *BookmarkedArticleRepository) SoftDelete(ctx context.Context, customId string, deletedAt time.Time) error {
+	if err := repo.ArticleRepository.SoftDelete(ctx, customId, deletedAt); err != nil {
+		return err
+	}
+
+	repo.remove(ctx, customId)
+	return nil
+}
+
+func (repo *BookmarkedArticleRepository) PurgeDeleted(ctx context.Context, before time.Time) ([]string, error) {
+	customIds, err := repo.ArticleRepository.PurgeDeleted(ctx, before)
+	for _, customId := range customIds {
+		repo.remove(ctx, customId)
+	}
+
+	return customIds, err
+}
+
+func (repo *BookmarkedArticleRepository) Delete(ctx context.Context, customId string) error {
+	if err := repo.ArticleRepository.Delete(ctx, customId); err != nil {
+		return err
+	}
+
+	repo.remove(ctx, customId)
+	return nil
+}
+
+func (repo *BookmarkedArticleRepository) remove(ctx context.Context, customId string) {
+	if err := repo.bookmarks.DeleteByArticle(ctx, customId); err != nil {
+		log.Println("Bookmarks removal of", customId, "failed:", err)
+	}
+}
@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type countingRevokedTokenRepository struct {
+	*MemoryRevokedTokenRepository
+	lookups int
+}
+
+func (repo *countingRevokedTokenRepository) IsRevoked(ctx context.Context, tokenId string) (bool, error) {
+	repo.lookups++
+	return repo.MemoryRevokedTokenRepository.IsRevoked(ctx, tokenId)
+}
+
+func TestCachedRevokedTokenRepository(t *testing.T) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		ttl         time.Duration
+		revoke      func(cached RevokedTokenRepository, inner RevokedTokenRepository)
+		wantRevoked bool
+		wantLookups int // Of the inner repository after the revocation
+	}{
+		{"valid token", time.Minute, func(cached RevokedTokenRepository, inner RevokedTokenRepository) {}, false, 1},
+		{"revoked through the cache", time.Minute, func(cached RevokedTokenRepository, inner RevokedTokenRepository) {
+			cached.Add(ctx, "token", expiresAt)
+		}, true, 0},
+		// Another replica revoked it, the cached answer holds until the ttl
+		{"revoked elsewhere", time.Minute, func(cached RevokedTokenRepository, inner RevokedTokenRepository) {
+			cached.IsRevoked(ctx, "token")
+			inner.Add(ctx, "token", expiresAt)
+		}, false, 0},
+		{"revoked elsewhere, cache expired", 0, func(cached RevokedTokenRepository, inner RevokedTokenRepository) {
+			cached.IsRevoked(ctx, "token")
+			inner.Add(ctx, "token", expiresAt)
+		}, true, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inner := &countingRevokedTokenRepository{MemoryRevokedTokenRepository: NewMemoryRevokedTokenRepository()}
+			cached := NewCachedRevokedTokenRepository(inner, test.ttl)
+			test.revoke(cached, inner)
+			inner.lookups = 0
+
+			revoked, err := cached.IsRevoked(ctx, "token")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The second answer comes from the cache
+			cached.IsRevoked(ctx, "token")
+
+			if revoked != test.wantRevoked || inner.lookups != test.wantLookups {
+				t.Errorf("got revoked %v after %d lookups, want %v after %d", revoked, inner.lookups, test.wantRevoked, test.wantLookups)
+			}
+		})
+	}
+}
+
+func TestCachedRevokedTokenRepositoryUsers(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	expiresAt := now.Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		ttl        time.Duration
+		revoke     func(cached RevokedTokenRepository, inner RevokedTokenRepository)
+		wantBefore time.Time
+	}{
+		{"not revoked", time.Minute, func(cached RevokedTokenRepository, inner RevokedTokenRepository) {}, time.Time{}},
+		{"revoked through the cache", time.Minute, func(cached RevokedTokenRepository, inner RevokedTokenRepository) {
+			cached.RevokeUser(ctx, "user", now, expiresAt)
+		}, now},
+		{"revoked elsewhere", time.Minute, func(cached RevokedTokenRepository, inner RevokedTokenRepository) {
+			cached.RevokedBefore(ctx, "user")
+			inner.RevokeUser(ctx, "user", now, expiresAt)
+		}, time.Time{}},
+		{"revoked elsewhere, cache expired", 0, func(cached RevokedTokenRepository, inner RevokedTokenRepository) {
+			cached.RevokedBefore(ctx, "user")
+			inner.RevokeUser(ctx, "user", now, expiresAt)
+		}, now},
+		{"earlier revocation", time.Minute, func(cached RevokedTokenRepository, inner RevokedTokenRepository) {
+			cached.RevokeUser(ctx, "user", now, expiresAt)
+			cached.RevokeUser(ctx, "user", now.Add(-time.Minute), expiresAt)
+		}, now},
+		{"revocation expired", 0, func(cached RevokedTokenRepository, inner RevokedTokenRepository) {
+			cached.RevokeUser(ctx, "user", now, now)
+		}, time.Time{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inner := NewMemoryRevokedTokenRepository()
+			cached := NewCachedRevokedTokenRepository(inner, test.ttl)
+			test.revoke(cached, inner)
+
+			before, err := cached.RevokedBefore(ctx, "user")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !before.Equal(test.wantBefore) {
+				t.Errorf("got %v, want %v", before, test.wantBefore)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/search"
+	"log"
+	"time"
+)
+
+// IndexedArticleRepository keeps a search index in sync with the articles saved through it,
+// only published articles are indexed. Index errors are logged rather than returned because
+// the article is already saved by then, a broken index can be rebuilt from the articles.
+type IndexedArticleRepository struct {
+	ArticleRepository
+	index search.SearchIndex
+}
+
+func NewIndexedArticleRepository(inner ArticleRepository, index search.SearchIndex) *IndexedArticleRepository {
+	return &IndexedArticleRepository{ArticleRepository: inner, index: index}
+}
+
+func (repo *IndexedArticleRepository) Create(ctx context.Context, article *models.Article) error {
+	if err := repo.ArticleRepository.Create(ctx, article); err != nil {
+		return err
+	}
+
+	repo.reindex(ctx, article)
+	return nil
+}
+
+func (repo *IndexedArticleRepository) Update(ctx context.Context, article *models.Article) error {
+	if err := repo.ArticleRepository.Update(ctx, article); err != nil {
+		return err
+	}
+
+	repo.reindex(ctx, article)
+	return nil
+}
+
+func (repo *IndexedArticleRepository) SoftDelete(ctx context.Context, customId string, deletedAt time.Time) error {
+	if err := repo.ArticleRepository.SoftDelete(ctx, customId, deletedAt); err != nil {
+		return err
+	}
+
+	repo.remove(ctx, customId)
+	return nil
+}
+
+func (repo *IndexedArticleRepository) Restore(ctx context.Context, customId string) error {
+	if err := repo.ArticleRepository.Restore(ctx, customId); err != nil {
+		return err
+	}
+
+	repo.reindexById(ctx, customId)
+	return nil
+}
+
+func (repo *IndexedArticleRepository) PublishDue(ctx context.Context, now time.Time) ([]string, error) {
+	customIds, err := repo.ArticleRepository.PublishDue(ctx, now)
+	for _, customId := range customIds {
+		repo.reindexById(ctx, customId)
+	}
+
+	return customIds, err
+}
+
+func (repo *IndexedArticleRepository) PurgeDeleted(ctx context.Context, before time.Time) ([]string, error) {
+	customIds, err := repo.ArticleRepository.PurgeDeleted(ctx, before)
+	for _, customId := range customIds {
+		repo.remove(ctx, customId)
+	}
+
+	return customIds, err
+}
+
+func (repo *IndexedArticleRepository) Delete(ctx context.Context, customId string) error {
+	if err := repo.ArticleRepository.Delete(ctx, customId); err != nil {
+		return err
+	}
+
+	repo.remove(ctx, customId)
+	return nil
+}
+
+func (repo *IndexedArticleRepository) reindexById(ctx context.Context, customId string) {
+	article, err := repo.ArticleRepository.FindByCustomId(ctx, customId)
+	if err != nil {
+		log.Println("Search index update of", customId, "failed:", err)
+		return
+	}
+
+	repo.reindex(ctx, article)
+}
+
+func (repo *IndexedArticleRepository) reindex(ctx context.Context, article *models.Article) {
+	if !IsPublished(article, time.Now()) {
+		repo.remove(ctx, article.CustomId)
+		return
+	}
+
+	if err := repo.index.Index(ctx, SearchDocument(article)); err != nil {
+		log.Println("Search index update of", article.CustomId, "failed:", err)
+	}
+}
+
+func (repo *IndexedArticleRepository) remove(ctx context.Context, customId string) {
+	if err := repo.index.Delete(ctx, customId); err != nil {
+		log.Println("Search index removal of", customId, "failed:", err)
+	}
+}
+
+// SearchDocument is what the search indexes know about the article
+func SearchDocument(article *models.Article) search.Document {
+	return search.Document{
+		Id:       article.CustomId,
+		Name:     article.Name,
+		Text:     article.SearchText,
+		Author:   article.Author,
+		Tags:     article.Tags,
+		Category: article.Category,
+	}
+}
+
+// PublishedDocuments returns the search documents of all published articles to rebuild an index with
+func PublishedDocuments(ctx context.Context, articles ArticleRepository) ([]search.Document, error) {
+	now := time.Now()
+	found, err := articles.List(ctx, ArticleFilter{IsDraft: Bool(false), PublishedBy: &now}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var documents []search.Document
+	for i := range found {
+		documents = append(documents, SearchDocument(&found[i]))
+	}
+
+	return documents, nil
+}
+
+// IsPublished tells if readers can see the article, the same as the filter of PublishedDocuments
+func IsPublished(article *models.Article, now time.Time) bool {
+	return article.DeletedAt == nil && !article.IsDraft && (article.PublishAt == nil || !article.PublishAt.After(now))
+}
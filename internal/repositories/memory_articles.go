@@ -0,0 +1,453 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/search"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type MemoryArticleRepository struct {
+	mutex    sync.RWMutex
+	articles []models.Article // Kept in insertion order, like a collection without a sort
+}
+
+func NewMemoryArticleRepository() *MemoryArticleRepository {
+	return &MemoryArticleRepository{}
+}
+
+func (repo *MemoryArticleRepository) Create(ctx context.Context, article *models.Article) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if repo.indexOf(article.CustomId) != -1 {
+		return ErrDuplicate
+	}
+
+	article.SearchText = searchText(article)
+	repo.articles = append(repo.articles, *article)
+	return nil
+}
+
+func (repo *MemoryArticleRepository) FindByCustomId(ctx context.Context, customId string) (*models.Article, error) {
+	return repo.findOne(func(article *models.Article) bool {
+		return article.DeletedAt == nil && article.CustomId == customId
+	})
+}
+
+func (repo *MemoryArticleRepository) FindByName(ctx context.Context, name string) (*models.Article, error) {
+	return repo.findOne(func(article *models.Article) bool {
+		return article.DeletedAt == nil && article.Name == name
+	})
+}
+
+func (repo *MemoryArticleRepository) FindDeletedByCustomId(ctx context.Context, customId string) (*models.Article, error) {
+	return repo.findOne(func(article *models.Article) bool {
+		return article.DeletedAt != nil && article.CustomId == customId
+	})
+}
+
+func (repo *MemoryArticleRepository) Search(ctx context.Context, query string, filter ArticleFilter, count uint, offset uint) ([]ArticleSearchResult, error) {
+	terms := search.Terms(query)
+
+	var results []ArticleSearchResult
+	for _, article := range repo.find(func(article *models.Article) bool { return matchesFilter(article, filter) }, 0, 0) {
+		// The same weights as the text index of the Mongo repository
+		score := float64(10*search.Count(article.Name, terms) + search.Count(article.SearchText, terms))
+		if score > 0 {
+			results = append(results, ArticleSearchResult{Article: article, Score: score})
+		}
+	}
+
+	if len(results) == 0 && offset == 0 {
+		prefix := strings.ToLower(query)
+		for _, article := range repo.find(func(article *models.Article) bool {
+			return matchesFilter(article, filter) && strings.HasPrefix(strings.ToLower(article.Name), prefix)
+		}, 0, 0) {
+			results = append(results, ArticleSearchResult{Article: article})
+		}
+
+		sortSearchResults(results)
+		if uint(len(results)) > prefixLimit(count) {
+			results = results[:prefixLimit(count)]
+		}
+		return results, nil
+	}
+
+	sortSearchResults(results)
+
+	if offset >= uint(len(results)) {
+		return nil, nil
+	}
+	results = results[offset:]
+	if count != 0 && uint(len(results)) > count {
+		results = results[:count]
+	}
+
+	return results, nil
+}
+
+func (repo *MemoryArticleRepository) FindByCustomIds(ctx context.Context, customIds []string) ([]models.Article, error) {
+	wanted := make(map[string]bool, len(customIds))
+	for _, customId := range customIds {
+		wanted[customId] = true
+	}
+
+	return repo.find(func(article *models.Article) bool {
+		return article.DeletedAt == nil && wanted[article.CustomId]
+	}, 0, 0), nil
+}
+
+// sortSearchResults puts the results in the order of the Mongo text search
+func sortSearchResults(results []ArticleSearchResult) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Article.CustomId < results[j].Article.CustomId
+	})
+}
+
+func (repo *MemoryArticleRepository) List(ctx context.Context, filter ArticleFilter, count uint, offset uint) ([]models.Article, error) {
+	results := repo.find(func(article *models.Article) bool {
+		return matchesFilter(article, filter)
+	}, 0, 0)
+
+	sort.Slice(results, func(i, j int) bool {
+		return PositionOf(&results[j], filter.Sort).Follows(PositionOf(&results[i], filter.Sort))
+	})
+
+	if offset >= uint(len(results)) {
+		return nil, nil
+	}
+	results = results[offset:]
+	if count != 0 && uint(len(results)) > count {
+		results = results[:count]
+	}
+
+	return results, nil
+}
+
+func (repo *MemoryArticleRepository) Count(ctx context.Context, filter ArticleFilter) (int64, error) {
+	return int64(len(repo.find(func(article *models.Article) bool {
+		return matchesFilter(article, filter)
+	}, 0, 0))), nil
+}
+
+func (repo *MemoryArticleRepository) PopularTags(ctx context.Context, filter ArticleFilter, count uint) ([]TagCount, error) {
+	counts := map[string]int{}
+	for _, article := range repo.find(func(article *models.Article) bool { return matchesFilter(article, filter) }, 0, 0) {
+		for _, tag := range article.Tags {
+			counts[tag]++
+		}
+	}
+
+	var results []TagCount
+	for tag, tagCount := range counts {
+		results = append(results, TagCount{Tag: tag, Count: tagCount})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Count != results[j].Count {
+			return results[i].Count > results[j].Count
+		}
+		return results[i].Tag < results[j].Tag
+	})
+	if count != 0 && uint(len(results)) > count {
+		results = results[:count]
+	}
+
+	return results, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesFilter(article *models.Article, filter ArticleFilter) bool {
+	if filter.Deleted != (article.DeletedAt != nil) {
+		return false
+	}
+	if filter.Author != "" && article.Author != filter.Author {
+		return false
+	}
+	if filter.IsDraft != nil && article.IsDraft != *filter.IsDraft {
+		return false
+	}
+	if filter.Tag != "" && !contains(article.Tags, filter.Tag) {
+		return false
+	}
+	if len(filter.Categories) != 0 && !contains(filter.Categories, article.Category) {
+		return false
+	}
+	if filter.PublishedBy != nil && article.PublishAt != nil && article.PublishAt.After(*filter.PublishedBy) {
+		return false
+	}
+	if filter.PublishedFrom != nil && (article.PublishedAt == nil || article.PublishedAt.Before(*filter.PublishedFrom)) {
+		return false
+	}
+	if filter.PublishedTo != nil && (article.PublishedAt == nil || !article.PublishedAt.Before(*filter.PublishedTo)) {
+		return false
+	}
+	if filter.HasThumbnail != nil && (article.Thumbnail != "") != *filter.HasThumbnail {
+		return false
+	}
+	if filter.After != nil && !PositionOf(article, filter.After.Sort).Follows(*filter.After) {
+		return false
+	}
+	return true
+}
+
+func (repo *MemoryArticleRepository) Update(ctx context.Context, article *models.Article) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	index := repo.indexOf(article.CustomId)
+	if index == -1 || repo.articles[index].DeletedAt != nil {
+		return ErrNotFound
+	}
+
+	if repo.articles[index].Version != article.Version {
+		return ErrConflict
+	}
+
+	article.Version++
+	article.SearchText = searchText(article)
+	// The counters may have changed since the article was read
+	article.ViewCount = repo.articles[index].ViewCount
+	article.Reactions = repo.articles[index].Reactions
+	article.CommentCount = repo.articles[index].CommentCount
+	article.CommentsLocked = repo.articles[index].CommentsLocked
+	repo.articles[index] = *article
+	return nil
+}
+
+func (repo *MemoryArticleRepository) AddViews(ctx context.Context, views map[string]int64) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	for customId, count := range views {
+		if index := repo.indexOf(customId); index != -1 {
+			repo.articles[index].ViewCount += count
+		}
+	}
+
+	return nil
+}
+
+func (repo *MemoryArticleRepository) AddReaction(ctx context.Context, customId string, kind models.ReactionKind, delta int64) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	index := repo.indexOf(customId)
+	if index == -1 {
+		return ErrNotFound
+	}
+
+	// The map is shared with the copies returned before
+	reactions := make(models.ReactionCounts)
+	for k, count := range repo.articles[index].Reactions {
+		reactions[k] = count
+	}
+	reactions[kind] += delta
+	repo.articles[index].Reactions = reactions
+
+	return nil
+}
+
+func (repo *MemoryArticleRepository) ReactionCounts(ctx context.Context) (map[string]models.ReactionCounts, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	counts := make(map[string]models.ReactionCounts)
+	for _, article := range repo.articles {
+		if len(article.Reactions) != 0 {
+			counts[article.CustomId] = article.Reactions
+		}
+	}
+
+	return counts, nil
+}
+
+func (repo *MemoryArticleRepository) SetReactions(ctx context.Context, customId string, counts models.ReactionCounts) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	index := repo.indexOf(customId)
+	if index == -1 {
+		return ErrNotFound
+	}
+
+	// The map is shared with the copies returned before
+	reactions := make(models.ReactionCounts)
+	for kind, count := range counts {
+		reactions[kind] = count
+	}
+	repo.articles[index].Reactions = reactions
+
+	return nil
+}
+
+func (repo *MemoryArticleRepository) AddComments(ctx context.Context, customId string, delta int64) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	index := repo.indexOf(customId)
+	if index == -1 {
+		return ErrNotFound
+	}
+
+	repo.articles[index].CommentCount += delta
+	return nil
+}
+
+func (repo *MemoryArticleRepository) LockComments(ctx context.Context, customId string, locked bool) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	index := repo.indexOf(customId)
+	if index == -1 || repo.articles[index].DeletedAt != nil {
+		return ErrNotFound
+	}
+
+	repo.articles[index].CommentsLocked = locked
+	return nil
+}
+
+func (repo *MemoryArticleRepository) SoftDelete(ctx context.Context, customId string, deletedAt time.Time) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	index := repo.indexOf(customId)
+	if index == -1 || repo.articles[index].DeletedAt != nil {
+		return ErrNotFound
+	}
+
+	repo.articles[index].DeletedAt = &deletedAt
+	return nil
+}
+
+func (repo *MemoryArticleRepository) Restore(ctx context.Context, customId string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	index := repo.indexOf(customId)
+	if index == -1 || repo.articles[index].DeletedAt == nil {
+		return ErrNotFound
+	}
+
+	repo.articles[index].DeletedAt = nil
+	return nil
+}
+
+func (repo *MemoryArticleRepository) PublishDue(ctx context.Context, now time.Time) ([]string, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	var customIds []string
+
+	for i := range repo.articles {
+		article := &repo.articles[i]
+		scheduled := article.State == models.StateApproved || (!article.State.IsValid() && article.IsDraft)
+		if scheduled && article.DeletedAt == nil && article.PublishAt != nil && !article.PublishAt.After(now) {
+			article.SetState(models.StatePublished)
+			article.Version++
+			customIds = append(customIds, article.CustomId)
+		}
+	}
+
+	return customIds, nil
+}
+
+func (repo *MemoryArticleRepository) PurgeDeleted(ctx context.Context, before time.Time) ([]string, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	var customIds []string
+	kept := repo.articles[:0]
+
+	for _, article := range repo.articles {
+		if article.DeletedAt != nil && article.DeletedAt.Before(before) {
+			customIds = append(customIds, article.CustomId)
+			continue
+		}
+		kept = append(kept, article)
+	}
+
+	repo.articles = kept
+	return customIds, nil
+}
+
+func (repo *MemoryArticleRepository) Delete(ctx context.Context, customId string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	index := repo.indexOf(customId)
+	if index == -1 {
+		return ErrNotFound
+	}
+
+	repo.articles = append(repo.articles[:index], repo.articles[index+1:]...)
+	return nil
+}
+
+// indexOf must be called with the mutex held
+func (repo *MemoryArticleRepository) indexOf(customId string) int {
+	for i := range repo.articles {
+		if repo.articles[i].CustomId == customId {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (repo *MemoryArticleRepository) findOne(match func(article *models.Article) bool) (*models.Article, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	for i := range repo.articles {
+		if match(&repo.articles[i]) {
+			article := repo.articles[i]
+			return &article, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (repo *MemoryArticleRepository) find(match func(article *models.Article) bool, count uint, offset uint) []models.Article {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	var results []models.Article
+	var skipped uint
+
+	for i := range repo.articles {
+		if !match(&repo.articles[i]) {
+			continue
+		}
+
+		if skipped < offset {
+			skipped++
+			continue
+		}
+
+		results = append(results, repo.articles[i])
+
+		if count != 0 && uint(len(results)) == count {
+			break
+		}
+	}
+
+	return results
+}
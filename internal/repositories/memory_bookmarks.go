@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"sync"
+)
+
+type MemoryBookmarkRepository struct {
+	mutex     sync.RWMutex
+	bookmarks []models.Bookmark // Oldest first
+}
+
+func NewMemoryBookmarkRepository() *MemoryBookmarkRepository {
+	return &MemoryBookmarkRepository{}
+}
+
+func (repo *MemoryBookmarkRepository) Add(ctx context.Context, bookmark *models.Bookmark) (bool, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	bookmark.Position = 0
+	for _, existing := range repo.bookmarks {
+		if !inList(existing, bookmark.Nickname, bookmark.ListId) {
+			continue
+		}
+		if existing.ArticleId == bookmark.ArticleId {
+			return false, nil
+		}
+		if existing.Position >= bookmark.Position {
+			bookmark.Position = existing.Position + 1
+		}
+	}
+
+	repo.bookmarks = append(repo.bookmarks, *bookmark)
+	return true, nil
+}
+
+func (repo *MemoryBookmarkRepository) Remove(ctx context.Context, nickname string, listId *primitive.ObjectID, articleId string) (bool, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	for i, bookmark := range repo.bookmarks {
+		if inList(bookmark, nickname, listId) && bookmark.ArticleId == articleId {
+			repo.bookmarks = append(repo.bookmarks[:i], repo.bookmarks[i+1:]...)
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (repo *MemoryBookmarkRepository) List(ctx context.Context, nickname string, listId *primitive.ObjectID, count uint, offset uint) ([]models.Bookmark, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	var results []models.Bookmark
+	for _, bookmark := range repo.bookmarks {
+		if inList(bookmark, nickname, listId) {
+			results = append(results, bookmark)
+		}
+	}
+
+	// Stable, so equal positions stay in the order of adding like the _id order in MongoDB
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Position < results[j].Position
+	})
+
+	if offset >= uint(len(results)) {
+		return nil, nil
+	}
+	results = results[offset:]
+	if count != 0 && uint(len(results)) > count {
+		results = results[:count]
+	}
+
+	return results, nil
+}
+
+func (repo *MemoryBookmarkRepository) Count(ctx context.Context, nickname string, listId *primitive.ObjectID) (int64, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	var count int64
+	for _, bookmark := range repo.bookmarks {
+		if inList(bookmark, nickname, listId) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (repo *MemoryBookmarkRepository) Reorder(ctx context.Context, nickname string, listId *primitive.ObjectID, articleIds []string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	for position, articleId := range articleIds {
+		for i := range repo.bookmarks {
+			if inList(repo.bookmarks[i], nickname, listId) && repo.bookmarks[i].ArticleId == articleId {
+				repo.bookmarks[i].Position = int64(position)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (repo *MemoryBookmarkRepository) DeleteByList(ctx context.Context, nickname string, listId primitive.ObjectID) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	repo.keep(func(bookmark models.Bookmark) bool {
+		return !inList(bookmark, nickname, &listId)
+	})
+	return nil
+}
+
+func (repo *MemoryBookmarkRepository) DeleteByArticle(ctx context.Context, articleId string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	repo.keep(func(bookmark models.Bookmark) bool {
+		return bookmark.ArticleId != articleId
+	})
+	return nil
+}
+
+func (repo *MemoryBookmarkRepository) keep(predicate func(bookmark models.Bookmark) bool) {
+	kept := repo.bookmarks[:0]
+	for _, bookmark := range repo.bookmarks {
+		if predicate(bookmark) {
+			kept = append(kept, bookmark)
+		}
+	}
+	repo.bookmarks = kept
+}
+
+func inList(bookmark models.Bookmark, nickname string, listId *primitive.ObjectID) bool {
+	if bookmark.Nickname != nickname || (bookmark.ListId == nil) != (listId == nil) {
+		return false
+	}
+
+	return listId == nil || *bookmark.ListId == *listId
+}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"sync"
+)
+
+type MemoryCategoryRepository struct {
+	mutex      sync.RWMutex
+	categories map[string]models.Category
+}
+
+func NewMemoryCategoryRepository() *MemoryCategoryRepository {
+	return &MemoryCategoryRepository{categories: make(map[string]models.Category)}
+}
+
+func (repo *MemoryCategoryRepository) Create(ctx context.Context, category *models.Category) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, exists := repo.categories[category.Slug]; exists {
+		return ErrDuplicate
+	}
+
+	repo.categories[category.Slug] = *category
+	return nil
+}
+
+func (repo *MemoryCategoryRepository) FindBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	category, exists := repo.categories[slug]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	return &category, nil
+}
+
+func (repo *MemoryCategoryRepository) List(ctx context.Context) ([]models.Category, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	var results []models.Category
+	for _, category := range repo.categories {
+		results = append(results, category)
+	}
+
+	return results, nil
+}
+
+func (repo *MemoryCategoryRepository) Update(ctx context.Context, category *models.Category) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, exists := repo.categories[category.Slug]; !exists {
+		return ErrNotFound
+	}
+
+	repo.categories[category.Slug] = *category
+	return nil
+}
+
+func (repo *MemoryCategoryRepository) Delete(ctx context.Context, slug string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, exists := repo.categories[slug]; !exists {
+		return ErrNotFound
+	}
+
+	delete(repo.categories, slug)
+	return nil
+}
@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"sync"
+)
+
+type MemoryCommentRepository struct {
+	mutex    sync.RWMutex
+	comments []models.Comment // Oldest first
+}
+
+func NewMemoryCommentRepository() *MemoryCommentRepository {
+	return &MemoryCommentRepository{}
+}
+
+func (repo *MemoryCommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	comment.Id = primitive.NewObjectID()
+	repo.comments = append(repo.comments, *comment)
+	return nil
+}
+
+func (repo *MemoryCommentRepository) FindById(ctx context.Context, id primitive.ObjectID) (*models.Comment, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	index := repo.indexOf(id)
+	if index == -1 {
+		return nil, ErrNotFound
+	}
+
+	comment := repo.comments[index]
+	return &comment, nil
+}
+
+func (repo *MemoryCommentRepository) Update(ctx context.Context, comment *models.Comment) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	index := repo.indexOf(comment.Id)
+	if index == -1 {
+		return ErrNotFound
+	}
+
+	repo.comments[index] = *comment
+	return nil
+}
+
+func (repo *MemoryCommentRepository) ListChildren(ctx context.Context, articleId string, parentId *primitive.ObjectID, count uint, offset uint) ([]models.Comment, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	children := repo.children(articleId, parentId)
+
+	// Stable, so the comments stay oldest first within the pinned and the other ones
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].Pinned && !children[j].Pinned
+	})
+
+	if offset >= uint(len(children)) {
+		return nil, nil
+	}
+	children = children[offset:]
+	if count != 0 && uint(len(children)) > count {
+		children = children[:count]
+	}
+
+	return children, nil
+}
+
+func (repo *MemoryCommentRepository) CountChildren(ctx context.Context, articleId string, parentId *primitive.ObjectID) (int64, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	return int64(len(repo.children(articleId, parentId))), nil
+}
+
+func (repo *MemoryCommentRepository) ListDescendants(ctx context.Context, ids []primitive.ObjectID) ([]models.Comment, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	var results []models.Comment
+	for _, comment := range repo.comments {
+		if hasAncestor(comment, ids) {
+			results = append(results, comment)
+		}
+	}
+
+	return results, nil
+}
+
+func (repo *MemoryCommentRepository) DeleteByArticle(ctx context.Context, articleId string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	kept := repo.comments[:0]
+	for _, comment := range repo.comments {
+		if comment.ArticleId != articleId {
+			kept = append(kept, comment)
+		}
+	}
+	repo.comments = kept
+
+	return nil
+}
+
+func (repo *MemoryCommentRepository) children(articleId string, parentId *primitive.ObjectID) []models.Comment {
+	var results []models.Comment
+	for _, comment := range repo.comments {
+		if comment.ArticleId != articleId {
+			continue
+		}
+		if (comment.ParentId == nil) != (parentId == nil) || (parentId != nil && *comment.ParentId != *parentId) {
+			continue
+		}
+		results = append(results, comment)
+	}
+
+	return results
+}
+
+func (repo *MemoryCommentRepository) indexOf(id primitive.ObjectID) int {
+	for i, comment := range repo.comments {
+		if comment.Id == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func hasAncestor(comment models.Comment, ids []primitive.ObjectID) bool {
+	for _, ancestor := range comment.Ancestors {
+		for _, id := range ids {
+			if ancestor == id {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type MemoryLeaseRepository struct {
+	mutex  sync.Mutex
+	leases map[string]memoryLease
+}
+
+type memoryLease struct {
+	holder    string
+	expiresAt time.Time
+}
+
+func NewMemoryLeaseRepository() *MemoryLeaseRepository {
+	return &MemoryLeaseRepository{leases: make(map[string]memoryLease)}
+}
+
+func (repo *MemoryLeaseRepository) Acquire(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	now := time.Now()
+
+	lease, exists := repo.leases[name]
+	if exists && lease.holder != holder && lease.expiresAt.After(now) {
+		return false, nil
+	}
+
+	repo.leases[name] = memoryLease{holder: holder, expiresAt: now.Add(ttl)}
+	return true, nil
+}
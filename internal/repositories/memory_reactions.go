@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"sync"
+)
+
+type MemoryReactionRepository struct {
+	mutex     sync.RWMutex
+	reactions []models.Reaction // Oldest first
+}
+
+func NewMemoryReactionRepository() *MemoryReactionRepository {
+	return &MemoryReactionRepository{}
+}
+
+func (repo *MemoryReactionRepository) Add(ctx context.Context, reaction *models.Reaction) (bool, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if repo.indexOf(reaction.ArticleId, reaction.Nickname, reaction.Kind) != -1 {
+		return false, nil
+	}
+
+	repo.reactions = append(repo.reactions, *reaction)
+	return true, nil
+}
+
+func (repo *MemoryReactionRepository) Remove(ctx context.Context, articleId string, nickname string, kind models.ReactionKind) (bool, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	index := repo.indexOf(articleId, nickname, kind)
+	if index == -1 {
+		return false, nil
+	}
+
+	repo.reactions = append(repo.reactions[:index], repo.reactions[index+1:]...)
+	return true, nil
+}
+
+func (repo *MemoryReactionRepository) Kinds(ctx context.Context, articleId string, nickname string) ([]models.ReactionKind, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	kinds := []models.ReactionKind{}
+	for _, reaction := range repo.reactions {
+		if reaction.ArticleId == articleId && reaction.Nickname == nickname {
+			kinds = append(kinds, reaction.Kind)
+		}
+	}
+
+	return kinds, nil
+}
+
+func (repo *MemoryReactionRepository) ListByNickname(ctx context.Context, nickname string, kind models.ReactionKind, count uint, offset uint) ([]models.Reaction, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	var results []models.Reaction
+	skipped := uint(0)
+
+	for i := len(repo.reactions) - 1; i >= 0; i-- {
+		reaction := repo.reactions[i]
+		if reaction.Nickname != nickname || (kind != "" && reaction.Kind != kind) {
+			continue
+		}
+
+		if skipped < offset {
+			skipped++
+			continue
+		}
+
+		results = append(results, reaction)
+		if count != 0 && uint(len(results)) == count {
+			break
+		}
+	}
+
+	return results, nil
+}
+
+func (repo *MemoryReactionRepository) CountByArticle(ctx context.Context) (map[string]models.ReactionCounts, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	counts := make(map[string]models.ReactionCounts)
+	for _, reaction := range repo.reactions {
+		if counts[reaction.ArticleId] == nil {
+			counts[reaction.ArticleId] = make(models.ReactionCounts)
+		}
+		counts[reaction.ArticleId][reaction.Kind]++
+	}
+
+	return counts, nil
+}
+
+func (repo *MemoryReactionRepository) DeleteByArticle(ctx context.Context, articleId string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	kept := repo.reactions[:0]
+	for _, reaction := range repo.reactions {
+		if reaction.ArticleId != articleId {
+			kept = append(kept, reaction)
+		}
+	}
+	repo.reactions = kept
+
+	return nil
+}
+
+func (repo *MemoryReactionRepository) indexOf(articleId string, nickname string, kind models.ReactionKind) int {
+	for i, reaction := range repo.reactions {
+		if reaction.ArticleId == articleId && reaction.Nickname == nickname && reaction.Kind == kind {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sync"
+)
+
+type MemoryReadingListRepository struct {
+	mutex sync.RWMutex
+	lists []models.ReadingList // Oldest first
+}
+
+func NewMemoryReadingListRepository() *MemoryReadingListRepository {
+	return &MemoryReadingListRepository{}
+}
+
+func (repo *MemoryReadingListRepository) Create(ctx context.Context, list *models.ReadingList) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	list.Id = primitive.NewObjectID()
+	repo.lists = append(repo.lists, *list)
+	return nil
+}
+
+func (repo *MemoryReadingListRepository) FindById(ctx context.Context, id primitive.ObjectID) (*models.ReadingList, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	index := repo.indexOf(id)
+	if index == -1 {
+		return nil, ErrNotFound
+	}
+
+	list := repo.lists[index]
+	return &list, nil
+}
+
+func (repo *MemoryReadingListRepository) ListByNickname(ctx context.Context, nickname string) ([]models.ReadingList, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	var results []models.ReadingList
+	for _, list := range repo.lists {
+		if list.Nickname == nickname {
+			results = append(results, list)
+		}
+	}
+
+	return results, nil
+}
+
+func (repo *MemoryReadingListRepository) Update(ctx context.Context, list *models.ReadingList) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	index := repo.indexOf(list.Id)
+	if index == -1 {
+		return ErrNotFound
+	}
+
+	repo.lists[index] = *list
+	return nil
+}
+
+func (repo *MemoryReadingListRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	index := repo.indexOf(id)
+	if index == -1 {
+		return ErrNotFound
+	}
+
+	repo.lists = append(repo.lists[:index], repo.lists[index+1:]...)
+	return nil
+}
+
+func (repo *MemoryReadingListRepository) indexOf(id primitive.ObjectID) int {
+	for i, list := range repo.lists {
+		if list.Id == id {
+			return i
+		}
+	}
+
+	return -1
+}
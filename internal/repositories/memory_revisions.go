@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"sync"
+)
+
+type MemoryRevisionRepository struct {
+	mutex     sync.RWMutex
+	revisions map[string][]models.ArticleRevision // Oldest first, the index is Number - 1
+}
+
+func NewMemoryRevisionRepository() *MemoryRevisionRepository {
+	return &MemoryRevisionRepository{revisions: make(map[string][]models.ArticleRevision)}
+}
+
+func (repo *MemoryRevisionRepository) Add(ctx context.Context, revision *models.ArticleRevision) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	revision.Number = uint(len(repo.revisions[revision.ArticleId])) + 1
+	repo.revisions[revision.ArticleId] = append(repo.revisions[revision.ArticleId], *revision)
+	return nil
+}
+
+func (repo *MemoryRevisionRepository) Find(ctx context.Context, articleId string, number uint) (*models.ArticleRevision, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	revisions := repo.revisions[articleId]
+	if number == 0 || number > uint(len(revisions)) {
+		return nil, ErrNotFound
+	}
+
+	revision := revisions[number-1]
+	return &revision, nil
+}
+
+func (repo *MemoryRevisionRepository) List(ctx context.Context, articleId string, count uint, offset uint) ([]models.ArticleRevision, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	revisions := repo.revisions[articleId]
+	var results []models.ArticleRevision
+
+	for i := len(revisions) - 1 - int(offset); i >= 0; i-- {
+		results = append(results, revisions[i])
+
+		if count != 0 && uint(len(results)) == count {
+			break
+		}
+	}
+
+	return results, nil
+}
+
+func (repo *MemoryRevisionRepository) DeleteByArticle(ctx context.Context, articleId string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	delete(repo.revisions, articleId)
+	return nil
+}
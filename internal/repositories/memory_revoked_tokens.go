@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type MemoryRevokedTokenRepository struct {
+	mutex  sync.RWMutex
+	tokens map[string]time.Time
+	users  map[string]revokedUser
+}
+
+type revokedUser struct {
+	issuedBefore time.Time
+	expiresAt    time.Time
+}
+
+func NewMemoryRevokedTokenRepository() *MemoryRevokedTokenRepository {
+	return &MemoryRevokedTokenRepository{
+		tokens: make(map[string]time.Time),
+		users:  make(map[string]revokedUser),
+	}
+}
+
+func (repo *MemoryRevokedTokenRepository) Add(ctx context.Context, tokenId string, expiresAt time.Time) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	// Same as the TTL index of the MongoDB implementation
+	now := time.Now()
+	for id, tokenExpiresAt := range repo.tokens {
+		if !tokenExpiresAt.After(now) {
+			delete(repo.tokens, id)
+		}
+	}
+
+	repo.tokens[tokenId] = expiresAt
+	return nil
+}
+
+func (repo *MemoryRevokedTokenRepository) IsRevoked(ctx context.Context, tokenId string) (bool, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	_, exists := repo.tokens[tokenId]
+	return exists, nil
+}
+
+func (repo *MemoryRevokedTokenRepository) RevokeUser(ctx context.Context, nickname string, issuedBefore time.Time, expiresAt time.Time) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	now := time.Now()
+	for name, user := range repo.users {
+		if !user.expiresAt.After(now) {
+			delete(repo.users, name)
+		}
+	}
+
+	// An earlier revocation is covered by a later one, but it can't be moved back
+	user := repo.users[nickname]
+	if issuedBefore.After(user.issuedBefore) {
+		user.issuedBefore = issuedBefore
+	}
+	if expiresAt.After(user.expiresAt) {
+		user.expiresAt = expiresAt
+	}
+	repo.users[nickname] = user
+
+	return nil
+}
+
+func (repo *MemoryRevokedTokenRepository) RevokedBefore(ctx context.Context, nickname string) (time.Time, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	user, exists := repo.users[nickname]
+	if !exists || !user.expiresAt.After(time.Now()) {
+		return time.Time{}, nil
+	}
+
+	return user.issuedBefore, nil
+}
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+type MemorySessionRepository struct {
+	mutex    sync.RWMutex
+	sessions map[string]models.Session
+}
+
+func NewMemorySessionRepository() *MemorySessionRepository {
+	return &MemorySessionRepository{sessions: make(map[string]models.Session)}
+}
+
+func (repo *MemorySessionRepository) Create(ctx context.Context, session *models.Session) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, exists := repo.sessions[session.Id]; exists {
+		return ErrDuplicate
+	}
+
+	repo.sessions[session.Id] = *session
+	return nil
+}
+
+func (repo *MemorySessionRepository) FindById(ctx context.Context, id string) (*models.Session, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	session, exists := repo.sessions[id]
+	if !exists || !session.ExpiresAt.After(time.Now()) {
+		return nil, ErrNotFound
+	}
+
+	return &session, nil
+}
+
+func (repo *MemorySessionRepository) ListByNickname(ctx context.Context, nickname string) ([]models.Session, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	now := time.Now()
+	var results []models.Session
+
+	for _, session := range repo.sessions {
+		if session.Nickname == nickname && session.ExpiresAt.After(now) {
+			results = append(results, session)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].LastUsedAt.After(results[j].LastUsedAt)
+	})
+
+	return results, nil
+}
+
+func (repo *MemorySessionRepository) Rotate(ctx context.Context, id string, oldHash string, newHash string, expiresAt time.Time) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	session, exists := repo.sessions[id]
+	if !exists || session.RefreshHash != oldHash {
+		return ErrNotFound
+	}
+
+	session.RefreshHash = newHash
+	session.LastUsedAt = time.Now()
+	session.ExpiresAt = expiresAt
+	repo.sessions[id] = session
+	return nil
+}
+
+func (repo *MemorySessionRepository) Delete(ctx context.Context, id string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, exists := repo.sessions[id]; !exists {
+		return ErrNotFound
+	}
+
+	delete(repo.sessions, id)
+	return nil
+}
+
+func (repo *MemorySessionRepository) DeleteByNickname(ctx context.Context, nickname string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	for id, session := range repo.sessions {
+		if session.Nickname == nickname {
+			delete(repo.sessions, id)
+		}
+	}
+
+	return nil
+}
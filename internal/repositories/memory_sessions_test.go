@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestMemorySessionRepositoryRotate(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		oldHash string
+		wantErr error
+	}{
+		{"current refresh token", "session", "first", nil},
+		{"another refresh token", "session", "another", ErrNotFound},
+		{"revoked session", "revoked", "first", ErrNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := NewMemorySessionRepository()
+			expiresAt := time.Now().Add(time.Hour)
+			for _, id := range []string{"session", "revoked"} {
+				if err := repo.Create(ctx, &models.Session{Id: id, Nickname: "nick", RefreshHash: "first", ExpiresAt: expiresAt}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := repo.Delete(ctx, "revoked"); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := repo.Rotate(ctx, test.id, test.oldHash, "second", expiresAt); err != test.wantErr {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if test.wantErr != nil {
+				return
+			}
+
+			// The rotated refresh token can't be used twice
+			if err := repo.Rotate(ctx, test.id, test.oldHash, "third", expiresAt); err != ErrNotFound {
+				t.Errorf("got error %v on reuse, want %v", err, ErrNotFound)
+			}
+			if session, err := repo.FindById(ctx, test.id); err != nil || session.RefreshHash != "second" {
+				t.Errorf("got session %+v and error %v", session, err)
+			}
+		})
+	}
+}
+
+func TestMemorySessionRepositoryDeleteByNickname(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemorySessionRepository()
+	expiresAt := time.Now().Add(time.Hour)
+	for _, session := range []models.Session{
+		{Id: "a", Nickname: "nick", ExpiresAt: expiresAt},
+		{Id: "b", Nickname: "nick", ExpiresAt: expiresAt},
+		{Id: "c", Nickname: "other", ExpiresAt: expiresAt},
+		{Id: "d", Nickname: "other", ExpiresAt: time.Now().Add(-time.Hour)},
+	} {
+		session := session
+		if err := repo.Create(ctx, &session); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := repo.DeleteByNickname(ctx, "nick"); err != nil {
+		t.Fatal(err)
+	}
+
+	if sessions, _ := repo.ListByNickname(ctx, "nick"); len(sessions) != 0 {
+		t.Errorf("got sessions %+v, want none", sessions)
+	}
+	// Expired sessions aren't listed
+	if sessions, _ := repo.ListByNickname(ctx, "other"); len(sessions) != 1 || sessions[0].Id != "c" {
+		t.Errorf("got sessions %+v, want c", sessions)
+	}
+}
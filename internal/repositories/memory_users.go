@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"sync"
+)
+
+type MemoryUserRepository struct {
+	mutex sync.RWMutex
+	users map[string]models.User
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: make(map[string]models.User)}
+}
+
+func (repo *MemoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, exists := repo.users[user.Nickname]; exists {
+		return ErrDuplicate
+	}
+
+	repo.users[user.Nickname] = *user
+	return nil
+}
+
+func (repo *MemoryUserRepository) FindByNickname(ctx context.Context, nickname string) (*models.User, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	user, exists := repo.users[nickname]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	return &user, nil
+}
+
+func (repo *MemoryUserRepository) Update(ctx context.Context, user *models.User) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, exists := repo.users[user.Nickname]; !exists {
+		return ErrNotFound
+	}
+
+	repo.users[user.Nickname] = *user
+	return nil
+}
+
+func (repo *MemoryUserRepository) Delete(ctx context.Context, nickname string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, exists := repo.users[nickname]; !exists {
+		return ErrNotFound
+	}
+
+	delete(repo.users, nickname)
+	return nil
+}
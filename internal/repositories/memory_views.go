@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+type MemoryViewRepository struct {
+	mutex sync.Mutex
+	views map[memoryViewKey]int64
+}
+
+type memoryViewKey struct {
+	articleId string
+	hour      time.Time
+}
+
+func NewMemoryViewRepository() *MemoryViewRepository {
+	return &MemoryViewRepository{views: make(map[memoryViewKey]int64)}
+}
+
+func (repo *MemoryViewRepository) Add(ctx context.Context, hour time.Time, views map[string]int64) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	for articleId, count := range views {
+		repo.views[memoryViewKey{articleId: articleId, hour: hour}] += count
+	}
+
+	// Forget the hours that no period reaches like the MongoDB TTL index does
+	expired := time.Now().Add(-models.ViewRetention)
+	for key := range repo.views {
+		if key.hour.Before(expired) {
+			delete(repo.views, key)
+		}
+	}
+
+	return nil
+}
+
+func (repo *MemoryViewRepository) Popular(ctx context.Context, since time.Time, halfLife time.Duration, count uint) ([]ArticleViews, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	now := time.Now()
+
+	scores := make(map[string]float64)
+	for key, views := range repo.views {
+		if key.hour.Before(since) {
+			continue
+		}
+		scores[key.articleId] += float64(views) * math.Pow(0.5, float64(now.Sub(key.hour))/float64(halfLife))
+	}
+
+	var results []ArticleViews
+	for articleId, score := range scores {
+		results = append(results, ArticleViews{ArticleId: articleId, Score: score})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ArticleId < results[j].ArticleId
+	})
+
+	if count != 0 && uint(len(results)) > count {
+		results = results[:count]
+	}
+
+	return results, nil
+}
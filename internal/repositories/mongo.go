@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"strings"
+)
+
+const mongoDuplicateKeyCode = 11000
+
+// duplicatesReportLimit caps the values listed when a unique index can't be created
+const duplicatesReportLimit = 20
+
+// isDuplicateKeyError tells if a unique index rejected the write or couldn't be created. The unique
+// indexes are relied on instead of a lookup before the insert, which would let a concurrent duplicate
+// slip in.
+func isDuplicateKeyError(err error) bool {
+	if writeException, ok := err.(mongo.WriteException); ok {
+		for _, writeError := range writeException.WriteErrors {
+			if writeError.Code == mongoDuplicateKeyCode {
+				return true
+			}
+		}
+	}
+
+	if commandError, ok := err.(mongo.CommandError); ok {
+		return commandError.Code == mongoDuplicateKeyCode
+	}
+
+	return false
+}
+
+// uniqueIndexError explains why the unique index on the field couldn't be created by listing the
+// values that are stored more than once, they have to be resolved by hand
+func uniqueIndexError(ctx context.Context, collection *mongo.Collection, field string, err error) error {
+	if !isDuplicateKeyError(err) {
+		return err
+	}
+
+	pipeline := bson.A{
+		bson.M{"$group": bson.M{"_id": "$" + field, "count": bson.M{"$sum": 1}}},
+		bson.M{"$match": bson.M{"count": bson.M{"$gt": 1}}},
+		bson.M{"$sort": bson.M{"_id": 1}},
+		bson.M{"$limit": duplicatesReportLimit},
+	}
+
+	cur, aggregateErr := collection.Aggregate(ctx, pipeline)
+	if aggregateErr != nil {
+		return fmt.Errorf("%s.%s has duplicates, the unique index can't be created: %v", collection.Name(), field, err)
+	}
+	defer cur.Close(ctx)
+
+	var duplicates []struct {
+		Value interface{} `bson:"_id"`
+		Count int         `bson:"count"`
+	}
+	if err := cur.All(ctx, &duplicates); err != nil {
+		return err
+	}
+
+	values := make([]string, len(duplicates))
+	for i, duplicate := range duplicates {
+		values[i] = fmt.Sprintf("%q (%d times)", fmt.Sprint(duplicate.Value), duplicate.Count)
+	}
+	if len(duplicates) == duplicatesReportLimit {
+		values = append(values, "...")
+	}
+
+	return fmt.Errorf("%s.%s has duplicates, resolve them to create the unique index: %s",
+		collection.Name(), field, strings.Join(values, ", "))
+}
@@ -0,0 +1,567 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+	"time"
+)
+
+type MongoArticleRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoArticleRepository(db *mongo.Database) *MongoArticleRepository {
+	return &MongoArticleRepository{collection: db.Collection("articles")}
+}
+
+// EnsureIndexes creates the unique custom id, text, taxonomy and List order indexes, and fills search_text,
+// view_count, reactions and published_at of the articles stored before they were introduced
+func (repo *MongoArticleRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := repo.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		// Soft-deleted articles keep their custom ids
+		{Keys: bson.D{{Key: "custom_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{
+			Keys: bson.D{{Key: "name", Value: "text"}, {Key: "search_text", Value: "text"}},
+			Options: options.Index().
+				SetName("articles_text").
+				SetWeights(bson.M{"name": 10, "search_text": 1}).
+				// Most articles are in Russian, English words are indexed unstemmed
+				SetDefaultLanguage("russian"),
+		},
+		{Keys: bson.D{{Key: "tags", Value: 1}}},
+		{Keys: bson.D{{Key: "category", Value: 1}}},
+		// The List orders, the newest and the oldest share one
+		{Keys: bson.D{{Key: "published_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "view_count", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}},
+		// Author pages
+		{Keys: bson.D{{Key: "author", Value: 1}, {Key: "published_at", Value: -1}, {Key: "_id", Value: -1}}},
+	})
+	if err != nil {
+		return uniqueIndexError(ctx, repo.collection, "custom_id", err)
+	}
+
+	// The popularity order needs the field, null would sort apart from zero
+	_, err = repo.collection.UpdateMany(ctx, bson.M{"view_count": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"view_count": 0}})
+	if err != nil {
+		return err
+	}
+
+	// AddReaction increments fields of the reactions document
+	_, err = repo.collection.UpdateMany(ctx, bson.M{"reactions": nil}, bson.M{"$set": bson.M{"reactions": bson.M{}}})
+	if err != nil {
+		return err
+	}
+
+	// Articles published before published_at was introduced are dated by publish_at or their creation
+	undated, err := repo.find(ctx, bson.M{"is_draft": false, "published_at": bson.M{"$exists": false}}, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	for i := range undated {
+		publishedAt := undated[i].Id.Timestamp()
+		if undated[i].PublishAt != nil {
+			publishedAt = *undated[i].PublishAt
+		}
+
+		update := bson.M{"$set": bson.M{"published_at": publishedAt}}
+		if _, err := repo.collection.UpdateOne(ctx, bson.M{"_id": undated[i].Id}, update); err != nil {
+			return err
+		}
+	}
+
+	missing, err := repo.find(ctx, bson.M{"search_text": bson.M{"$exists": false}}, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	for i := range missing {
+		update := bson.M{"$set": bson.M{"search_text": searchText(&missing[i])}}
+		if _, err := repo.collection.UpdateOne(ctx, bson.M{"_id": missing[i].Id}, update); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (repo *MongoArticleRepository) Create(ctx context.Context, article *models.Article) error {
+	article.SearchText = searchText(article)
+	// A nil map would be stored as null, and $inc can't create fields in null
+	if article.Reactions == nil {
+		article.Reactions = models.ReactionCounts{}
+	}
+
+	if _, err := repo.collection.InsertOne(ctx, article); err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrDuplicate
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (repo *MongoArticleRepository) FindByCustomId(ctx context.Context, customId string) (*models.Article, error) {
+	return repo.findOne(ctx, bson.M{"custom_id": customId, "deleted_at": nil})
+}
+
+func (repo *MongoArticleRepository) FindByName(ctx context.Context, name string) (*models.Article, error) {
+	return repo.findOne(ctx, bson.M{"name": name, "deleted_at": nil})
+}
+
+func (repo *MongoArticleRepository) FindDeletedByCustomId(ctx context.Context, customId string) (*models.Article, error) {
+	return repo.findOne(ctx, bson.M{"custom_id": customId, "deleted_at": bson.M{"$ne": nil}})
+}
+
+func (repo *MongoArticleRepository) Search(ctx context.Context, query string, filter ArticleFilter, count uint, offset uint) ([]ArticleSearchResult, error) {
+	textQuery := filterQuery(filter)
+	textQuery["$text"] = bson.M{"$search": query}
+
+	// Only what the facets and the ranking need is read, the pages are loaded by custom id
+	projection := bson.M{"score": bson.M{"$meta": "textScore"}, "custom_id": 1, "author": 1, "tags": 1, "category": 1}
+	sort := bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "custom_id", Value: 1}}
+	findOptions := options.Find().SetProjection(projection).SetSort(sort).SetLimit(int64(count)).SetSkip(int64(offset))
+
+	results, err := repo.findSearchResults(ctx, textQuery, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) != 0 || offset != 0 {
+		return results, nil
+	}
+
+	// The text index only matches whole words, so a name being typed is matched by its beginning.
+	// The query is escaped and anchored, so it's neither a regex injection nor a collection scan.
+	prefixQuery := filterQuery(filter)
+	prefixQuery["name"] = bson.M{"$regex": "^" + regexp.QuoteMeta(query), "$options": "i"}
+
+	delete(projection, "score")
+	findOptions = options.Find().SetProjection(projection).SetSort(bson.M{"custom_id": 1}).SetLimit(int64(prefixLimit(count)))
+
+	return repo.findSearchResults(ctx, prefixQuery, findOptions)
+}
+
+func (repo *MongoArticleRepository) FindByCustomIds(ctx context.Context, customIds []string) ([]models.Article, error) {
+	if len(customIds) == 0 {
+		return nil, nil
+	}
+
+	return repo.find(ctx, bson.M{"custom_id": bson.M{"$in": customIds}, "deleted_at": nil}, 0, 0)
+}
+
+func (repo *MongoArticleRepository) List(ctx context.Context, filter ArticleFilter, count uint, offset uint) ([]models.Article, error) {
+	field, direction := sortField(filter.Sort)
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: field, Value: direction}, {Key: "_id", Value: direction}})
+	findOptions.SetLimit(int64(count))
+	findOptions.SetSkip(int64(offset))
+
+	cur, err := repo.collection.Find(ctx, filterQuery(filter), findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var results []models.Article
+	if err := cur.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (repo *MongoArticleRepository) Count(ctx context.Context, filter ArticleFilter) (int64, error) {
+	return repo.collection.CountDocuments(ctx, filterQuery(filter))
+}
+
+func (repo *MongoArticleRepository) PopularTags(ctx context.Context, filter ArticleFilter, count uint) ([]TagCount, error) {
+	pipeline := bson.A{
+		bson.M{"$match": filterQuery(filter)},
+		bson.M{"$unwind": "$tags"},
+		bson.M{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+	}
+	if count != 0 {
+		pipeline = append(pipeline, bson.M{"$limit": count})
+	}
+
+	cur, err := repo.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var results []TagCount
+	if err := cur.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func filterQuery(filter ArticleFilter) bson.M {
+	query := bson.M{"deleted_at": nil}
+	if filter.Deleted {
+		query["deleted_at"] = bson.M{"$ne": nil}
+	}
+	if filter.Author != "" {
+		query["author"] = filter.Author
+	}
+	if filter.IsDraft != nil {
+		query["is_draft"] = *filter.IsDraft
+	}
+	if filter.Tag != "" {
+		query["tags"] = filter.Tag
+	}
+	if len(filter.Categories) != 0 {
+		query["category"] = bson.M{"$in": filter.Categories}
+	}
+	if filter.PublishedBy != nil {
+		query["$or"] = bson.A{
+			bson.M{"publish_at": nil},
+			bson.M{"publish_at": bson.M{"$lte": *filter.PublishedBy}},
+		}
+	}
+	if filter.PublishedFrom != nil || filter.PublishedTo != nil {
+		publishedAt := bson.M{}
+		if filter.PublishedFrom != nil {
+			publishedAt["$gte"] = *filter.PublishedFrom
+		}
+		if filter.PublishedTo != nil {
+			publishedAt["$lt"] = *filter.PublishedTo
+		}
+		query["published_at"] = publishedAt
+	}
+	if filter.HasThumbnail != nil {
+		if *filter.HasThumbnail {
+			query["thumbnail"] = bson.M{"$nin": bson.A{nil, ""}}
+		} else {
+			query["thumbnail"] = bson.M{"$in": bson.A{nil, ""}}
+		}
+	}
+	if filter.After != nil {
+		query["$and"] = bson.A{afterQuery(*filter.After)}
+	}
+
+	return query
+}
+
+// sortField returns the field of the List order and its direction, every order has an index
+func sortField(sort models.ArticleSort) (string, int) {
+	switch sort {
+	case models.SortOldest:
+		return "published_at", 1
+	case models.SortPopularity:
+		return "view_count", -1
+	case models.SortName:
+		return "name", 1
+	}
+
+	return "published_at", -1
+}
+
+// afterQuery matches the articles following the position in the List order, a null published_at
+// sorts lower than any date, so unpublished articles come last when sorted by the newest
+func afterQuery(position ArticlePosition) bson.M {
+	field, direction := sortField(position.Sort)
+
+	var value interface{}
+	switch field {
+	case "view_count":
+		value = position.ViewCount
+	case "name":
+		value = position.Name
+	default:
+		if position.PublishedAt == nil {
+			if direction < 0 {
+				return bson.M{"published_at": nil, "_id": bson.M{"$lt": position.Id}}
+			}
+			return bson.M{"$or": bson.A{
+				bson.M{"published_at": nil, "_id": bson.M{"$gt": position.Id}},
+				bson.M{"published_at": bson.M{"$ne": nil}},
+			}}
+		}
+		value = *position.PublishedAt
+	}
+
+	operator := "$gt"
+	if direction < 0 {
+		operator = "$lt"
+	}
+
+	after := bson.A{
+		bson.M{field: bson.M{operator: value}},
+		bson.M{field: value, "_id": bson.M{operator: position.Id}},
+	}
+	if field == "published_at" && direction < 0 {
+		after = append(after, bson.M{"published_at": nil})
+	}
+
+	return bson.M{"$or": after}
+}
+
+func (repo *MongoArticleRepository) Update(ctx context.Context, article *models.Article) error {
+	filter := bson.M{"custom_id": article.CustomId, "deleted_at": nil, "version": article.Version}
+	if article.Version == 0 {
+		// Articles created before versioning have no version field
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	article.SearchText = searchText(article)
+
+	updated := *article
+	updated.Version++
+
+	set, err := withoutCounters(&updated)
+	if err != nil {
+		return err
+	}
+
+	err = repo.updateOne(ctx, filter, bson.M{"$set": set})
+	if err == ErrNotFound {
+		if _, findErr := repo.FindByCustomId(ctx, article.CustomId); findErr == nil {
+			return ErrConflict
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	article.Version = updated.Version
+	return nil
+}
+
+// withoutCounters returns the document of the article without the fields that are changed in place
+// by their own methods, the article read before an update has stale values of them
+func withoutCounters(article *models.Article) (bson.M, error) {
+	data, err := bson.Marshal(article)
+	if err != nil {
+		return nil, err
+	}
+
+	var document bson.M
+	if err := bson.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	delete(document, "view_count")
+	delete(document, "reactions")
+	delete(document, "comment_count")
+	delete(document, "comments_locked")
+
+	return document, nil
+}
+
+func (repo *MongoArticleRepository) AddViews(ctx context.Context, views map[string]int64) error {
+	if len(views) == 0 {
+		return nil
+	}
+
+	var writes []mongo.WriteModel
+	for customId, count := range views {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"custom_id": customId}).
+			SetUpdate(bson.M{"$inc": bson.M{"view_count": count}}))
+	}
+
+	_, err := repo.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (repo *MongoArticleRepository) AddReaction(ctx context.Context, customId string, kind models.ReactionKind, delta int64) error {
+	filter := bson.M{"custom_id": customId}
+
+	return repo.updateOne(ctx, filter, bson.M{"$inc": bson.M{"reactions." + string(kind): delta}})
+}
+
+func (repo *MongoArticleRepository) ReactionCounts(ctx context.Context) (map[string]models.ReactionCounts, error) {
+	findOptions := options.Find().SetProjection(bson.M{"custom_id": 1, "reactions": 1})
+
+	cursor, err := repo.collection.Find(ctx, bson.M{"reactions": bson.M{"$nin": bson.A{nil, bson.M{}}}}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var articles []struct {
+		CustomId  string                `bson:"custom_id"`
+		Reactions models.ReactionCounts `bson:"reactions"`
+	}
+	if err := cursor.All(ctx, &articles); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]models.ReactionCounts)
+	for _, article := range articles {
+		counts[article.CustomId] = article.Reactions
+	}
+
+	return counts, nil
+}
+
+func (repo *MongoArticleRepository) SetReactions(ctx context.Context, customId string, counts models.ReactionCounts) error {
+	filter := bson.M{"custom_id": customId}
+
+	return repo.updateOne(ctx, filter, bson.M{"$set": bson.M{"reactions": counts}})
+}
+
+func (repo *MongoArticleRepository) AddComments(ctx context.Context, customId string, delta int64) error {
+	filter := bson.M{"custom_id": customId}
+
+	return repo.updateOne(ctx, filter, bson.M{"$inc": bson.M{"comment_count": delta}})
+}
+
+func (repo *MongoArticleRepository) LockComments(ctx context.Context, customId string, locked bool) error {
+	filter := bson.M{"custom_id": customId, "deleted_at": nil}
+
+	return repo.updateOne(ctx, filter, bson.M{"$set": bson.M{"comments_locked": locked}})
+}
+
+func (repo *MongoArticleRepository) SoftDelete(ctx context.Context, customId string, deletedAt time.Time) error {
+	filter := bson.M{"custom_id": customId, "deleted_at": nil}
+
+	return repo.updateOne(ctx, filter, bson.M{"$set": bson.M{"deleted_at": deletedAt}})
+}
+
+func (repo *MongoArticleRepository) Restore(ctx context.Context, customId string) error {
+	filter := bson.M{"custom_id": customId, "deleted_at": bson.M{"$ne": nil}}
+
+	return repo.updateOne(ctx, filter, bson.M{"$set": bson.M{"deleted_at": nil}})
+}
+
+func (repo *MongoArticleRepository) PublishDue(ctx context.Context, now time.Time) ([]string, error) {
+	filter := bson.M{
+		"deleted_at": nil,
+		"publish_at": bson.M{"$ne": nil, "$lte": now},
+		"$or": bson.A{
+			bson.M{"state": models.StateApproved},
+			// Scheduled before the editorial workflow was introduced
+			bson.M{"state": bson.M{"$in": bson.A{nil, ""}}, "is_draft": true},
+		},
+	}
+
+	due, err := repo.find(ctx, filter, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var customIds []string
+	for _, article := range due {
+		customIds = append(customIds, article.CustomId)
+	}
+
+	if len(customIds) == 0 {
+		return nil, nil
+	}
+
+	// Publishing is a change, so editors holding the old version get a conflict
+	filter["custom_id"] = bson.M{"$in": customIds}
+	update := bson.M{"$set": bson.M{"state": models.StatePublished, "is_draft": false, "published_at": now}, "$inc": bson.M{"version": 1}}
+	if _, err := repo.collection.UpdateMany(ctx, filter, update); err != nil {
+		return nil, err
+	}
+
+	return customIds, nil
+}
+
+func (repo *MongoArticleRepository) PurgeDeleted(ctx context.Context, before time.Time) ([]string, error) {
+	expired, err := repo.find(ctx, bson.M{"deleted_at": bson.M{"$ne": nil, "$lt": before}}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var customIds []string
+	for _, article := range expired {
+		customIds = append(customIds, article.CustomId)
+	}
+
+	if len(customIds) == 0 {
+		return nil, nil
+	}
+
+	// deleted_at is checked again in case an article was restored in the meantime
+	filter := bson.M{"custom_id": bson.M{"$in": customIds}, "deleted_at": bson.M{"$ne": nil, "$lt": before}}
+	if _, err := repo.collection.DeleteMany(ctx, filter); err != nil {
+		return nil, err
+	}
+
+	return customIds, nil
+}
+
+func (repo *MongoArticleRepository) Delete(ctx context.Context, customId string) error {
+	result, err := repo.collection.DeleteOne(ctx, bson.M{"custom_id": customId})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (repo *MongoArticleRepository) updateOne(ctx context.Context, filter bson.M, update bson.M) error {
+	result, err := repo.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (repo *MongoArticleRepository) findOne(ctx context.Context, filter bson.M) (*models.Article, error) {
+	var article models.Article
+	if err := repo.collection.FindOne(ctx, filter).Decode(&article); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &article, nil
+}
+
+func (repo *MongoArticleRepository) findSearchResults(ctx context.Context, filter bson.M, findOptions *options.FindOptions) ([]ArticleSearchResult, error) {
+	cur, err := repo.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var results []ArticleSearchResult
+	if err := cur.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (repo *MongoArticleRepository) find(ctx context.Context, filter bson.M, count uint, offset uint) ([]models.Article, error) {
+	findOptions := options.Find()
+	findOptions.SetLimit(int64(count))
+	findOptions.SetSkip(int64(offset))
+
+	cur, err := repo.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var results []models.Article
+	if err := cur.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
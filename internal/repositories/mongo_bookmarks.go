@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoBookmarkRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoBookmarkRepository(db *mongo.Database) *MongoBookmarkRepository {
+	return &MongoBookmarkRepository{collection: db.Collection("bookmarks")}
+}
+
+// EnsureIndexes keeps an article once per list and indexes the list order and the cleanup by article
+func (repo *MongoBookmarkRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := repo.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "nickname", Value: 1}, {Key: "list_id", Value: 1}, {Key: "article_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "nickname", Value: 1}, {Key: "list_id", Value: 1}, {Key: "position", Value: 1}}},
+		{Keys: bson.M{"article_id": 1}},
+	})
+	return err
+}
+
+func (repo *MongoBookmarkRepository) Add(ctx context.Context, bookmark *models.Bookmark) (bool, error) {
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "position", Value: -1}})
+
+	var last models.Bookmark
+	err := repo.collection.FindOne(ctx, bson.M{"nickname": bookmark.Nickname, "list_id": bookmark.ListId}, findOptions).Decode(&last)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return false, err
+	}
+
+	// Concurrent adds may get the same position, the order between them doesn't matter
+	bookmark.Position = 0
+	if err == nil {
+		bookmark.Position = last.Position + 1
+	}
+
+	_, err = repo.collection.InsertOne(ctx, bookmark)
+	if isDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (repo *MongoBookmarkRepository) Remove(ctx context.Context, nickname string, listId *primitive.ObjectID, articleId string) (bool, error) {
+	result, err := repo.collection.DeleteOne(ctx, bson.M{"nickname": nickname, "list_id": listId, "article_id": articleId})
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount != 0, nil
+}
+
+func (repo *MongoBookmarkRepository) List(ctx context.Context, nickname string, listId *primitive.ObjectID, count uint, offset uint) ([]models.Bookmark, error) {
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "position", Value: 1}, {Key: "_id", Value: 1}})
+	findOptions.SetLimit(int64(count))
+	findOptions.SetSkip(int64(offset))
+
+	cursor, err := repo.collection.Find(ctx, bson.M{"nickname": nickname, "list_id": listId}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var bookmarks []models.Bookmark
+	if err := cursor.All(ctx, &bookmarks); err != nil {
+		return nil, err
+	}
+
+	return bookmarks, nil
+}
+
+func (repo *MongoBookmarkRepository) Count(ctx context.Context, nickname string, listId *primitive.ObjectID) (int64, error) {
+	return repo.collection.CountDocuments(ctx, bson.M{"nickname": nickname, "list_id": listId})
+}
+
+func (repo *MongoBookmarkRepository) Reorder(ctx context.Context, nickname string, listId *primitive.ObjectID, articleIds []string) error {
+	if len(articleIds) == 0 {
+		return nil
+	}
+
+	var writes []mongo.WriteModel
+	for position, articleId := range articleIds {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"nickname": nickname, "list_id": listId, "article_id": articleId}).
+			SetUpdate(bson.M{"$set": bson.M{"position": position}}))
+	}
+
+	_, err := repo.collection.BulkWrite(ctx, writes)
+	return err
+}
+
+func (repo *MongoBookmarkRepository) DeleteByList(ctx context.Context, nickname string, listId primitive.ObjectID) error {
+	_, err := repo.collection.DeleteMany(ctx, bson.M{"nickname": nickname, "list_id": listId})
+	return err
+}
+
+func (repo *MongoBookmarkRepository) DeleteByArticle(ctx context.Context, articleId string) error {
+	_, err := repo.collection.DeleteMany(ctx, bson.M{"article_id": articleId})
+	return err
+}
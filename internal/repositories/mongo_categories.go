@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoCategoryRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoCategoryRepository(db *mongo.Database) *MongoCategoryRepository {
+	return &MongoCategoryRepository{collection: db.Collection("categories")}
+}
+
+func (repo *MongoCategoryRepository) Create(ctx context.Context, category *models.Category) error {
+	// The slug is the _id, so a duplicate can't slip in between a check and the insert
+	if _, err := repo.collection.InsertOne(ctx, category); err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrDuplicate
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (repo *MongoCategoryRepository) FindBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	var category models.Category
+	if err := repo.collection.FindOne(ctx, bson.M{"_id": slug}).Decode(&category); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+func (repo *MongoCategoryRepository) List(ctx context.Context) ([]models.Category, error) {
+	cur, err := repo.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var results []models.Category
+	if err := cur.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (repo *MongoCategoryRepository) Update(ctx context.Context, category *models.Category) error {
+	result, err := repo.collection.ReplaceOne(ctx, bson.M{"_id": category.Slug}, category)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (repo *MongoCategoryRepository) Delete(ctx context.Context, slug string) error {
+	result, err := repo.collection.DeleteOne(ctx, bson.M{"_id": slug})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
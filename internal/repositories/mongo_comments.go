@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoCommentRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoCommentRepository(db *mongo.Database) *MongoCommentRepository {
+	return &MongoCommentRepository{collection: db.Collection("comments")}
+}
+
+// EnsureIndexes creates the index of the thread order and the one that finds replies at any depth
+func (repo *MongoCommentRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := repo.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{
+			{Key: "article_id", Value: 1},
+			{Key: "parent_id", Value: 1},
+			{Key: "pinned", Value: -1},
+			{Key: "created_at", Value: 1},
+			{Key: "_id", Value: 1},
+		}},
+		{Keys: bson.M{"ancestors": 1}},
+	})
+	return err
+}
+
+func (repo *MongoCommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	comment.Id = primitive.NewObjectID()
+
+	_, err := repo.collection.InsertOne(ctx, comment)
+	return err
+}
+
+func (repo *MongoCommentRepository) FindById(ctx context.Context, id primitive.ObjectID) (*models.Comment, error) {
+	var comment models.Comment
+	if err := repo.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&comment); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+func (repo *MongoCommentRepository) Update(ctx context.Context, comment *models.Comment) error {
+	result, err := repo.collection.ReplaceOne(ctx, bson.M{"_id": comment.Id}, comment)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (repo *MongoCommentRepository) ListChildren(ctx context.Context, articleId string, parentId *primitive.ObjectID, count uint, offset uint) ([]models.Comment, error) {
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "pinned", Value: -1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	findOptions.SetLimit(int64(count))
+	findOptions.SetSkip(int64(offset))
+
+	return repo.find(ctx, bson.M{"article_id": articleId, "parent_id": parentId}, findOptions)
+}
+
+func (repo *MongoCommentRepository) CountChildren(ctx context.Context, articleId string, parentId *primitive.ObjectID) (int64, error) {
+	return repo.collection.CountDocuments(ctx, bson.M{"article_id": articleId, "parent_id": parentId})
+}
+
+func (repo *MongoCommentRepository) ListDescendants(ctx context.Context, ids []primitive.ObjectID) ([]models.Comment, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	return repo.find(ctx, bson.M{"ancestors": bson.M{"$in": ids}}, findOptions)
+}
+
+func (repo *MongoCommentRepository) DeleteByArticle(ctx context.Context, articleId string) error {
+	_, err := repo.collection.DeleteMany(ctx, bson.M{"article_id": articleId})
+	return err
+}
+
+func (repo *MongoCommentRepository) find(ctx context.Context, filter bson.M, findOptions *options.FindOptions) ([]models.Comment, error) {
+	cursor, err := repo.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var comments []models.Comment
+	if err := cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type MongoLeaseRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoLeaseRepository(db *mongo.Database) *MongoLeaseRepository {
+	return &MongoLeaseRepository{collection: db.Collection("leases")}
+}
+
+func (repo *MongoLeaseRepository) Acquire(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+
+	// Matches a free, expired or own lease. A lease held by someone else doesn't match,
+	// so the upsert tries to insert a second document with the same _id and fails.
+	filter := bson.M{
+		"_id": name,
+		"$or": bson.A{
+			bson.M{"expires_at": bson.M{"$lte": now}},
+			bson.M{"holder": holder},
+		},
+	}
+	update := bson.M{"$set": bson.M{"holder": holder, "expires_at": now.Add(ttl)}}
+
+	_, err := repo.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if isDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
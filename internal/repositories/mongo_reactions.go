@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoReactionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoReactionRepository(db *mongo.Database) *MongoReactionRepository {
+	return &MongoReactionRepository{collection: db.Collection("reactions")}
+}
+
+// EnsureIndexes makes reactions unique per user, article and kind and indexes the lists of the users
+func (repo *MongoReactionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := repo.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "article_id", Value: 1}, {Key: "nickname", Value: 1}, {Key: "kind", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "nickname", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	return err
+}
+
+func (repo *MongoReactionRepository) Add(ctx context.Context, reaction *models.Reaction) (bool, error) {
+	_, err := repo.collection.InsertOne(ctx, reaction)
+	if isDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (repo *MongoReactionRepository) Remove(ctx context.Context, articleId string, nickname string, kind models.ReactionKind) (bool, error) {
+	result, err := repo.collection.DeleteOne(ctx, bson.M{"article_id": articleId, "nickname": nickname, "kind": kind})
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount != 0, nil
+}
+
+func (repo *MongoReactionRepository) Kinds(ctx context.Context, articleId string, nickname string) ([]models.ReactionKind, error) {
+	cursor, err := repo.collection.Find(ctx, bson.M{"article_id": articleId, "nickname": nickname})
+	if err != nil {
+		return nil, err
+	}
+
+	var reactions []models.Reaction
+	if err := cursor.All(ctx, &reactions); err != nil {
+		return nil, err
+	}
+
+	kinds := []models.ReactionKind{}
+	for _, reaction := range reactions {
+		kinds = append(kinds, reaction.Kind)
+	}
+
+	return kinds, nil
+}
+
+func (repo *MongoReactionRepository) ListByNickname(ctx context.Context, nickname string, kind models.ReactionKind, count uint, offset uint) ([]models.Reaction, error) {
+	filter := bson.M{"nickname": nickname}
+	if kind != "" {
+		filter["kind"] = kind
+	}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	findOptions.SetLimit(int64(count))
+	findOptions.SetSkip(int64(offset))
+
+	cursor, err := repo.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var reactions []models.Reaction
+	if err := cursor.All(ctx, &reactions); err != nil {
+		return nil, err
+	}
+
+	return reactions, nil
+}
+
+func (repo *MongoReactionRepository) CountByArticle(ctx context.Context) (map[string]models.ReactionCounts, error) {
+	pipeline := bson.A{
+		bson.M{"$group": bson.M{
+			"_id":   bson.M{"article_id": "$article_id", "kind": "$kind"},
+			"count": bson.M{"$sum": 1},
+		}},
+	}
+
+	cursor, err := repo.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []struct {
+		Id struct {
+			ArticleId string              `bson:"article_id"`
+			Kind      models.ReactionKind `bson:"kind"`
+		} `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]models.ReactionCounts)
+	for _, group := range groups {
+		if counts[group.Id.ArticleId] == nil {
+			counts[group.Id.ArticleId] = make(models.ReactionCounts)
+		}
+		counts[group.Id.ArticleId][group.Id.Kind] = group.Count
+	}
+
+	return counts, nil
+}
+
+func (repo *MongoReactionRepository) DeleteByArticle(ctx context.Context, articleId string) error {
+	_, err := repo.collection.DeleteMany(ctx, bson.M{"article_id": articleId})
+	return err
+}
@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoReadingListRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoReadingListRepository(db *mongo.Database) *MongoReadingListRepository {
+	return &MongoReadingListRepository{collection: db.Collection("reading_lists")}
+}
+
+func (repo *MongoReadingListRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := repo.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "nickname", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	return err
+}
+
+func (repo *MongoReadingListRepository) Create(ctx context.Context, list *models.ReadingList) error {
+	list.Id = primitive.NewObjectID()
+
+	_, err := repo.collection.InsertOne(ctx, list)
+	return err
+}
+
+func (repo *MongoReadingListRepository) FindById(ctx context.Context, id primitive.ObjectID) (*models.ReadingList, error) {
+	var list models.ReadingList
+	if err := repo.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&list); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+func (repo *MongoReadingListRepository) ListByNickname(ctx context.Context, nickname string) ([]models.ReadingList, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := repo.collection.Find(ctx, bson.M{"nickname": nickname}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var lists []models.ReadingList
+	if err := cursor.All(ctx, &lists); err != nil {
+		return nil, err
+	}
+
+	return lists, nil
+}
+
+func (repo *MongoReadingListRepository) Update(ctx context.Context, list *models.ReadingList) error {
+	result, err := repo.collection.ReplaceOne(ctx, bson.M{"_id": list.Id}, list)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (repo *MongoReadingListRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := repo.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
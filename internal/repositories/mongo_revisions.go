@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoRevisionAddAttempts limits retries when concurrent updates take the same revision number
+const mongoRevisionAddAttempts = 5
+
+type MongoRevisionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoRevisionRepository(db *mongo.Database) *MongoRevisionRepository {
+	return &MongoRevisionRepository{collection: db.Collection("article_revisions")}
+}
+
+// EnsureIndexes makes revision numbers unique per article
+func (repo *MongoRevisionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := repo.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "article_id", Value: 1}, {Key: "number", Value: -1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func (repo *MongoRevisionRepository) Add(ctx context.Context, revision *models.ArticleRevision) error {
+	for attempt := 0; ; attempt++ {
+		last, err := repo.List(ctx, revision.ArticleId, 1, 0)
+		if err != nil {
+			return err
+		}
+
+		revision.Number = 1
+		if len(last) != 0 {
+			revision.Number = last[0].Number + 1
+		}
+
+		_, err = repo.collection.InsertOne(ctx, revision)
+		if err == nil || !isDuplicateKeyError(err) || attempt == mongoRevisionAddAttempts-1 {
+			return err
+		}
+	}
+}
+
+func (repo *MongoRevisionRepository) Find(ctx context.Context, articleId string, number uint) (*models.ArticleRevision, error) {
+	var revision models.ArticleRevision
+	if err := repo.collection.FindOne(ctx, bson.M{"article_id": articleId, "number": number}).Decode(&revision); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+func (repo *MongoRevisionRepository) List(ctx context.Context, articleId string, count uint, offset uint) ([]models.ArticleRevision, error) {
+	findOptions := options.Find()
+	findOptions.SetSort(bson.M{"number": -1})
+	findOptions.SetLimit(int64(count))
+	findOptions.SetSkip(int64(offset))
+
+	cur, err := repo.collection.Find(ctx, bson.M{"article_id": articleId}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var results []models.ArticleRevision
+	if err := cur.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (repo *MongoRevisionRepository) DeleteByArticle(ctx context.Context, articleId string) error {
+	_, err := repo.collection.DeleteMany(ctx, bson.M{"article_id": articleId})
+	return err
+}
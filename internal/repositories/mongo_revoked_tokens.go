@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type MongoRevokedTokenRepository struct {
+	collection      *mongo.Collection
+	usersCollection *mongo.Collection
+}
+
+func NewMongoRevokedTokenRepository(db *mongo.Database) *MongoRevokedTokenRepository {
+	return &MongoRevokedTokenRepository{
+		collection:      db.Collection("revoked_tokens"),
+		usersCollection: db.Collection("revoked_users"),
+	}
+}
+
+// EnsureIndexes makes MongoDB forget revoked tokens once they expire anyway
+func (repo *MongoRevokedTokenRepository) EnsureIndexes(ctx context.Context) error {
+	for _, collection := range []*mongo.Collection{repo.collection, repo.usersCollection} {
+		_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.M{"expires_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (repo *MongoRevokedTokenRepository) Add(ctx context.Context, tokenId string, expiresAt time.Time) error {
+	_, err := repo.collection.UpdateOne(ctx,
+		bson.M{"_id": tokenId},
+		bson.M{"$set": bson.M{"expires_at": expiresAt}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (repo *MongoRevokedTokenRepository) IsRevoked(ctx context.Context, tokenId string) (bool, error) {
+	count, err := repo.collection.CountDocuments(ctx, bson.M{"_id": tokenId}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+
+	return count != 0, nil
+}
+
+func (repo *MongoRevokedTokenRepository) RevokeUser(ctx context.Context, nickname string, issuedBefore time.Time, expiresAt time.Time) error {
+	// An earlier revocation is covered by a later one, but it can't be moved back
+	_, err := repo.usersCollection.UpdateOne(ctx,
+		bson.M{"_id": nickname},
+		bson.M{"$max": bson.M{"issued_before": issuedBefore, "expires_at": expiresAt}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (repo *MongoRevokedTokenRepository) RevokedBefore(ctx context.Context, nickname string) (time.Time, error) {
+	var user struct {
+		IssuedBefore time.Time `bson:"issued_before"`
+		ExpiresAt    time.Time `bson:"expires_at"`
+	}
+
+	err := repo.usersCollection.FindOne(ctx, bson.M{"_id": nickname}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// The TTL monitor runs once a minute, an expired entry can still be there
+	if !user.ExpiresAt.After(time.Now()) {
+		return time.Time{}, nil
+	}
+
+	return user.IssuedBefore, nil
+}
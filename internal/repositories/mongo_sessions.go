@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type MongoSessionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoSessionRepository(db *mongo.Database) *MongoSessionRepository {
+	return &MongoSessionRepository{collection: db.Collection("sessions")}
+}
+
+// EnsureIndexes makes MongoDB remove sessions with expired refresh tokens by itself
+func (repo *MongoSessionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := repo.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"nickname": 1}},
+		{Keys: bson.M{"expires_at": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+func (repo *MongoSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	_, err := repo.collection.InsertOne(ctx, session)
+	if isDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (repo *MongoSessionRepository) FindById(ctx context.Context, id string) (*models.Session, error) {
+	var session models.Session
+	filter := bson.M{"_id": id, "expires_at": bson.M{"$gt": time.Now()}}
+	if err := repo.collection.FindOne(ctx, filter).Decode(&session); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (repo *MongoSessionRepository) ListByNickname(ctx context.Context, nickname string) ([]models.Session, error) {
+	filter := bson.M{"nickname": nickname, "expires_at": bson.M{"$gt": time.Now()}}
+	findOptions := options.Find().SetSort(bson.M{"last_used_at": -1})
+
+	cur, err := repo.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var results []models.Session
+	if err := cur.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (repo *MongoSessionRepository) Rotate(ctx context.Context, id string, oldHash string, newHash string, expiresAt time.Time) error {
+	filter := bson.M{"_id": id, "refresh_hash": oldHash}
+	update := bson.M{"$set": bson.M{
+		"refresh_hash": newHash,
+		"last_used_at": time.Now(),
+		"expires_at":   expiresAt,
+	}}
+
+	result, err := repo.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (repo *MongoSessionRepository) Delete(ctx context.Context, id string) error {
+	result, err := repo.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (repo *MongoSessionRepository) DeleteByNickname(ctx context.Context, nickname string) error {
+	_, err := repo.collection.DeleteMany(ctx, bson.M{"nickname": nickname})
+	return err
+}
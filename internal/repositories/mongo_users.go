@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoUserRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoUserRepository(db *mongo.Database) *MongoUserRepository {
+	return &MongoUserRepository{collection: db.Collection("users")}
+}
+
+// EnsureIndexes makes nicknames unique
+func (repo *MongoUserRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := repo.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "nickname", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return uniqueIndexError(ctx, repo.collection, "nickname", err)
+	}
+
+	return nil
+}
+
+func (repo *MongoUserRepository) Create(ctx context.Context, user *models.User) error {
+	if _, err := repo.collection.InsertOne(ctx, user); err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrDuplicate
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (repo *MongoUserRepository) FindByNickname(ctx context.Context, nickname string) (*models.User, error) {
+	var user models.User
+	if err := repo.collection.FindOne(ctx, bson.M{"nickname": nickname}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (repo *MongoUserRepository) Update(ctx context.Context, user *models.User) error {
+	result, err := repo.collection.UpdateOne(ctx, bson.M{"nickname": user.Nickname}, bson.M{"$set": user})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (repo *MongoUserRepository) Delete(ctx context.Context, nickname string) error {
+	result, err := repo.collection.DeleteOne(ctx, bson.M{"nickname": nickname})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
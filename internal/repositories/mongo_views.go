@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"math"
+	"time"
+)
+
+type MongoViewRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoViewRepository(db *mongo.Database) *MongoViewRepository {
+	return &MongoViewRepository{collection: db.Collection("article_views")}
+}
+
+// EnsureIndexes keeps one document per article and hour and makes MongoDB forget the hours
+// that no period reaches
+func (repo *MongoViewRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := repo.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "article_id", Value: 1}, {Key: "hour", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.M{"hour": 1},
+			Options: options.Index().SetExpireAfterSeconds(int32(models.ViewRetention / time.Second)),
+		},
+	})
+	return err
+}
+
+func (repo *MongoViewRepository) Add(ctx context.Context, hour time.Time, views map[string]int64) error {
+	if len(views) == 0 {
+		return nil
+	}
+
+	var writes []mongo.WriteModel
+	for articleId, count := range views {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"article_id": articleId, "hour": hour}).
+			SetUpdate(bson.M{"$inc": bson.M{"count": count}}).
+			SetUpsert(true))
+	}
+
+	_, err := repo.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (repo *MongoViewRepository) Popular(ctx context.Context, since time.Time, halfLife time.Duration, count uint) ([]ArticleViews, error) {
+	now := time.Now()
+
+	// count * e^(ln 2 * (hour - now) / halfLife), date subtraction gives milliseconds
+	weight := bson.M{"$exp": bson.M{"$multiply": bson.A{
+		math.Ln2,
+		bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{"$hour", now}}, float64(halfLife / time.Millisecond)}},
+	}}}
+
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"hour": bson.M{"$gte": since}}},
+		bson.M{"$group": bson.M{
+			"_id":   "$article_id",
+			"score": bson.M{"$sum": bson.M{"$multiply": bson.A{"$count", weight}}},
+		}},
+		bson.M{"$sort": bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: 1}}},
+	}
+	if count != 0 {
+		pipeline = append(pipeline, bson.M{"$limit": int64(count)})
+	}
+
+	cursor, err := repo.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []ArticleViews
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
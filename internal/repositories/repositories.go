@@ -0,0 +1,310 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/renderer"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound  = errors.New("document not found")
+	ErrDuplicate = errors.New("document already exists")
+	ErrConflict  = errors.New("document was changed concurrently")
+)
+
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	FindByNickname(ctx context.Context, nickname string) (*models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	Delete(ctx context.Context, nickname string) error
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	FindById(ctx context.Context, id string) (*models.Session, error)
+	ListByNickname(ctx context.Context, nickname string) ([]models.Session, error)
+	// Rotate replaces the refresh hash of the session only if it still equals oldHash,
+	// ErrNotFound means that the old refresh token was already used or the session was revoked
+	Rotate(ctx context.Context, id string, oldHash string, newHash string, expiresAt time.Time) error
+	Delete(ctx context.Context, id string) error
+	DeleteByNickname(ctx context.Context, nickname string) error
+}
+
+// RevokedTokenRepository is a denylist of access token ids and session ids, a token only has to be
+// kept until it expires and a session until the last access token issued for it does
+type RevokedTokenRepository interface {
+	Add(ctx context.Context, tokenId string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, tokenId string) (bool, error)
+	// RevokeUser denies the access tokens of the user issued before issuedBefore, the entry only
+	// has to be kept until the last of them expires
+	RevokeUser(ctx context.Context, nickname string, issuedBefore time.Time, expiresAt time.Time) error
+	// RevokedBefore returns the issuedBefore of the user, it's zero if there is none
+	RevokedBefore(ctx context.Context, nickname string) (time.Time, error)
+}
+
+// prefixMatchesMaxLimit bounds the fallback of ArticleRepository.Search, a short prefix matches
+// a good part of the articles
+const prefixMatchesMaxLimit uint = 100
+
+// ArticleRepository ignores soft-deleted articles unless a method says otherwise
+type ArticleRepository interface {
+	// Create returns ErrDuplicate if the custom id is taken, even by a soft-deleted article
+	Create(ctx context.Context, article *models.Article) error
+	FindByCustomId(ctx context.Context, customId string) (*models.Article, error)
+	FindByName(ctx context.Context, name string) (*models.Article, error)
+	// FindDeletedByCustomId only finds soft-deleted articles
+	FindDeletedByCustomId(ctx context.Context, customId string) (*models.Article, error)
+	// Search returns live articles matching the filter, the best matches of the query on the name
+	// and the content first and the ties by custom id. If none match, names starting with the query
+	// are returned with score 0, at most 100 of them. The articles may only have custom_id, author,
+	// tags and category. count == 0 means no limit
+	Search(ctx context.Context, query string, filter ArticleFilter, count uint, offset uint) ([]ArticleSearchResult, error)
+	// FindByCustomIds returns the live articles with the custom ids in no particular order, the missing
+	// ones are skipped
+	FindByCustomIds(ctx context.Context, customIds []string) ([]models.Article, error)
+	// List returns articles matching the filter in the order of filter.Sort, count == 0 means no limit
+	List(ctx context.Context, filter ArticleFilter, count uint, offset uint) ([]models.Article, error)
+	// Count returns the number of articles matching the filter
+	Count(ctx context.Context, filter ArticleFilter) (int64, error)
+	// PopularTags returns the tags of the articles matching the filter, the most used first
+	PopularTags(ctx context.Context, filter ArticleFilter, count uint) ([]TagCount, error)
+	// Update saves the article only if its stored version still equals article.Version and increments
+	// the version, ErrConflict means that someone else has updated the article in the meantime
+	Update(ctx context.Context, article *models.Article) error
+	SoftDelete(ctx context.Context, customId string, deletedAt time.Time) error
+	Restore(ctx context.Context, customId string) error
+	// PublishDue publishes approved articles whose publish_at has come and returns their custom ids
+	PublishDue(ctx context.Context, now time.Time) ([]string, error)
+	// AddViews increments view_count of the articles by custom id, the missing ones are skipped
+	AddViews(ctx context.Context, views map[string]int64) error
+	// AddReaction changes the count of the reactions of the kind by delta
+	AddReaction(ctx context.Context, customId string, kind models.ReactionKind, delta int64) error
+	// ReactionCounts returns the reaction counts of the articles with any, soft-deleted ones included
+	ReactionCounts(ctx context.Context) (map[string]models.ReactionCounts, error)
+	// SetReactions replaces the reaction counts, even of a soft-deleted article
+	SetReactions(ctx context.Context, customId string, counts models.ReactionCounts) error
+	// AddComments changes the comment count by delta
+	AddComments(ctx context.Context, customId string, delta int64) error
+	// LockComments forbids or allows new comments on the article
+	LockComments(ctx context.Context, customId string, locked bool) error
+	// PurgeDeleted removes articles soft-deleted before the given time for good and returns their custom ids
+	PurgeDeleted(ctx context.Context, before time.Time) ([]string, error)
+	Delete(ctx context.Context, customId string) error
+}
+
+type ArticleFilter struct {
+	Author  string // Empty string matches any author
+	IsDraft *bool  // nil matches both drafts and published articles
+	Deleted bool   // true lists the trash instead of live articles
+	Tag     string // Empty string matches any tag
+	// Empty matches any category, otherwise the category must be one of these
+	Categories []string
+	// nil matches any publish_at, otherwise publish_at must be unset or not later than the time
+	PublishedBy *time.Time
+	// Bounds of published_at, the start is inclusive and the end exclusive, nil means unbounded
+	PublishedFrom *time.Time
+	PublishedTo   *time.Time
+	HasThumbnail  *bool // nil matches articles with and without a thumbnail
+	// Sort is the List order, empty means models.SortNewest
+	Sort models.ArticleSort
+	// nil matches any article, otherwise only the articles following the position in the List order,
+	// the position must be taken in the same order
+	After *ArticlePosition
+}
+
+// ArticlePosition is the place of an article in the List order
+type ArticlePosition struct {
+	Sort        models.ArticleSort
+	PublishedAt *time.Time
+	ViewCount   int64
+	Name        string
+	Id          primitive.ObjectID
+}
+
+func PositionOf(article *models.Article, sort models.ArticleSort) ArticlePosition {
+	return ArticlePosition{
+		Sort:        sort,
+		PublishedAt: article.PublishedAt,
+		ViewCount:   article.ViewCount,
+		Name:        article.Name,
+		Id:          article.Id,
+	}
+}
+
+// descending tells if the sort puts greater values first, ties are broken by _id in the same direction
+func descending(sort models.ArticleSort) bool {
+	return sort != models.SortOldest && sort != models.SortName
+}
+
+// Follows tells if the position comes after the other one in the List order
+func (position ArticlePosition) Follows(other ArticlePosition) bool {
+	order := position.compare(other)
+	if order == 0 {
+		order = bytes.Compare(position.Id[:], other.Id[:])
+	}
+
+	if descending(position.Sort) {
+		return order < 0
+	}
+	return order > 0
+}
+
+// compare compares the sort values in the ascending order, a missing publication date is the lowest
+func (position ArticlePosition) compare(other ArticlePosition) int {
+	switch position.Sort {
+	case models.SortPopularity:
+		if position.ViewCount != other.ViewCount {
+			if position.ViewCount < other.ViewCount {
+				return -1
+			}
+			return 1
+		}
+		return 0
+	case models.SortName:
+		return strings.Compare(position.Name, other.Name)
+	}
+
+	if position.PublishedAt == nil || other.PublishedAt == nil {
+		if (position.PublishedAt == nil) == (other.PublishedAt == nil) {
+			return 0
+		}
+		if position.PublishedAt == nil {
+			return -1
+		}
+		return 1
+	}
+	if position.PublishedAt.Before(*other.PublishedAt) {
+		return -1
+	}
+	if position.PublishedAt.After(*other.PublishedAt) {
+		return 1
+	}
+	return 0
+}
+
+type ArticleSearchResult struct {
+	Article models.Article `bson:",inline"`
+	Score   float64        `bson:"score"`
+}
+
+type TagCount struct {
+	Tag   string `bson:"_id"`
+	Count int    `bson:"count"`
+}
+
+func Bool(value bool) *bool {
+	return &value
+}
+
+type RevisionRepository interface {
+	// Add assigns the next revision number of the article to the revision
+	Add(ctx context.Context, revision *models.ArticleRevision) error
+	Find(ctx context.Context, articleId string, number uint) (*models.ArticleRevision, error)
+	// List returns revisions of the article newest first, count == 0 means no limit
+	List(ctx context.Context, articleId string, count uint, offset uint) ([]models.ArticleRevision, error)
+	DeleteByArticle(ctx context.Context, articleId string) error
+}
+
+type CategoryRepository interface {
+	// Create returns ErrDuplicate if the slug is taken
+	Create(ctx context.Context, category *models.Category) error
+	FindBySlug(ctx context.Context, slug string) (*models.Category, error)
+	// List returns all the categories, the tree is small enough to be built in memory
+	List(ctx context.Context) ([]models.Category, error)
+	Update(ctx context.Context, category *models.Category) error
+	Delete(ctx context.Context, slug string) error
+}
+
+// ViewRepository keeps hourly view counts of the articles for models.ViewRetention
+type ViewRepository interface {
+	// Add increments the counts of the hour by the views per article custom id
+	Add(ctx context.Context, hour time.Time, views map[string]int64) error
+	// Popular returns the articles viewed since the given time, the highest score first. Every view
+	// counts for 0.5^(age/halfLife), count == 0 means no limit
+	Popular(ctx context.Context, since time.Time, halfLife time.Duration, count uint) ([]ArticleViews, error)
+}
+
+type ArticleViews struct {
+	ArticleId string  `bson:"_id"`
+	Score     float64 `bson:"score"`
+}
+
+type ReactionRepository interface {
+	// Add returns false if the user has already reacted to the article with the kind
+	Add(ctx context.Context, reaction *models.Reaction) (bool, error)
+	// Remove returns false if the user hasn't reacted to the article with the kind
+	Remove(ctx context.Context, articleId string, nickname string, kind models.ReactionKind) (bool, error)
+	// Kinds returns the kinds the user has reacted to the article with
+	Kinds(ctx context.Context, articleId string, nickname string) ([]models.ReactionKind, error)
+	// ListByNickname returns the reactions of the user newest first, empty kind matches any kind,
+	// count == 0 means no limit
+	ListByNickname(ctx context.Context, nickname string, kind models.ReactionKind, count uint, offset uint) ([]models.Reaction, error)
+	// CountByArticle returns the reaction counts of every article anyone reacted to
+	CountByArticle(ctx context.Context) (map[string]models.ReactionCounts, error)
+	DeleteByArticle(ctx context.Context, articleId string) error
+}
+
+type CommentRepository interface {
+	// Create assigns an id to the comment
+	Create(ctx context.Context, comment *models.Comment) error
+	FindById(ctx context.Context, id primitive.ObjectID) (*models.Comment, error)
+	Update(ctx context.Context, comment *models.Comment) error
+	// ListChildren returns the replies of the parent, or the top-level comments of the article if it is nil,
+	// pinned first and then oldest first, count == 0 means no limit
+	ListChildren(ctx context.Context, articleId string, parentId *primitive.ObjectID, count uint, offset uint) ([]models.Comment, error)
+	CountChildren(ctx context.Context, articleId string, parentId *primitive.ObjectID) (int64, error)
+	// ListDescendants returns the replies at any depth below the comments, oldest first
+	ListDescendants(ctx context.Context, ids []primitive.ObjectID) ([]models.Comment, error)
+	DeleteByArticle(ctx context.Context, articleId string) error
+}
+
+// BookmarkRepository keeps the bookmarks of the users, listId == nil means the plain bookmarks
+type BookmarkRepository interface {
+	// Add puts the bookmark at the end of its list, it returns false if the article is already there
+	Add(ctx context.Context, bookmark *models.Bookmark) (bool, error)
+	// Remove returns false if the article isn't in the list
+	Remove(ctx context.Context, nickname string, listId *primitive.ObjectID, articleId string) (bool, error)
+	// List returns the bookmarks of the list in the order set by the user, count == 0 means no limit
+	List(ctx context.Context, nickname string, listId *primitive.ObjectID, count uint, offset uint) ([]models.Bookmark, error)
+	Count(ctx context.Context, nickname string, listId *primitive.ObjectID) (int64, error)
+	// Reorder puts the articles of the list in the given order
+	Reorder(ctx context.Context, nickname string, listId *primitive.ObjectID, articleIds []string) error
+	DeleteByList(ctx context.Context, nickname string, listId primitive.ObjectID) error
+	// DeleteByArticle removes the article from the bookmarks and the reading lists of every user
+	DeleteByArticle(ctx context.Context, articleId string) error
+}
+
+type ReadingListRepository interface {
+	// Create assigns an id to the list
+	Create(ctx context.Context, list *models.ReadingList) error
+	FindById(ctx context.Context, id primitive.ObjectID) (*models.ReadingList, error)
+	// ListByNickname returns the lists of the user oldest first
+	ListByNickname(ctx context.Context, nickname string) ([]models.ReadingList, error)
+	Update(ctx context.Context, list *models.ReadingList) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// LeaseRepository lets only one of several replicas run a background job at a time
+type LeaseRepository interface {
+	// Acquire takes or extends the lease for ttl, it returns false while another holder has it
+	Acquire(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error)
+}
+
+// searchText is the plain text the articles are searched by besides the name
+func searchText(article *models.Article) string {
+	text, _ := renderer.Render(renderer.FormatText, article.ArticleData)
+	return text
+}
+
+func prefixLimit(count uint) uint {
+	if count == 0 || count > prefixMatchesMaxLimit {
+		return prefixMatchesMaxLimit
+	}
+
+	return count
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/search"
+	"time"
+)
+
+// searchMaxMatches bounds the matches read for a query. The total and the facets only count these,
+// the best ones, and the pages past them are empty.
+const searchMaxMatches = 1000
+
+// RepositorySearchIndex searches with ArticleRepository.Search, so the articles are indexed
+// by the storage itself and Index and Delete have nothing to do
+type RepositorySearchIndex struct {
+	articles ArticleRepository
+}
+
+func NewRepositorySearchIndex(articles ArticleRepository) *RepositorySearchIndex {
+	return &RepositorySearchIndex{articles: articles}
+}
+
+func (index *RepositorySearchIndex) Index(ctx context.Context, document search.Document) error {
+	return nil
+}
+
+func (index *RepositorySearchIndex) Delete(ctx context.Context, id string) error {
+	return nil
+}
+
+// Search ignores Fuzzy, the storage has no fuzzy matching
+func (index *RepositorySearchIndex) Search(ctx context.Context, query search.Query) (*search.Results, error) {
+	now := time.Now()
+	filter := ArticleFilter{
+		Author:      query.Author,
+		IsDraft:     Bool(false),
+		Tag:         query.Tag,
+		Categories:  query.Categories,
+		PublishedBy: &now,
+	}
+
+	// The total, the facets and the position of a cursor need all the matches, a page alone only
+	// the ones up to it and one more to tell if there are more
+	limit := uint(searchMaxMatches)
+	if !query.WithTotals && query.After == nil && query.Count != 0 && query.Offset+query.Count < limit {
+		limit = query.Offset + query.Count + 1
+	}
+
+	found, err := index.articles.Search(ctx, query.Text, filter, limit, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []search.Hit
+	counter := search.FacetCounter{}
+	for i := range found {
+		document := SearchDocument(&found[i].Article)
+		if !query.Matches(document) {
+			continue
+		}
+
+		hits = append(hits, search.Hit{Id: document.Id, Score: found[i].Score})
+		if query.WithTotals {
+			counter.Add(document)
+		}
+	}
+
+	// The storage already sorts them like this, except for the prefix matches
+	search.SortHits(hits)
+	page, more := search.Page(hits, query)
+
+	results := &search.Results{Hits: page, More: more}
+	if query.WithTotals {
+		results.Total = len(hits)
+		results.Facets = counter.Facets()
+	}
+
+	return results, nil
+}
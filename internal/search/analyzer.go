@@ -0,0 +1,70 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Analyze turns the text into index terms: lowercase words without stop words, Cyrillic ones
+// stemmed by the Russian stemmer and Latin ones by the English one. Other words, including
+// those mixing scripts or digits, are kept as they are.
+func Analyze(text string) []string {
+	var terms []string
+
+	for _, w := range words([]rune(text)) {
+		term := strings.Replace(w.text, "ё", "е", -1)
+		if stopWords[term] {
+			continue
+		}
+
+		switch script(term) {
+		case unicode.Cyrillic:
+			term = stemRussian(term)
+		case unicode.Latin:
+			term = stemEnglish(term)
+		}
+
+		terms = append(terms, term)
+	}
+
+	return terms
+}
+
+// script returns the table all the runes of the word belong to, or nil if there are several.
+// Only letters a-z are Latin here, the English stemmer knows nothing about diacritics.
+func script(w string) *unicode.RangeTable {
+	var table *unicode.RangeTable
+
+	for _, r := range w {
+		var current *unicode.RangeTable
+		if r >= 'a' && r <= 'z' {
+			current = unicode.Latin
+		} else if r >= 'а' && r <= 'я' {
+			current = unicode.Cyrillic
+		} else {
+			return nil
+		}
+
+		if table != nil && table != current {
+			return nil
+		}
+		table = current
+	}
+
+	return table
+}
+
+var stopWords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		a an and are as at be but by for from has have if in into is it its of on or
+		that the their there these they this to was were will with
+		а без бы был была были было быть в вам вас во вот все всё вы где да для до его
+		ее если есть еще же за и из или им их к как ко когда кто ли либо мне мы на над
+		не него нет ни но о об однако он она они оно от по под при с со так также то
+		того тоже только у уже чем что чтобы эта эти это этот я
+	`) {
+		stopWords[strings.Replace(w, "ё", "е", -1)] = true
+	}
+}
@@ -0,0 +1,189 @@
+package search
+
+import (
+	"context"
+	"sort"
+)
+
+// SearchIndex finds published articles, implementations are safe for concurrent use
+type SearchIndex interface {
+	// Index adds the document or replaces the one with the same id
+	Index(ctx context.Context, document Document) error
+	Delete(ctx context.Context, id string) error
+	Search(ctx context.Context, query Query) (*Results, error)
+}
+
+// Document is what an index knows about an article
+type Document struct {
+	Id       string   `json:"id"`
+	Name     string   `json:"name"`
+	Text     string   `json:"text"`
+	Author   string   `json:"author"`
+	Tags     []string `json:"tags,omitempty"`
+	Category string   `json:"category,omitempty"`
+}
+
+type Query struct {
+	Text   string
+	Fuzzy  bool   // Also match words a typo or two away from the query words
+	Author string // Empty string matches any author
+	Tag    string // Empty string matches any tag
+	// Empty matches any category, otherwise the category must be one of these
+	Categories []string
+	Count      uint // 0 means no limit
+	Offset     uint
+	// Counts all the matches and their facets, without it the index may only read the matches
+	// up to the page
+	WithTotals bool
+	// Starts the page right after this hit instead of at the offset, so the matches added or
+	// removed before it don't shift the page
+	After *Hit
+}
+
+type Hit struct {
+	Id    string
+	Score float64
+}
+
+type Results struct {
+	Hits []Hit // The requested page, the best matches first
+	More bool  // There are matches after the page
+	// Number of all matches, an index may only count the best ones. Only set with WithTotals.
+	Total int
+	// Values of the "author" and "tag" fields among all matches, the most frequent first. Only set
+	// with WithTotals.
+	Facets map[string][]Facet
+}
+
+type Facet struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+const (
+	FacetAuthor = "author"
+	FacetTag    = "tag"
+)
+
+// maxFacetValues keeps the facets of broad queries short
+const maxFacetValues = 20
+
+// FacetCounter collects the facets of the matching documents
+type FacetCounter map[string]map[string]int
+
+func (counter FacetCounter) Add(document Document) {
+	counter.add(FacetAuthor, document.Author)
+	for _, tag := range document.Tags {
+		counter.add(FacetTag, tag)
+	}
+}
+
+func (counter FacetCounter) add(field string, value string) {
+	if value == "" {
+		return
+	}
+	if counter[field] == nil {
+		counter[field] = map[string]int{}
+	}
+	counter[field][value]++
+}
+
+func (counter FacetCounter) Facets() map[string][]Facet {
+	facets := map[string][]Facet{FacetAuthor: {}, FacetTag: {}}
+
+	for field, values := range counter {
+		list := []Facet{}
+		for value, count := range values {
+			list = append(list, Facet{Value: value, Count: count})
+		}
+
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Count != list[j].Count {
+				return list[i].Count > list[j].Count
+			}
+			return list[i].Value < list[j].Value
+		})
+		if len(list) > maxFacetValues {
+			list = list[:maxFacetValues]
+		}
+
+		facets[field] = list
+	}
+
+	return facets
+}
+
+// SortHits puts the best matches first, the ties are broken by the id so that a hit has the same
+// position in every search
+func SortHits(hits []Hit) {
+	sort.Slice(hits, func(i, j int) bool {
+		return hits[i].Before(hits[j])
+	})
+}
+
+// Before tells if the hit goes before the other one in the order of SortHits
+func (hit Hit) Before(other Hit) bool {
+	if hit.Score != other.Score {
+		return hit.Score > other.Score
+	}
+	return hit.Id < other.Id
+}
+
+// Page slices the hits of all matches sorted by SortHits by the count and the offset or the
+// position of the query, it also tells if there are hits after the page
+func Page(hits []Hit, query Query) ([]Hit, bool) {
+	start := int(query.Offset)
+	if query.After != nil {
+		start = position(hits, *query.After)
+	}
+
+	if start >= len(hits) {
+		return []Hit{}, false
+	}
+
+	hits = hits[start:]
+	if query.Count != 0 && uint(len(hits)) > query.Count {
+		return hits[:query.Count], true
+	}
+
+	return hits, false
+}
+
+// position returns the index of the first hit after the given one. Scores shift as the index
+// changes, so the hit is looked up by its id first and only a removed one is placed by its score.
+func position(hits []Hit, after Hit) int {
+	for i, hit := range hits {
+		if hit.Id == after.Id {
+			return i + 1
+		}
+	}
+
+	return sort.Search(len(hits), func(i int) bool {
+		return after.Before(hits[i])
+	})
+}
+
+// Matches tells if the document passes the author, tag and category filters of the query
+func (query Query) Matches(document Document) bool {
+	if query.Author != "" && document.Author != query.Author {
+		return false
+	}
+	if query.Tag != "" && !contains(document.Tags, query.Tag) {
+		return false
+	}
+	if len(query.Categories) != 0 && !contains(query.Categories, document.Category) {
+		return false
+	}
+
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
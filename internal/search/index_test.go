@@ -0,0 +1,43 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPage(t *testing.T) {
+	hits := []Hit{{Id: "c", Score: 1}, {Id: "a", Score: 2}, {Id: "b", Score: 1}, {Id: "d", Score: 0.5}}
+	SortHits(hits)
+
+	tests := []struct {
+		name     string
+		query    Query
+		wantIds  []string
+		wantMore bool
+	}{
+		{"all", Query{}, []string{"a", "b", "c", "d"}, false},
+		{"first page", Query{Count: 2}, []string{"a", "b"}, true},
+		{"offset", Query{Count: 2, Offset: 2}, []string{"c", "d"}, false},
+		{"offset past the end", Query{Offset: 4}, []string{}, false},
+		{"after a hit", Query{Count: 2, After: &Hit{Id: "a", Score: 2}}, []string{"b", "c"}, true},
+		{"after a tie", Query{Count: 2, After: &Hit{Id: "b", Score: 1}}, []string{"c", "d"}, false},
+		// The hit of the cursor is gone, the page starts where it was
+		{"after a removed hit", Query{After: &Hit{Id: "bb", Score: 1}}, []string{"c", "d"}, false},
+		{"after a hit whose score changed", Query{After: &Hit{Id: "b", Score: 3}}, []string{"c", "d"}, false},
+		{"after the last hit", Query{After: &Hit{Id: "d", Score: 0.5}}, []string{}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			page, more := Page(hits, test.query)
+
+			ids := []string{}
+			for _, hit := range page {
+				ids = append(ids, hit.Id)
+			}
+			if !reflect.DeepEqual(ids, test.wantIds) || more != test.wantMore {
+				t.Errorf("got %v and more %v, want %v and %v", ids, more, test.wantIds, test.wantMore)
+			}
+		})
+	}
+}
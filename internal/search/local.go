@@ -0,0 +1,448 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// BM25 parameters and field weights, a match in the name is worth three in the text
+const (
+	bm25K1     = 1.2
+	bm25B      = 0.75
+	nameWeight = 3.0
+	textWeight = 1.0
+	fuzzyBoost = 0.5 // Words matched with a typo count for less than exact ones
+)
+
+const journalName = "journal.jsonl"
+
+// LocalIndex is an embedded inverted index kept in memory and persisted as a journal of changes
+// in a directory. Only one process may open the directory at a time, so the index suits
+// deployments with a single webapi instance.
+type LocalIndex struct {
+	mutex     sync.RWMutex
+	path      string
+	journal   *os.File
+	entries   int // Lines in the journal, it is compacted when they outnumber the documents a lot
+	documents map[string]*localDocument
+	postings  map[string]map[string]*posting // Term to document id
+	// Total numbers of terms in the names and the texts, for the average lengths of BM25
+	nameLength int
+	textLength int
+}
+
+type localDocument struct {
+	Document
+	nameLength int
+	textLength int
+	terms      []string // Distinct terms, to remove the document from the postings
+}
+
+// posting counts the occurrences of a term in a document
+type posting struct {
+	name int
+	text int
+}
+
+type journalEntry struct {
+	Op       string    `json:"op"` // "put" or "delete"
+	Id       string    `json:"id,omitempty"`
+	Document *Document `json:"document,omitempty"`
+}
+
+// OpenLocalIndex loads the index from the directory, creating it if needed
+func OpenLocalIndex(path string) (*LocalIndex, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+
+	index := &LocalIndex{
+		path:      path,
+		documents: map[string]*localDocument{},
+		postings:  map[string]map[string]*posting{},
+	}
+
+	truncated, err := index.replay()
+	if err != nil {
+		return nil, err
+	}
+
+	// The broken line must not stay in the middle of the journal once new lines are appended
+	if truncated {
+		err = index.compact()
+	} else {
+		index.journal, err = os.OpenFile(index.journalPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+func (index *LocalIndex) Close() error {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	return index.journal.Close()
+}
+
+func (index *LocalIndex) IsEmpty() bool {
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+
+	return len(index.documents) == 0
+}
+
+func (index *LocalIndex) Index(ctx context.Context, document Document) error {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	if err := index.write(journalEntry{Op: "put", Document: &document}); err != nil {
+		return err
+	}
+
+	index.put(document)
+	return index.compactIfNeeded()
+}
+
+func (index *LocalIndex) Delete(ctx context.Context, id string) error {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	if _, ok := index.documents[id]; !ok {
+		return nil
+	}
+
+	if err := index.write(journalEntry{Op: "delete", Id: id}); err != nil {
+		return err
+	}
+
+	index.remove(id)
+	return index.compactIfNeeded()
+}
+
+// Reset replaces all the documents of the index, it is used to rebuild the index from the articles
+func (index *LocalIndex) Reset(documents []Document) error {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	index.documents = map[string]*localDocument{}
+	index.postings = map[string]map[string]*posting{}
+	index.nameLength, index.textLength = 0, 0
+
+	for _, document := range documents {
+		index.put(document)
+	}
+
+	return index.compact()
+}
+
+// Search matches documents having any of the query terms and ranks them by BM25
+func (index *LocalIndex) Search(ctx context.Context, query Query) (*Results, error) {
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+
+	scores := map[string]float64{}
+	for term, boost := range index.expand(Analyze(query.Text), query.Fuzzy) {
+		index.score(term, boost, scores)
+	}
+
+	var hits []Hit
+	counter := FacetCounter{}
+	for id, score := range scores {
+		document := index.documents[id].Document
+		if !query.Matches(document) {
+			continue
+		}
+
+		hits = append(hits, Hit{Id: id, Score: score})
+		if query.WithTotals {
+			counter.Add(document)
+		}
+	}
+
+	SortHits(hits)
+	page, more := Page(hits, query)
+
+	results := &Results{Hits: page, More: more}
+	if query.WithTotals {
+		results.Total = len(hits)
+		results.Facets = counter.Facets()
+	}
+
+	return results, nil
+}
+
+// expand maps the terms to the indexed terms they match and the boosts of the matches
+func (index *LocalIndex) expand(terms []string, fuzzy bool) map[string]float64 {
+	expanded := map[string]float64{}
+
+	for _, term := range terms {
+		expanded[term] = 1
+
+		distance := maxTypos(term)
+		if !fuzzy || distance == 0 {
+			continue
+		}
+
+		for indexed := range index.postings {
+			if indexed == term || expanded[indexed] != 0 {
+				continue
+			}
+			if withinDistance([]rune(term), []rune(indexed), distance) {
+				expanded[indexed] = fuzzyBoost
+			}
+		}
+	}
+
+	return expanded
+}
+
+// maxTypos is the edit distance fuzzy matching allows, short words would match too much otherwise
+func maxTypos(term string) int {
+	length := len([]rune(term))
+	if length >= 8 {
+		return 2
+	}
+	if length >= 5 {
+		return 1
+	}
+	return 0
+}
+
+func (index *LocalIndex) score(term string, boost float64, scores map[string]float64) {
+	documents := index.postings[term]
+	if len(documents) == 0 {
+		return
+	}
+
+	count := float64(len(index.documents))
+	idf := math.Log(1 + (count-float64(len(documents))+0.5)/(float64(len(documents))+0.5))
+	averageName := float64(index.nameLength) / count
+	averageText := float64(index.textLength) / count
+
+	for id, p := range documents {
+		document := index.documents[id]
+		score := nameWeight*bm25(p.name, document.nameLength, averageName) + textWeight*bm25(p.text, document.textLength, averageText)
+		scores[id] += boost * idf * score
+	}
+}
+
+func bm25(frequency int, length int, averageLength float64) float64 {
+	if frequency == 0 || averageLength == 0 {
+		return 0
+	}
+
+	f := float64(frequency)
+	return f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*float64(length)/averageLength))
+}
+
+// withinDistance tells if the Levenshtein distance between the words is at most max
+func withinDistance(a []rune, b []rune, max int) bool {
+	if len(a)-len(b) > max || len(b)-len(a) > max {
+		return false
+	}
+
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		best := current[0]
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+			if current[j] < best {
+				best = current[j]
+			}
+		}
+
+		if best > max {
+			return false
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)] <= max
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// put must be called with the mutex held
+func (index *LocalIndex) put(document Document) {
+	index.remove(document.Id)
+
+	stored := &localDocument{Document: document}
+	frequencies := map[string]*posting{}
+
+	for _, term := range Analyze(document.Name) {
+		if frequencies[term] == nil {
+			frequencies[term] = &posting{}
+		}
+		frequencies[term].name++
+		stored.nameLength++
+	}
+	for _, term := range Analyze(document.Text) {
+		if frequencies[term] == nil {
+			frequencies[term] = &posting{}
+		}
+		frequencies[term].text++
+		stored.textLength++
+	}
+
+	for term, p := range frequencies {
+		if index.postings[term] == nil {
+			index.postings[term] = map[string]*posting{}
+		}
+		index.postings[term][document.Id] = p
+		stored.terms = append(stored.terms, term)
+	}
+
+	index.documents[document.Id] = stored
+	index.nameLength += stored.nameLength
+	index.textLength += stored.textLength
+}
+
+// remove must be called with the mutex held
+func (index *LocalIndex) remove(id string) {
+	stored, ok := index.documents[id]
+	if !ok {
+		return
+	}
+
+	for _, term := range stored.terms {
+		delete(index.postings[term], id)
+		if len(index.postings[term]) == 0 {
+			delete(index.postings, term)
+		}
+	}
+
+	delete(index.documents, id)
+	index.nameLength -= stored.nameLength
+	index.textLength -= stored.textLength
+}
+
+func (index *LocalIndex) journalPath() string {
+	return filepath.Join(index.path, journalName)
+}
+
+// replay applies the journal, a broken last line is left by a crash in the middle of a write
+// and is ignored, truncated tells if there was one
+func (index *LocalIndex) replay() (truncated bool, err error) {
+	file, err := os.Open(index.journalPath())
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64*1024*1024)
+
+	var broken error
+	for scanner.Scan() {
+		if broken != nil {
+			return false, broken
+		}
+
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			broken = fmt.Errorf("broken search index journal line %d: %v", index.entries+1, err)
+			continue
+		}
+
+		if entry.Op == "put" && entry.Document != nil {
+			index.put(*entry.Document)
+		} else if entry.Op == "delete" {
+			index.remove(entry.Id)
+		}
+		index.entries++
+	}
+
+	return broken != nil, scanner.Err()
+}
+
+// write must be called with the mutex held
+func (index *LocalIndex) write(entry journalEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if _, err := index.journal.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	index.entries++
+
+	return index.journal.Sync()
+}
+
+func (index *LocalIndex) compactIfNeeded() error {
+	if index.entries > 2*len(index.documents)+1000 {
+		return index.compact()
+	}
+	return nil
+}
+
+// compact rewrites the journal with a put per document, the old journal is replaced atomically
+func (index *LocalIndex) compact() error {
+	temporary := index.journalPath() + ".tmp"
+	file, err := os.Create(temporary)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, stored := range index.documents {
+		if err := encoder.Encode(journalEntry{Op: "put", Document: &stored.Document}); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(temporary, index.journalPath()); err != nil {
+		return err
+	}
+
+	if index.journal != nil {
+		index.journal.Close()
+	}
+	index.journal, err = os.OpenFile(index.journalPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	index.entries = len(index.documents)
+	return err
+}
@@ -0,0 +1,145 @@
+// Package search holds the text helpers shared by the article search implementations.
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+type word struct {
+	start, end int // Rune offsets
+	text       string
+}
+
+// Terms splits the query into lowercase words
+func Terms(query string) []string {
+	var terms []string
+
+	for _, w := range words([]rune(query)) {
+		terms = append(terms, w.text)
+	}
+
+	return terms
+}
+
+// Matches tells if the word matches one of the terms, a term matches words with the same beginning
+// so "articles" and "article" match each other like they do in a stemmed index. Short terms
+// only match whole words, otherwise "a" would match everything.
+func Matches(w string, terms []string) bool {
+	for _, term := range terms {
+		prefix := []rune(term)
+		if len(prefix) < 4 {
+			if w == term {
+				return true
+			}
+			continue
+		}
+		if len(prefix) > 5 {
+			prefix = prefix[:len(prefix)-2]
+		}
+
+		if strings.HasPrefix(w, string(prefix)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Count returns how many words of the text match the terms
+func Count(text string, terms []string) int {
+	count := 0
+
+	for _, w := range words([]rune(text)) {
+		if Matches(w.text, terms) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Snippet returns about length runes of the text around the first match, HTML-escaped,
+// with the matching words wrapped in <mark>
+func Snippet(text string, terms []string, length int) string {
+	runes := []rune(text)
+	all := words(runes)
+
+	var matched []word
+	for _, w := range all {
+		if Matches(w.text, terms) {
+			matched = append(matched, w)
+		}
+	}
+
+	start := 0
+	if len(matched) != 0 {
+		start = matched[0].start - length/3
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + length
+	if end > len(runes) {
+		end = len(runes)
+		if start = end - length; start < 0 {
+			start = 0
+		}
+	}
+
+	// Don't cut words in half
+	for start > 0 && isWordRune(runes[start-1]) && isWordRune(runes[start]) {
+		start++
+	}
+	for end < len(runes) && end > start && isWordRune(runes[end-1]) && isWordRune(runes[end]) {
+		end--
+	}
+
+	var out strings.Builder
+	if start > 0 {
+		out.WriteString("…")
+	}
+
+	position := start
+	for _, w := range matched {
+		if w.start < start || w.end > end {
+			continue
+		}
+		out.WriteString(html.EscapeString(string(runes[position:w.start])))
+		out.WriteString("<mark>" + html.EscapeString(string(runes[w.start:w.end])) + "</mark>")
+		position = w.end
+	}
+	out.WriteString(html.EscapeString(string(runes[position:end])))
+
+	if end < len(runes) {
+		out.WriteString("…")
+	}
+
+	return strings.Join(strings.Fields(out.String()), " ")
+}
+
+func words(runes []rune) []word {
+	var result []word
+
+	start := -1
+	for i := 0; i <= len(runes); i++ {
+		if i < len(runes) && isWordRune(runes[i]) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+
+		if start >= 0 {
+			result = append(result, word{start: start, end: i, text: strings.ToLower(string(runes[start:i]))})
+			start = -1
+		}
+	}
+
+	return result
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,263 @@
+package search
+
+// stemEnglish is the Porter stemming algorithm, the word must be lowercase ASCII letters.
+// See https://tartarus.org/martin/PorterStemmer/def.txt
+func stemEnglish(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+
+	s := &porter{b: []byte(word)}
+	s.step1ab()
+	if len(s.b) > 1 {
+		s.step1c()
+		s.step2()
+		s.step3()
+		s.step4()
+		s.step5()
+	}
+
+	return string(s.b)
+}
+
+// porter keeps the word in b, its end k is always len(b) - 1, j marks the stem end found by ends
+type porter struct {
+	b []byte
+	j int
+}
+
+func (s *porter) k() int {
+	return len(s.b) - 1
+}
+
+func (s *porter) cons(i int) bool {
+	switch s.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !s.cons(i-1)
+	}
+
+	return true
+}
+
+// m measures the number of consonant-vowel sequences in b[0..j]
+func (s *porter) m() int {
+	n, i := 0, 0
+
+	for {
+		if i > s.j {
+			return n
+		}
+		if !s.cons(i) {
+			break
+		}
+		i++
+	}
+	i++
+
+	for {
+		for {
+			if i > s.j {
+				return n
+			}
+			if s.cons(i) {
+				break
+			}
+			i++
+		}
+		i++
+		n++
+
+		for {
+			if i > s.j {
+				return n
+			}
+			if !s.cons(i) {
+				break
+			}
+			i++
+		}
+		i++
+	}
+}
+
+func (s *porter) vowelInStem() bool {
+	for i := 0; i <= s.j; i++ {
+		if !s.cons(i) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *porter) doubleC(j int) bool {
+	return j >= 1 && s.b[j] == s.b[j-1] && s.cons(j)
+}
+
+// cvc tells if b[i-2..i] is consonant-vowel-consonant and the last one isn't w, x or y
+func (s *porter) cvc(i int) bool {
+	if i < 2 || !s.cons(i) || s.cons(i-1) || !s.cons(i-2) {
+		return false
+	}
+
+	c := s.b[i]
+	return c != 'w' && c != 'x' && c != 'y'
+}
+
+func (s *porter) ends(suffix string) bool {
+	k := s.k()
+	if len(suffix) > k+1 || string(s.b[k+1-len(suffix):]) != suffix {
+		return false
+	}
+
+	s.j = k - len(suffix)
+	return true
+}
+
+func (s *porter) setTo(suffix string) {
+	s.b = append(s.b[:s.j+1], suffix...)
+}
+
+func (s *porter) r(suffix string) {
+	if s.m() > 0 {
+		s.setTo(suffix)
+	}
+}
+
+func (s *porter) trim() {
+	s.b = s.b[:len(s.b)-1]
+}
+
+// step1ab removes plurals and -ed or -ing
+func (s *porter) step1ab() {
+	if s.b[s.k()] == 's' {
+		if s.ends("sses") {
+			s.b = s.b[:len(s.b)-2]
+		} else if s.ends("ies") {
+			s.setTo("i")
+		} else if s.b[s.k()-1] != 's' {
+			s.trim()
+		}
+	}
+
+	if s.ends("eed") {
+		if s.m() > 0 {
+			s.trim()
+		}
+	} else if (s.ends("ed") || s.ends("ing")) && s.vowelInStem() {
+		s.b = s.b[:s.j+1]
+
+		if s.ends("at") {
+			s.setTo("ate")
+		} else if s.ends("bl") {
+			s.setTo("ble")
+		} else if s.ends("iz") {
+			s.setTo("ize")
+		} else if s.doubleC(s.k()) {
+			if c := s.b[s.k()]; c != 'l' && c != 's' && c != 'z' {
+				s.trim()
+			}
+		} else if s.j = s.k(); s.m() == 1 && s.cvc(s.k()) {
+			s.b = append(s.b, 'e')
+		}
+	}
+}
+
+// step1c turns terminal y to i when there is another vowel in the stem
+func (s *porter) step1c() {
+	if s.ends("y") && s.vowelInStem() {
+		s.b[s.k()] = 'i'
+	}
+}
+
+var porterStep2 = map[byte][][2]string{
+	'a': {{"ational", "ate"}, {"tional", "tion"}},
+	'c': {{"enci", "ence"}, {"anci", "ance"}},
+	'e': {{"izer", "ize"}},
+	'l': {{"bli", "ble"}, {"alli", "al"}, {"entli", "ent"}, {"eli", "e"}, {"ousli", "ous"}},
+	'o': {{"ization", "ize"}, {"ation", "ate"}, {"ator", "ate"}},
+	's': {{"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"}, {"ousness", "ous"}},
+	't': {{"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"}},
+	'g': {{"logi", "log"}},
+}
+
+// step2 maps double suffixes to single ones
+func (s *porter) step2() {
+	for _, rule := range porterStep2[s.b[s.k()-1]] {
+		if s.ends(rule[0]) {
+			s.r(rule[1])
+			return
+		}
+	}
+}
+
+var porterStep3 = map[byte][][2]string{
+	'e': {{"icate", "ic"}, {"ative", ""}, {"alize", "al"}},
+	'i': {{"iciti", "ic"}},
+	'l': {{"ical", "ic"}, {"ful", ""}},
+	's': {{"ness", ""}},
+}
+
+// step3 deals with -ic-, -full, -ness etc.
+func (s *porter) step3() {
+	for _, rule := range porterStep3[s.b[s.k()]] {
+		if s.ends(rule[0]) {
+			s.r(rule[1])
+			return
+		}
+	}
+}
+
+var porterStep4 = map[byte][]string{
+	'a': {"al"},
+	'c': {"ance", "ence"},
+	'e': {"er"},
+	'i': {"ic"},
+	'l': {"able", "ible"},
+	'n': {"ant", "ement", "ment", "ent"},
+	's': {"ism"},
+	't': {"ate", "iti"},
+	'u': {"ous"},
+	'v': {"ive"},
+	'z': {"ize"},
+}
+
+// step4 takes off -ant, -ence etc. in context <c>vcvc<v>
+func (s *porter) step4() {
+	if len(s.b) < 2 {
+		return
+	}
+
+	found := false
+	if s.b[s.k()-1] == 'o' {
+		found = s.ends("ion") && s.j >= 0 && (s.b[s.j] == 's' || s.b[s.j] == 't') || s.ends("ou")
+	} else {
+		for _, suffix := range porterStep4[s.b[s.k()-1]] {
+			if s.ends(suffix) {
+				found = true
+				break
+			}
+		}
+	}
+
+	if found && s.m() > 1 {
+		s.b = s.b[:s.j+1]
+	}
+}
+
+// step5 removes a final -e and changes -ll to -l when m > 1
+func (s *porter) step5() {
+	s.j = s.k()
+
+	if s.b[s.k()] == 'e' {
+		if a := s.m(); a > 1 || a == 1 && !s.cvc(s.k()-1) {
+			s.trim()
+		}
+	}
+
+	if s.b[s.k()] == 'l' && s.doubleC(s.k()) && s.m() > 1 {
+		s.trim()
+	}
+}
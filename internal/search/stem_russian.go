@@ -0,0 +1,141 @@
+package search
+
+// stemRussian is the Snowball Russian stemmer, the word must be lowercase with ё replaced by е.
+// See https://snowballstem.org/algorithms/russian/stemmer.html
+func stemRussian(word string) string {
+	w := []rune(word)
+	rv, r2 := russianRegions(w)
+	if rv >= len(w) {
+		return word
+	}
+
+	// Step 1
+	if end, ok := russianSuffix(w, rv, perfectiveGerund1, perfectiveGerund2); ok {
+		w = w[:end]
+	} else {
+		if end, ok := russianSuffix(w, rv, nil, reflexive); ok {
+			w = w[:end]
+		}
+
+		if end, ok := russianSuffix(w, rv, nil, adjective); ok {
+			w = w[:end]
+			if end, ok := russianSuffix(w, rv, participle1, participle2); ok {
+				w = w[:end]
+			}
+		} else if end, ok := russianSuffix(w, rv, verb1, verb2); ok {
+			w = w[:end]
+		} else if end, ok := russianSuffix(w, rv, nil, noun); ok {
+			w = w[:end]
+		}
+	}
+
+	// Step 2
+	if len(w) > rv && w[len(w)-1] == 'и' {
+		w = w[:len(w)-1]
+	}
+
+	// Step 3
+	if end, ok := russianSuffix(w, r2, nil, derivational); ok {
+		w = w[:end]
+	}
+
+	// Step 4
+	if end, ok := russianSuffix(w, rv, nil, superlative); ok {
+		w = w[:end]
+	}
+	if len(w)-2 >= rv && w[len(w)-1] == 'н' && w[len(w)-2] == 'н' {
+		w = w[:len(w)-1]
+	} else if len(w) > rv && w[len(w)-1] == 'ь' {
+		w = w[:len(w)-1]
+	}
+
+	return string(w)
+}
+
+var (
+	perfectiveGerund1 = []string{"в", "вши", "вшись"}
+	perfectiveGerund2 = []string{"ив", "ивши", "ившись", "ыв", "ывши", "ывшись"}
+	adjective         = []string{
+		"ее", "ие", "ые", "ое", "ими", "ыми", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом",
+		"его", "ого", "ему", "ому", "их", "ых", "ую", "юю", "ая", "яя", "ою", "ею",
+	}
+	participle1 = []string{"ем", "нн", "вш", "ющ", "щ"}
+	participle2 = []string{"ивш", "ывш", "ующ"}
+	reflexive   = []string{"ся", "сь"}
+	verb1       = []string{"ла", "на", "ете", "йте", "ли", "й", "л", "ем", "н", "ло", "но", "ет", "ют", "ны", "ть", "ешь", "нно"}
+	verb2       = []string{
+		"ила", "ыла", "ена", "ейте", "уйте", "ите", "или", "ыли", "ей", "уй", "ил", "ыл", "им", "ым", "ен",
+		"ило", "ыло", "ено", "ят", "ует", "уют", "ит", "ыт", "ены", "ить", "ыть", "ишь", "ую", "ю",
+	}
+	noun = []string{
+		"а", "ев", "ов", "ие", "ье", "е", "иями", "ями", "ами", "еи", "ии", "и", "ией", "ей", "ой", "ий", "й",
+		"иям", "ям", "ием", "ем", "ам", "ом", "о", "у", "ах", "иях", "ях", "ы", "ь", "ию", "ью", "ю", "ия", "ья", "я",
+	}
+	derivational = []string{"ост", "ость"}
+	superlative  = []string{"ейш", "ейше"}
+)
+
+func isRussianVowel(r rune) bool {
+	switch r {
+	case 'а', 'е', 'и', 'о', 'у', 'ы', 'э', 'ю', 'я':
+		return true
+	}
+
+	return false
+}
+
+// russianRegions returns the starts of RV, the part after the first vowel, and of R2
+func russianRegions(w []rune) (rv int, r2 int) {
+	rv, r1, r2 := len(w), len(w), len(w)
+
+	for i := range w {
+		if isRussianVowel(w[i]) {
+			rv = i + 1
+			break
+		}
+	}
+
+	// R1 is the part after the first non-vowel following a vowel, R2 is the same within R1
+	for i := 1; i < len(w); i++ {
+		if !isRussianVowel(w[i]) && isRussianVowel(w[i-1]) {
+			r1 = i + 1
+			break
+		}
+	}
+	for i := r1 + 1; i < len(w); i++ {
+		if !isRussianVowel(w[i]) && isRussianVowel(w[i-1]) {
+			r2 = i + 1
+			break
+		}
+	}
+
+	return rv, r2
+}
+
+// russianSuffix finds the longest of the suffixes lying in the region starting at start and returns
+// where the word would end without it. The suffixes of afterAOrYa must follow а or я, which is kept.
+func russianSuffix(w []rune, start int, afterAOrYa []string, suffixes []string) (int, bool) {
+	best, found, needsAOrYa := len(w), false, false
+
+	try := func(list []string, after bool) {
+		for _, suffix := range list {
+			s := []rune(suffix)
+			end := len(w) - len(s)
+			if end < start || end >= best || string(w[end:]) != suffix {
+				continue
+			}
+			best, found, needsAOrYa = end, true, after
+		}
+	}
+	try(afterAOrYa, true)
+	try(suffixes, false)
+
+	if !found {
+		return 0, false
+	}
+	if needsAOrYa && (best-1 < start || w[best-1] != 'а' && w[best-1] != 'я') {
+		return 0, false
+	}
+
+	return best, true
+}
@@ -2,31 +2,278 @@ package utils
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
-var Configuration *ConfigType
-
 type ConfigType struct {
 	Debug   *ProfileType `json:"debug"`
 	Release *ProfileType `json:"release"`
 }
 
 type ProfileType struct {
-	ServerPort            *string `json:"server_port"`
-	MongoConnectionString *string `json:"mongo_connection_string"`
+	ServerPort                     *string `json:"server_port"`
+	MongoConnectionString          *string `json:"mongo_connection_string"`
+	MongoDatabase                  *string `json:"mongo_database"`
+	MongoConnectTimeoutSeconds     *uint   `json:"mongo_connect_timeout_seconds"`
+	MongoPingTimeoutSeconds        *uint   `json:"mongo_ping_timeout_seconds"`
+	MongoMinPoolSize               *uint   `json:"mongo_min_pool_size"`
+	MongoMaxPoolSize               *uint   `json:"mongo_max_pool_size"`
+	JwtSigningKey                  *string `json:"jwt_signing_key"`
+	JwtIssuer                      *string `json:"jwt_issuer"`
+	JwtAudience                    *string `json:"jwt_audience"`
+	JwtAccessLifetimeMinutes       *uint   `json:"jwt_access_lifetime_minutes"`
+	JwtRefreshLifetimeMinutes      *uint   `json:"jwt_refresh_lifetime_minutes"`
+	JwtLeewaySeconds               *uint   `json:"jwt_leeway_seconds"`
+	JwtRevocationCacheSeconds      *uint   `json:"jwt_revocation_cache_seconds"`
+	ArticlesTrashRetentionDays     *uint   `json:"articles_trash_retention_days"`
+	ArticlesPublishIntervalSeconds *uint   `json:"articles_publish_interval_seconds"`
+	ArticlesSearchBackend          *string `json:"articles_search_backend"` // "repository" or "local"
+	ArticlesSearchIndexPath        *string `json:"articles_search_index_path"`
+	// A viewer is counted once per article within the window
+	ArticlesViewWindowMinutes *uint `json:"articles_view_window_minutes"`
+	ArticlesViewFlushSeconds  *uint `json:"articles_view_flush_seconds"`
+	// Lets authors publish and schedule their drafts without a review, off makes the review required
+	ArticlesAuthorsCanPublish *bool `json:"articles_authors_can_publish"`
+	// Hosts whose embeds are rendered as frames, embeds from other hosts become links
+	ArticlesEmbedHosts []string `json:"articles_embed_hosts"`
+	// Signs pagination cursors, without it a random key is used and cursors break on restarts
+	// and between replicas
+	PaginationCursorKey *string `json:"pagination_cursor_key"`
+	// Asymmetric keys, when present they replace jwt_signing_key
+	JwtKeys []JwtKeyConfig `json:"jwt_keys"`
+}
+
+// JwtKeyConfig describes a PEM encoded RSA or Ed25519 key. A key without private_key_file can only
+// verify tokens. For a rotation the next key is added with a future active_from and the previous
+// one gets a retire_at later than that by at least jwt_refresh_lifetime_minutes.
+type JwtKeyConfig struct {
+	Id             string     `json:"kid"`
+	PrivateKeyFile *string    `json:"private_key_file"`
+	PublicKeyFile  *string    `json:"public_key_file"`
+	ActiveFrom     *time.Time `json:"active_from"`
+	RetireAt       *time.Time `json:"retire_at"`
 }
 
-func init() {
-	file, err := os.Open("./configs/appsettings.json")
+// DefaultJwtSigningKey is only good for local development
+const DefaultJwtSigningKey = "secret"
+
+// jwtMinSigningKeyLength is the HS256 key size recommended by RFC 7518
+const jwtMinSigningKeyLength = 32
+
+// ConfigurationPath is where the commands look for the config
+const ConfigurationPath = "./configs/appsettings.json"
+
+// LoadConfiguration reads the config file. It isn't done on import so that the packages which
+// depend on utils can be tested without a config.
+func LoadConfiguration(path string) (*ConfigType, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		log.Fatal("Invalid config path!\n\t>>> ", err)
+		return nil, err
+	}
+	defer file.Close()
+
+	configuration := new(ConfigType)
+	if err := json.NewDecoder(file).Decode(configuration); err != nil {
+		return nil, err
+	}
+
+	return configuration, nil
+}
+
+// ApplyEnvironment overrides profile values with SHURYAK_* environment variables
+// and fills the values missing in both places with defaults
+func (profile *ProfileType) ApplyEnvironment() error {
+	overrideString(&profile.ServerPort, "SHURYAK_SERVER_PORT")
+	overrideString(&profile.MongoConnectionString, "SHURYAK_MONGO_URI")
+	overrideString(&profile.MongoDatabase, "SHURYAK_MONGO_DATABASE")
+	overrideString(&profile.JwtSigningKey, "SHURYAK_JWT_SIGNING_KEY")
+	overrideString(&profile.JwtIssuer, "SHURYAK_JWT_ISSUER")
+	overrideString(&profile.JwtAudience, "SHURYAK_JWT_AUDIENCE")
+	overrideString(&profile.ArticlesSearchBackend, "SHURYAK_ARTICLES_SEARCH_BACKEND")
+	overrideString(&profile.ArticlesSearchIndexPath, "SHURYAK_ARTICLES_SEARCH_INDEX_PATH")
+	overrideString(&profile.PaginationCursorKey, "SHURYAK_PAGINATION_CURSOR_KEY")
+	if err := overrideBool(&profile.ArticlesAuthorsCanPublish, "SHURYAK_ARTICLES_AUTHORS_CAN_PUBLISH"); err != nil {
+		return err
+	}
+	overrideList(&profile.ArticlesEmbedHosts, "SHURYAK_ARTICLES_EMBED_HOSTS")
+
+	uintOverrides := []struct {
+		field **uint
+		name  string
+	}{
+		{&profile.MongoConnectTimeoutSeconds, "SHURYAK_MONGO_CONNECT_TIMEOUT_SECONDS"},
+		{&profile.MongoPingTimeoutSeconds, "SHURYAK_MONGO_PING_TIMEOUT_SECONDS"},
+		{&profile.MongoMinPoolSize, "SHURYAK_MONGO_MIN_POOL_SIZE"},
+		{&profile.MongoMaxPoolSize, "SHURYAK_MONGO_MAX_POOL_SIZE"},
+		{&profile.JwtAccessLifetimeMinutes, "SHURYAK_JWT_ACCESS_LIFETIME_MINUTES"},
+		{&profile.JwtRefreshLifetimeMinutes, "SHURYAK_JWT_REFRESH_LIFETIME_MINUTES"},
+		{&profile.JwtLeewaySeconds, "SHURYAK_JWT_LEEWAY_SECONDS"},
+		{&profile.JwtRevocationCacheSeconds, "SHURYAK_JWT_REVOCATION_CACHE_SECONDS"},
+		{&profile.ArticlesTrashRetentionDays, "SHURYAK_ARTICLES_TRASH_RETENTION_DAYS"},
+		{&profile.ArticlesPublishIntervalSeconds, "SHURYAK_ARTICLES_PUBLISH_INTERVAL_SECONDS"},
+		{&profile.ArticlesViewWindowMinutes, "SHURYAK_ARTICLES_VIEW_WINDOW_MINUTES"},
+		{&profile.ArticlesViewFlushSeconds, "SHURYAK_ARTICLES_VIEW_FLUSH_SECONDS"},
+	}
+	for _, override := range uintOverrides {
+		if err := overrideUint(override.field, override.name); err != nil {
+			return err
+		}
+	}
+
+	defaultString(&profile.ServerPort, "8181")
+	defaultString(&profile.MongoConnectionString, "mongodb://localhost:27017")
+	defaultString(&profile.MongoDatabase, "shuryakDb")
+	defaultUint(&profile.MongoConnectTimeoutSeconds, 10)
+	defaultUint(&profile.MongoPingTimeoutSeconds, 5)
+	defaultUint(&profile.MongoMinPoolSize, 0)
+	defaultUint(&profile.MongoMaxPoolSize, 100)
+	defaultString(&profile.JwtSigningKey, DefaultJwtSigningKey)
+	defaultString(&profile.JwtIssuer, "shuryak")
+	defaultString(&profile.JwtAudience, "shuryak")
+	defaultUint(&profile.JwtAccessLifetimeMinutes, 30)
+	defaultUint(&profile.JwtRefreshLifetimeMinutes, 30*24*60)
+	defaultUint(&profile.JwtLeewaySeconds, 30)
+	defaultUint(&profile.JwtRevocationCacheSeconds, 5)
+	defaultUint(&profile.ArticlesTrashRetentionDays, 30)
+	defaultUint(&profile.ArticlesPublishIntervalSeconds, 30)
+	defaultBool(&profile.ArticlesAuthorsCanPublish, true)
+	defaultList(&profile.ArticlesEmbedHosts, []string{"www.youtube.com", "www.youtube-nocookie.com", "player.vimeo.com"})
+	defaultString(&profile.ArticlesSearchBackend, "repository")
+	defaultString(&profile.ArticlesSearchIndexPath, "./data/search")
+	defaultUint(&profile.ArticlesViewWindowMinutes, 30)
+	defaultUint(&profile.ArticlesViewFlushSeconds, 10)
+	defaultString(&profile.PaginationCursorKey, "")
+
+	if *profile.MongoMinPoolSize > *profile.MongoMaxPoolSize {
+		return fmt.Errorf("mongo_min_pool_size > mongo_max_pool_size")
+	}
+
+	if *profile.JwtAccessLifetimeMinutes == 0 || *profile.JwtRefreshLifetimeMinutes == 0 {
+		return fmt.Errorf("jwt lifetimes must be positive")
+	}
+
+	if *profile.ArticlesPublishIntervalSeconds == 0 {
+		return fmt.Errorf("articles_publish_interval_seconds must be positive")
+	}
+
+	if *profile.ArticlesViewFlushSeconds == 0 {
+		return fmt.Errorf("articles_view_flush_seconds must be positive")
+	}
+
+	if *profile.ArticlesSearchBackend != "repository" && *profile.ArticlesSearchBackend != "local" {
+		return fmt.Errorf("articles_search_backend must be repository or local")
+	}
+
+	// The hosts go into the Content-Security-Policy header as is
+	for _, host := range profile.ArticlesEmbedHosts {
+		if host == "" || strings.ContainsAny(host, " \t;,/'\"*") {
+			return fmt.Errorf("articles_embed_hosts must contain host names, got %q", host)
+		}
+	}
+
+	return nil
+}
+
+// CheckSecrets refuses a profile that would sign tokens or cursors with a missing, default or short
+// key, it must be called after ApplyEnvironment
+func (profile *ProfileType) CheckSecrets() error {
+	// A random key would break the cursors on every restart and between instances
+	if *profile.PaginationCursorKey == "" {
+		return fmt.Errorf("pagination_cursor_key is missing, set it with SHURYAK_PAGINATION_CURSOR_KEY")
+	}
+
+	// Asymmetric keys are checked when they are loaded
+	if len(profile.JwtKeys) != 0 {
+		return nil
+	}
+
+	if *profile.JwtSigningKey == DefaultJwtSigningKey {
+		return fmt.Errorf("jwt_signing_key is missing or default, set it with SHURYAK_JWT_SIGNING_KEY")
 	}
-	decoder := json.NewDecoder(file)
-	Configuration = new(ConfigType)
-	err = decoder.Decode(&Configuration)
+
+	if len(*profile.JwtSigningKey) < jwtMinSigningKeyLength {
+		return fmt.Errorf("jwt_signing_key length < %d", jwtMinSigningKeyLength)
+	}
+
+	return nil
+}
+
+func overrideString(field **string, name string) {
+	if value, ok := os.LookupEnv(name); ok {
+		*field = &value
+	}
+}
+
+// overrideList reads a comma separated list, an empty variable gives an empty list
+func overrideList(field *[]string, name string) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return
+	}
+
+	*field = []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*field = append(*field, item)
+		}
+	}
+}
+
+func overrideUint(field **uint, name string) error {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return nil
+	}
+
+	parsed, err := strconv.ParseUint(value, 10, 0)
 	if err != nil {
-		log.Fatal("Bad config!")
+		return fmt.Errorf("bad %s value: %v", name, err)
+	}
+
+	result := uint(parsed)
+	*field = &result
+	return nil
+}
+
+func overrideBool(field **bool, name string) error {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("bad %s value: %v", name, err)
+	}
+
+	*field = &parsed
+	return nil
+}
+
+func defaultString(field **string, value string) {
+	if *field == nil {
+		*field = &value
+	}
+}
+
+func defaultList(field *[]string, value []string) {
+	if *field == nil {
+		*field = value
+	}
+}
+
+func defaultUint(field **uint, value uint) {
+	if *field == nil {
+		*field = &value
+	}
+}
+
+func defaultBool(field **bool, value bool) {
+	if *field == nil {
+		*field = &value
 	}
 }
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var ErrBadCursor = errors.New("bad cursor")
+
+// cursorKey is replaced with the profile value by ConfigureCursors on startup if there is one
+var cursorKey = randomCursorKey()
+
+func randomCursorKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+
+	return key
+}
+
+func ConfigureCursors(config *ProfileType) {
+	if *config.PaginationCursorKey != "" {
+		cursorKey = []byte(*config.PaginationCursorKey)
+	}
+}
+
+// SignCursor encodes the position in a list as an opaque cursor, the signature keeps clients
+// from making up positions
+func SignCursor(position interface{}) (string, error) {
+	payload, err := json.Marshal(position)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + cursorSignature(encoded), nil
+}
+
+// ParseCursor decodes a cursor made by SignCursor into the position
+func ParseCursor(cursor string, position interface{}) error {
+	parts := strings.Split(cursor, ".")
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(cursorSignature(parts[0]))) {
+		return ErrBadCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return ErrBadCursor
+	}
+
+	if err := json.Unmarshal(payload, position); err != nil {
+		return ErrBadCursor
+	}
+
+	return nil
+}
+
+func cursorSignature(encoded string) string {
+	mac := hmac.New(sha256.New, cursorKey)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -7,6 +7,11 @@ import (
 )
 
 func WriteError(w *http.ResponseWriter, errorCode models.ErrorCode, description string) {
+	WriteFieldErrors(w, errorCode, description, nil)
+}
+
+// WriteFieldErrors is WriteError with the paths of the invalid fields
+func WriteFieldErrors(w *http.ResponseWriter, errorCode models.ErrorCode, description string, fields []models.FieldError) {
 	var httpStatusCode int
 
 	switch errorCode {
@@ -26,6 +31,14 @@ func WriteError(w *http.ResponseWriter, errorCode models.ErrorCode, description
 		httpStatusCode = http.StatusBadRequest
 	case models.InvalidFieldLength:
 		httpStatusCode = http.StatusBadRequest
+	case models.Forbidden:
+		httpStatusCode = http.StatusForbidden
+	case models.VersionConflict:
+		httpStatusCode = http.StatusConflict
+	case models.InvalidTransition:
+		httpStatusCode = http.StatusConflict
+	case models.InvalidDocument:
+		httpStatusCode = http.StatusBadRequest
 	default:
 		httpStatusCode = http.StatusInternalServerError
 	}
@@ -35,6 +48,7 @@ func WriteError(w *http.ResponseWriter, errorCode models.ErrorCode, description
 	errorMessage := models.ErrorDTO{
 		ErrorCode: errorCode,
 		Message:   description,
+		Fields:    fields,
 	}
 	json.NewEncoder(*w).Encode(errorMessage)
 }
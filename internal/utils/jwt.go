@@ -1,27 +1,90 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
 	"time"
 )
 
-var SigningKey = []byte("secret")
+// Values of the "typ" claim, a refresh token must never be accepted where an access token is expected
+const (
+	AccessTokenType  = "access"
+	RefreshTokenType = "refresh"
+)
+
+type JwtSettings struct {
+	Keys            *KeyRing
+	Issuer          string
+	Audience        string
+	AccessLifetime  time.Duration
+	RefreshLifetime time.Duration
+	Leeway          time.Duration // Allowed clock skew between token issuer and verifier
+}
+
+// Jwt is replaced with the profile values by ConfigureJwt on startup
+var Jwt = JwtSettings{
+	Keys:            NewHmacKeyRing([]byte(DefaultJwtSigningKey)),
+	Issuer:          "shuryak",
+	Audience:        "shuryak",
+	AccessLifetime:  30 * time.Minute,
+	RefreshLifetime: 30 * 24 * time.Hour,
+	Leeway:          30 * time.Second,
+}
+
+func ConfigureJwt(config *ProfileType) error {
+	keys := NewHmacKeyRing([]byte(*config.JwtSigningKey))
+
+	if len(config.JwtKeys) != 0 {
+		var err error
+		if keys, err = LoadKeyRing(config.JwtKeys); err != nil {
+			return err
+		}
+	}
+
+	Jwt = JwtSettings{
+		Keys:            keys,
+		Issuer:          *config.JwtIssuer,
+		Audience:        *config.JwtAudience,
+		AccessLifetime:  time.Duration(*config.JwtAccessLifetimeMinutes) * time.Minute,
+		RefreshLifetime: time.Duration(*config.JwtRefreshLifetimeMinutes) * time.Minute,
+		Leeway:          time.Duration(*config.JwtLeewaySeconds) * time.Second,
+	}
+
+	return nil
+}
+
+// GenerateJWT issues an access and refresh token pair for the session, the session id is
+// also the rotation family of the refresh token
+func GenerateJWT(firstName string, lastName string, nickname string, role string, sessionId string) (map[string]interface{}, error) {
+	now := time.Now()
+
+	key, err := Jwt.Keys.SigningKey(now)
+	if err != nil {
+		return nil, err
+	}
 
-func GenerateJWT(firstName string, lastName string, nickname string, accessMinutes uint) (map[string]interface{}, error) {
 	// region Access Token
-	accessToken := jwt.New(jwt.SigningMethodHS256)
+	accessToken := jwt.New(key.Method)
+	accessToken.Header["kid"] = key.Id
 
 	accessClaims := accessToken.Claims.(jwt.MapClaims)
 
-	accessExpiresIn := time.Minute * time.Duration(accessMinutes)
-
 	accessClaims["first_name"] = firstName
 	accessClaims["last_name"] = lastName
 	accessClaims["nickname"] = nickname
-	accessClaims["exp"] = time.Now().Add(accessExpiresIn).Unix()
+	accessClaims["role"] = role
+	accessClaims["typ"] = AccessTokenType
+	accessClaims["jti"] = NewTokenId()
+	accessClaims["sid"] = sessionId
+	accessClaims["iss"] = Jwt.Issuer
+	accessClaims["aud"] = Jwt.Audience
+	accessClaims["iat"] = now.Unix()
+	accessClaims["exp"] = now.Add(Jwt.AccessLifetime).Unix()
 
-	accessTokenString, err := accessToken.SignedString(SigningKey)
+	accessTokenString, err := accessToken.SignedString(key.PrivateKey)
 
 	if err != nil {
 		return nil, err
@@ -29,13 +92,21 @@ func GenerateJWT(firstName string, lastName string, nickname string, accessMinut
 	// endregion Access Token
 
 	// region Refresh Token
-	refreshToken := jwt.New(jwt.SigningMethodHS256)
+	refreshToken := jwt.New(key.Method)
+	refreshToken.Header["kid"] = key.Id
 
 	refreshClaims := refreshToken.Claims.(jwt.MapClaims)
 
 	refreshClaims["nickname"] = nickname
+	refreshClaims["typ"] = RefreshTokenType
+	refreshClaims["jti"] = NewTokenId()
+	refreshClaims["fam"] = sessionId
+	refreshClaims["iss"] = Jwt.Issuer
+	refreshClaims["aud"] = Jwt.Audience
+	refreshClaims["iat"] = now.Unix()
+	refreshClaims["exp"] = now.Add(Jwt.RefreshLifetime).Unix()
 
-	refreshTokenString, err := refreshToken.SignedString(SigningKey)
+	refreshTokenString, err := refreshToken.SignedString(key.PrivateKey)
 
 	if err != nil {
 		return nil, err
@@ -43,31 +114,92 @@ func GenerateJWT(firstName string, lastName string, nickname string, accessMinut
 	// endregion Refresh Token
 
 	return map[string]interface{}{
-		"access_token":      accessTokenString,
-		"refresh_token":     refreshTokenString,
-		"access_expires_in": int64(accessExpiresIn.Seconds()),
+		"access_token":       accessTokenString,
+		"refresh_token":      refreshTokenString,
+		"access_expires_in":  int64(Jwt.AccessLifetime.Seconds()),
+		"refresh_expires_in": int64(Jwt.RefreshLifetime.Seconds()),
 	}, nil
 }
 
-func GetClaimsFromToken(tokenString string) (jwt.MapClaims, bool, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("there was an error")
+func NewTokenId() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(bytes)
+}
+
+// HashToken is used to store refresh tokens without being able to use them from a database dump
+func HashToken(tokenString string) string {
+	hash := sha256.Sum256([]byte(tokenString))
+	return hex.EncodeToString(hash[:])
+}
+
+// GetClaimsFromToken verifies the token and checks that its "typ" claim equals tokenType
+func GetClaimsFromToken(tokenString string, tokenType string) (jwt.MapClaims, bool, error) {
+	// Time based claims are checked by validateClaims to take the leeway into account
+	parser := jwt.Parser{SkipClaimsValidation: true}
+
+	token, err := parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		keyId, _ := token.Header["kid"].(string)
+
+		key, err := Jwt.Keys.VerificationKey(keyId, time.Now())
+		if err != nil {
+			return nil, err
+		}
+
+		// Otherwise a public RSA key could be used as an HMAC secret
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
 		}
-		return SigningKey, nil
+
+		return key.PublicKey, nil
 	})
 
-	if !token.Valid {
+	if err != nil || token == nil || !token.Valid {
 		return jwt.MapClaims{}, false, fmt.Errorf("invalid token")
 	}
 
-	if err != nil {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return jwt.MapClaims{}, true, fmt.Errorf("bad claims")
+	}
+
+	if err := validateClaims(claims); err != nil {
 		return jwt.MapClaims{}, true, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		return claims, true, nil
-	} else {
-		return jwt.MapClaims{}, true, fmt.Errorf("bad claims")
+	if claims["typ"] != tokenType {
+		return jwt.MapClaims{}, true, fmt.Errorf("token type is not %s", tokenType)
+	}
+
+	return claims, true, nil
+}
+
+func validateClaims(claims jwt.MapClaims) error {
+	now := time.Now().Unix()
+	leeway := int64(Jwt.Leeway.Seconds())
+
+	if !claims.VerifyExpiresAt(now-leeway, true) {
+		return fmt.Errorf("token is expired")
+	}
+
+	if !claims.VerifyIssuedAt(now+leeway, false) {
+		return fmt.Errorf("token used before issued")
+	}
+
+	if !claims.VerifyNotBefore(now+leeway, false) {
+		return fmt.Errorf("token is not valid yet")
+	}
+
+	if !claims.VerifyIssuer(Jwt.Issuer, true) {
+		return fmt.Errorf("bad token issuer")
 	}
+
+	if !claims.VerifyAudience(Jwt.Audience, true) {
+		return fmt.Errorf("bad token audience")
+	}
+
+	return nil
 }
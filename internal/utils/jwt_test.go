@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetClaimsFromToken(t *testing.T) {
+	defaults := Jwt
+	defer func() { Jwt = defaults }()
+
+	tokens, err := GenerateJWT("First", "Last", "nick", "author", "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	access, refresh := tokens["access_token"].(string), tokens["refresh_token"].(string)
+
+	expired := func() string {
+		Jwt.AccessLifetime = -time.Hour
+		defer func() { Jwt.AccessLifetime = defaults.AccessLifetime }()
+
+		tokens, err := GenerateJWT("First", "Last", "nick", "author", "session")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tokens["access_token"].(string)
+	}()
+
+	tests := []struct {
+		name      string
+		token     string
+		tokenType string
+		settings  func(settings *JwtSettings)
+		wantValid bool
+	}{
+		{"access token", access, AccessTokenType, nil, true},
+		{"refresh token", refresh, RefreshTokenType, nil, true},
+		{"refresh token as an access token", refresh, AccessTokenType, nil, false},
+		{"access token as a refresh token", access, RefreshTokenType, nil, false},
+		{"tampered token", access[:len(access)-2] + "xx", AccessTokenType, nil, false},
+		{"garbage", "a.b.c", AccessTokenType, nil, false},
+		{"expired token", expired, AccessTokenType, nil, false},
+		{"another key", access, AccessTokenType, func(settings *JwtSettings) {
+			settings.Keys = NewHmacKeyRing([]byte("another secret"))
+		}, false},
+		{"another issuer", access, AccessTokenType, func(settings *JwtSettings) { settings.Issuer = "another" }, false},
+		{"another audience", access, AccessTokenType, func(settings *JwtSettings) { settings.Audience = "another" }, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			Jwt = defaults
+			if test.settings != nil {
+				test.settings(&Jwt)
+			}
+
+			claims, _, err := GetClaimsFromToken(test.token, test.tokenType)
+
+			if valid := err == nil; valid != test.wantValid {
+				t.Fatalf("got error %v, want valid %v", err, test.wantValid)
+			}
+			if test.wantValid && claims["nickname"] != "nick" {
+				t.Errorf("got claims %v", claims)
+			}
+		})
+	}
+}
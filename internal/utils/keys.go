@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"io/ioutil"
+	"sort"
+	"time"
+)
+
+// hmacKeyId is used for the shared secret when no asymmetric keys are configured
+const hmacKeyId = "hs256"
+
+type SigningKey struct {
+	Id         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.PrivateKey // nil for keys that can only verify
+	PublicKey  crypto.PublicKey  // The shared secret for HS256
+	ActiveFrom time.Time         // Zero time means always
+	RetireAt   time.Time         // Zero time means never
+}
+
+// KeyRing holds every key that may verify a token and picks the one that signs new tokens.
+// Keys are rotated on schedule: the signing key is the newest active one, and the previous
+// keys keep verifying until they retire.
+type KeyRing struct {
+	keys []SigningKey // Sorted by ActiveFrom, newest first
+}
+
+func NewHmacKeyRing(secret []byte) *KeyRing {
+	return &KeyRing{keys: []SigningKey{{
+		Id:         hmacKeyId,
+		Method:     jwt.SigningMethodHS256,
+		PrivateKey: secret,
+		PublicKey:  secret,
+	}}}
+}
+
+func LoadKeyRing(configs []JwtKeyConfig) (*KeyRing, error) {
+	ring := &KeyRing{}
+
+	for _, config := range configs {
+		key, err := loadSigningKey(config)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %v", config.Id, err)
+		}
+
+		for _, loaded := range ring.keys {
+			if loaded.Id == key.Id {
+				return nil, fmt.Errorf("jwt key %q is duplicated", key.Id)
+			}
+		}
+
+		ring.keys = append(ring.keys, *key)
+	}
+
+	sort.SliceStable(ring.keys, func(i, j int) bool {
+		return ring.keys[i].ActiveFrom.After(ring.keys[j].ActiveFrom)
+	})
+
+	if _, err := ring.SigningKey(time.Now()); err != nil {
+		return nil, err
+	}
+
+	return ring, nil
+}
+
+func (ring *KeyRing) SigningKey(now time.Time) (*SigningKey, error) {
+	for i := range ring.keys {
+		key := &ring.keys[i]
+		if key.PrivateKey != nil && key.isActive(now) {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no active jwt signing key")
+}
+
+// VerificationKey accepts keys that aren't active yet, so a token signed by another replica
+// right after the rotation time isn't rejected because of clock skew
+func (ring *KeyRing) VerificationKey(id string, now time.Time) (*SigningKey, error) {
+	// Tokens issued before key ids were introduced have no "kid" header
+	if id == "" {
+		id = hmacKeyId
+	}
+
+	for i := range ring.keys {
+		key := &ring.keys[i]
+		if key.Id == id && !key.isRetired(now) {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown jwt key %q", id)
+}
+
+// PublicKeys returns the keys that can be published, shared secrets are never among them
+func (ring *KeyRing) PublicKeys(now time.Time) []SigningKey {
+	var results []SigningKey
+
+	for _, key := range ring.keys {
+		if key.Method != jwt.SigningMethodHS256 && !key.isRetired(now) {
+			results = append(results, key)
+		}
+	}
+
+	return results
+}
+
+func (key *SigningKey) isActive(now time.Time) bool {
+	return !now.Before(key.ActiveFrom) && !key.isRetired(now)
+}
+
+func (key *SigningKey) isRetired(now time.Time) bool {
+	return !key.RetireAt.IsZero() && !now.Before(key.RetireAt)
+}
+
+func loadSigningKey(config JwtKeyConfig) (*SigningKey, error) {
+	if config.Id == "" {
+		return nil, fmt.Errorf("kid is empty")
+	}
+
+	key := &SigningKey{Id: config.Id}
+
+	if config.ActiveFrom != nil {
+		key.ActiveFrom = *config.ActiveFrom
+	}
+	if config.RetireAt != nil {
+		key.RetireAt = *config.RetireAt
+	}
+
+	if config.PrivateKeyFile != nil {
+		block, err := readPem(*config.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		privateKey, err := parsePrivateKey(block)
+		if err != nil {
+			return nil, err
+		}
+
+		key.PrivateKey = privateKey
+		key.PublicKey = privateKey.(crypto.Signer).Public()
+	} else if config.PublicKeyFile != nil {
+		block, err := readPem(*config.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		key.PublicKey = publicKey
+	} else {
+		return nil, fmt.Errorf("private_key_file or public_key_file is required")
+	}
+
+	switch publicKey := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if publicKey.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA key size < 2048")
+		}
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("only RSA and Ed25519 keys are supported")
+	}
+
+	return key, nil
+}
+
+func readPem(path string) (*pem.Block, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+
+	return block, nil
+}
+
+func parsePrivateKey(block *pem.Block) (crypto.PrivateKey, error) {
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch privateKey.(type) {
+	case *rsa.PrivateKey, ed25519.PrivateKey:
+		return privateKey, nil
+	default:
+		return nil, fmt.Errorf("only RSA and Ed25519 keys are supported")
+	}
+}
@@ -6,31 +6,44 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"log"
+	"time"
 )
 
 var Mongo *mongo.Client
 
-func OpenMongo(address string) {
+func OpenMongo(config *ProfileType) {
 	// Initializing MongoDB Client
+	clientOptions := options.Client().
+		ApplyURI(*config.MongoConnectionString).
+		SetConnectTimeout(time.Duration(*config.MongoConnectTimeoutSeconds) * time.Second).
+		SetServerSelectionTimeout(time.Duration(*config.MongoConnectTimeoutSeconds) * time.Second).
+		SetMinPoolSize(uint64(*config.MongoMinPoolSize)).
+		SetMaxPoolSize(uint64(*config.MongoMaxPoolSize))
+
 	var err error
-	Mongo, err = mongo.NewClient(options.Client().ApplyURI(address))
+	Mongo, err = mongo.NewClient(clientOptions)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	// Create connection
-	err = Mongo.Connect(context.TODO())
+	connectCtx, cancel := context.WithTimeout(context.Background(), time.Duration(*config.MongoConnectTimeoutSeconds)*time.Second)
+	defer cancel()
+	err = Mongo.Connect(connectCtx)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	// Check the connection
-	err = Mongo.Ping(context.TODO(), nil)
+	pingCtx, cancel := context.WithTimeout(context.Background(), time.Duration(*config.MongoPingTimeoutSeconds)*time.Second)
+	defer cancel()
+	err = Mongo.Ping(pingCtx, nil)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	fmt.Println("Successfully connected to " + address + "!")
+	// The connection string isn't printed because it may contain credentials
+	fmt.Println("Successfully connected to MongoDB, using " + *config.MongoDatabase + " database!")
 }
 
 func CloseMongo() {
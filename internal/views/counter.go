@@ -0,0 +1,143 @@
+// Package views counts article views in memory and flushes them to the repositories in batches.
+package views
+
+import (
+	"context"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+	"strings"
+	"sync"
+	"time"
+)
+
+// botMarkers are parts of the user agents of crawlers and link previews, matched in lowercase
+var botMarkers = []string{
+	"bot", "crawl", "spider", "slurp", "facebookexternalhit", "embedly", "preview",
+	"curl", "wget", "python-requests", "go-http-client", "headless",
+}
+
+// IsBot tells if the user agent belongs to a crawler, a requester without a user agent is one too
+func IsBot(userAgent string) bool {
+	userAgent = strings.ToLower(strings.TrimSpace(userAgent))
+	if userAgent == "" {
+		return true
+	}
+
+	for _, marker := range botMarkers {
+		if strings.Contains(userAgent, marker) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Counter counts a view of an article once per viewer per window. The views are kept in memory
+// until Flush, so a crash loses at most one flush interval of them.
+type Counter struct {
+	articles repositories.ArticleRepository
+	views    repositories.ViewRepository
+	window   time.Duration
+
+	mutex   sync.Mutex
+	seen    map[string]time.Time           // Viewer and article to the end of the window
+	pending map[time.Time]map[string]int64 // Hour to the views per article
+	totals  map[string]int64               // Views per article already saved by the hour but not in the article
+}
+
+func NewCounter(articles repositories.ArticleRepository, views repositories.ViewRepository, window time.Duration) *Counter {
+	return &Counter{
+		articles: articles,
+		views:    views,
+		window:   window,
+		seen:     make(map[string]time.Time),
+		pending:  make(map[time.Time]map[string]int64),
+		totals:   make(map[string]int64),
+	}
+}
+
+// Count records a view of the article and tells if it was counted
+func (counter *Counter) Count(articleId string, viewer string, userAgent string) bool {
+	if IsBot(userAgent) {
+		return false
+	}
+
+	now := time.Now()
+	key := viewer + "\x00" + articleId
+
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+
+	if until, exists := counter.seen[key]; exists && now.Before(until) {
+		return false
+	}
+	counter.seen[key] = now.Add(counter.window)
+
+	hour := now.UTC().Truncate(time.Hour)
+	if counter.pending[hour] == nil {
+		counter.pending[hour] = make(map[string]int64)
+	}
+	counter.pending[hour][articleId]++
+
+	return true
+}
+
+// Flush saves the pending views, the ones that fail to be saved are kept for the next flush
+func (counter *Counter) Flush(ctx context.Context) error {
+	now := time.Now()
+
+	counter.mutex.Lock()
+	pending := counter.pending
+	counter.pending = make(map[time.Time]map[string]int64)
+	totals := counter.totals
+	counter.totals = make(map[string]int64)
+	for key, until := range counter.seen {
+		if !now.Before(until) {
+			delete(counter.seen, key)
+		}
+	}
+	counter.mutex.Unlock()
+
+	var addErr error
+	for hour, views := range pending {
+		if addErr = counter.views.Add(ctx, hour, views); addErr != nil {
+			break
+		}
+		delete(pending, hour)
+
+		for articleId, count := range views {
+			totals[articleId] += count
+		}
+	}
+	counter.restore(pending)
+
+	// Only the totals of the saved hours, retrying the rest would count them twice
+	if err := counter.articles.AddViews(ctx, totals); err != nil {
+		counter.restoreTotals(totals)
+		return err
+	}
+
+	return addErr
+}
+
+func (counter *Counter) restore(pending map[time.Time]map[string]int64) {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+
+	for hour, views := range pending {
+		if counter.pending[hour] == nil {
+			counter.pending[hour] = make(map[string]int64)
+		}
+		for articleId, count := range views {
+			counter.pending[hour][articleId] += count
+		}
+	}
+}
+
+func (counter *Counter) restoreTotals(totals map[string]int64) {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+
+	for articleId, count := range totals {
+		counter.totals[articleId] += count
+	}
+}
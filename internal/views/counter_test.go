@@ -0,0 +1,50 @@
+package views
+
+import (
+	"context"
+	"errors"
+	"github.com/shuryak/shuryak-backend/internal/models"
+	"github.com/shuryak/shuryak-backend/internal/repositories"
+	"testing"
+	"time"
+)
+
+type failingArticleRepository struct {
+	*repositories.MemoryArticleRepository
+	fail bool
+}
+
+func (repo *failingArticleRepository) AddViews(ctx context.Context, views map[string]int64) error {
+	if repo.fail {
+		return errors.New("unavailable")
+	}
+
+	return repo.MemoryArticleRepository.AddViews(ctx, views)
+}
+
+func TestCounterFlushKeepsUnsavedTotals(t *testing.T) {
+	ctx := context.Background()
+	articles := &failingArticleRepository{MemoryArticleRepository: repositories.NewMemoryArticleRepository()}
+	articles.Create(ctx, &models.Article{CustomId: "article"})
+	counter := NewCounter(articles, repositories.NewMemoryViewRepository(), time.Hour)
+
+	counter.Count("article", "first", "Mozilla/5.0")
+	articles.fail = true
+	if err := counter.Flush(ctx); err == nil {
+		t.Fatal("got no error from the failed flush")
+	}
+
+	counter.Count("article", "second", "Mozilla/5.0")
+	articles.fail = false
+	if err := counter.Flush(ctx); err != nil {
+		t.Fatalf("got error %v", err)
+	}
+
+	article, err := articles.FindByCustomId(ctx, "article")
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if article.ViewCount != 2 {
+		t.Errorf("got view count %d, want 2", article.ViewCount)
+	}
+}